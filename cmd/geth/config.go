@@ -23,6 +23,8 @@ import (
 	"os"
 	"reflect"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 
@@ -34,6 +36,9 @@ import (
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/wasm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/catalyst"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -90,11 +95,24 @@ type ethstatsConfig struct {
 	URL string `toml:",omitempty"`
 }
 
+// concretePrecompileConfig is a WASM precompile loaded from a file and active
+// from the given block.
+type concretePrecompileConfig struct {
+	Address common.Address
+	Path    string
+	Block   uint64 `toml:",omitempty"`
+}
+
+type concreteConfig struct {
+	Precompiles []concretePrecompileConfig `toml:",omitempty"`
+}
+
 type gethConfig struct {
 	Eth      ethconfig.Config
 	Node     node.Config
 	Ethstats ethstatsConfig
 	Metrics  metrics.Config
+	Concrete concreteConfig
 }
 
 func loadConfig(file string, cfg *gethConfig) error {
@@ -145,6 +163,82 @@ func loadBaseConfig(ctx *cli.Context) gethConfig {
 	return cfg
 }
 
+// loadConcreteConfig loads the concrete section of the config file, and the
+// precompiles given on the command line.
+func loadConcreteConfig(ctx *cli.Context) (concreteConfig, error) {
+	var cfg gethConfig
+	if file := ctx.String(configFileFlag.Name); file != "" {
+		if err := loadConfig(file, &cfg); err != nil {
+			return concreteConfig{}, err
+		}
+	}
+	err := setConcreteConfig(ctx, &cfg.Concrete)
+	return cfg.Concrete, err
+}
+
+func setConcreteConfig(ctx *cli.Context, cfg *concreteConfig) error {
+	for _, value := range ctx.StringSlice(utils.ConcretePrecompileFlag.Name) {
+		entry, err := parseConcretePrecompile(value)
+		if err != nil {
+			return err
+		}
+		cfg.Precompiles = append(cfg.Precompiles, entry)
+	}
+	return nil
+}
+
+// parseConcretePrecompile parses a precompile flag value of the form
+// <address>=<path.wasm>[@block].
+func parseConcretePrecompile(value string) (concretePrecompileConfig, error) {
+	invalid := fmt.Errorf("invalid concrete precompile %q, want <address>=<path.wasm>[@block]", value)
+	address, path, ok := strings.Cut(value, "=")
+	if !ok || !common.IsHexAddress(address) {
+		return concretePrecompileConfig{}, invalid
+	}
+	entry := concretePrecompileConfig{Address: common.HexToAddress(address), Path: path}
+	if idx := strings.LastIndex(path, "@"); idx >= 0 {
+		block, err := strconv.ParseUint(path[idx+1:], 10, 64)
+		if err != nil {
+			return concretePrecompileConfig{}, invalid
+		}
+		entry.Path, entry.Block = path[:idx], block
+	}
+	if entry.Path == "" {
+		return concretePrecompileConfig{}, invalid
+	}
+	return entry, nil
+}
+
+// addWasmPrecompiles adds the configured WASM precompiles to the registry.
+// Precompiles stay active in every later activation unless replaced by a later
+// entry, so upgrading one precompile does not deactivate the others.
+func addWasmPrecompiles(registry concrete.PrecompileRegistry, entries []concretePrecompileConfig) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	generic, ok := registry.(*concrete.GenericPrecompileRegistry)
+	if !ok {
+		return fmt.Errorf("cannot add WASM precompiles to a %T registry", registry)
+	}
+	entries = append([]concretePrecompileConfig{}, entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Block < entries[j].Block })
+
+	lastBlock := make(map[common.Address]uint64)
+	for _, entry := range entries {
+		if block, ok := lastBlock[entry.Address]; ok && block == entry.Block {
+			return fmt.Errorf("concrete precompile %v set more than once at block %d", entry.Address, entry.Block)
+		}
+		lastBlock[entry.Address] = entry.Block
+		code, err := os.ReadFile(entry.Path)
+		if err != nil {
+			return fmt.Errorf("concrete precompile %v: %w", entry.Address, err)
+		}
+		generic.SetPrecompile(entry.Block, entry.Address, wasm.NewWazeroPrecompile(code))
+		log.Info("Loaded concrete precompile", "address", entry.Address, "path", entry.Path, "block", entry.Block, "codeHash", crypto.Keccak256Hash(code))
+	}
+	return nil
+}
+
 // makeConfigNode loads geth configuration and creates a blank node instance.
 func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	cfg := loadBaseConfig(ctx)
@@ -0,0 +1,120 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package geth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/lib"
+)
+
+func TestParseConcretePrecompile(t *testing.T) {
+	tests := []struct {
+		value string
+		want  concretePrecompileConfig
+		err   bool
+	}{
+		{value: "0x80=add.wasm", err: true},
+		{value: "0x0000000000000000000000000000000000000080=add.wasm", want: concretePrecompileConfig{Address: common.BytesToAddress([]byte{0x80}), Path: "add.wasm"}},
+		{value: "0x0000000000000000000000000000000000000080=/a@b/add.wasm@100", want: concretePrecompileConfig{Address: common.BytesToAddress([]byte{0x80}), Path: "/a@b/add.wasm", Block: 100}},
+		{value: "0x0000000000000000000000000000000000000080=add.wasm@", err: true},
+		{value: "0x0000000000000000000000000000000000000080=@10", err: true},
+		{value: "add.wasm", err: true},
+	}
+	for _, test := range tests {
+		got, err := parseConcretePrecompile(test.value)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected error", test.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.value, err)
+		} else if got != test.want {
+			t.Errorf("%q: got %+v, want %+v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestAddWasmPrecompiles(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		path  = filepath.Join(dir, "pc.wasm")
+		addrA = common.BytesToAddress([]byte{0x80})
+		addrB = common.BytesToAddress([]byte{0x81})
+		addrC = common.BytesToAddress([]byte{0x82})
+	)
+	if err := os.WriteFile(path, []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	registry := concrete.NewRegistry()
+	registry.AddPrecompiles(20, concrete.PrecompileMap{addrC: &lib.BlankPrecompile{}})
+	err := addWasmPrecompiles(registry, []concretePrecompileConfig{
+		{Address: addrB, Path: path, Block: 10},
+		{Address: addrA, Path: path},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := len(registry.Precompiles(0, 0)); have != 1 {
+		t.Errorf("precompiles at block 0: have %d, want 1", have)
+	}
+	// Precompiles activated before stay active
+	if have := len(registry.Precompiles(10, 0)); have != 2 {
+		t.Errorf("precompiles at block 10: have %d, want 2", have)
+	}
+	// Existing later activations keep the added precompiles
+	if have := len(registry.Precompiles(20, 0)); have != 3 {
+		t.Errorf("precompiles at block 20: have %d, want 3", have)
+	}
+
+	err = addWasmPrecompiles(concrete.NewRegistry(), []concretePrecompileConfig{{Address: addrA, Path: filepath.Join(dir, "missing.wasm")}})
+	if err == nil || !strings.Contains(err.Error(), "missing.wasm") {
+		t.Errorf("expected missing file error, got %v", err)
+	}
+	err = addWasmPrecompiles(concrete.NewRegistry(), []concretePrecompileConfig{{Address: addrA, Path: path}, {Address: addrA, Path: path}})
+	if err == nil {
+		t.Error("expected duplicate precompile error")
+	}
+}
+
+func TestLoadConcreteConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	config := `
+[[Concrete.Precompiles]]
+Address = "0x0000000000000000000000000000000000000080"
+Path = "add.wasm"
+Block = 5
+`
+	if err := os.WriteFile(file, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var cfg gethConfig
+	if err := loadConfig(file, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := []concretePrecompileConfig{{Address: common.BytesToAddress([]byte{0x80}), Path: "add.wasm", Block: 5}}
+	if len(cfg.Concrete.Precompiles) != 1 || cfg.Concrete.Precompiles[0] != want[0] {
+		t.Errorf("have %+v, want %+v", cfg.Concrete.Precompiles, want)
+	}
+}
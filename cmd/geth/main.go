@@ -142,6 +142,8 @@ var (
 		utils.DeveloperGasLimitFlag,
 		utils.DeveloperPeriodFlag,
 		utils.VMEnableDebugFlag,
+		utils.ConcretePrecompileFlag,
+		utils.WasmCacheDirFlag,
 		utils.NetworkIdFlag,
 		utils.EthStatsURLFlag,
 		utils.NoCompactionFlag,
@@ -372,6 +374,19 @@ func newConcreteGeth(concreteRegistry concrete.PrecompileRegistry) func(ctx *cli
 		stack, backend := makeFullNode(ctx)
 		defer stack.Close()
 
+		concreteConfig, err := loadConcreteConfig(ctx)
+		if err != nil {
+			return err
+		}
+		if err := addWasmPrecompiles(concreteRegistry, concreteConfig.Precompiles); err != nil {
+			return err
+		}
+		if err := concrete.ValidateSchedule(concreteRegistry, backend.ChainConfig().Concrete); err != nil {
+			return fmt.Errorf("concrete precompile schedule mismatch: %w", err)
+		}
+		if err := concrete.CompilePrecompiles(concreteRegistry, stack.Config().WasmCachePath()); err != nil {
+			return fmt.Errorf("concrete precompile compilation failed: %w", err)
+		}
 		backend.SetConcrete(concreteRegistry)
 
 		startNode(ctx, stack, backend, false)
@@ -536,6 +536,17 @@ var (
 		Usage:    "Record information useful for VM and contract debugging",
 		Category: flags.VMCategory,
 	}
+	ConcretePrecompileFlag = &cli.StringSliceFlag{
+		Name:     "concrete.precompile",
+		Usage:    "WASM precompile to load, as <address>=<path.wasm>[@block]. This flag can be given multiple times.",
+		Category: flags.VMCategory,
+	}
+	WasmCacheDirFlag = &cli.StringFlag{
+		Name:     "concrete.wasmcache",
+		Usage:    "Directory for compiled WASM precompiles, relative to the instance directory (empty = compile at every start)",
+		Value:    node.DefaultConfig.WasmCacheDir,
+		Category: flags.VMCategory,
+	}
 
 	// API options.
 	RPCGlobalGasCapFlag = &cli.Uint64Flag{
@@ -1490,6 +1501,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(InsecureUnlockAllowedFlag.Name) {
 		cfg.InsecureUnlockAllowed = ctx.Bool(InsecureUnlockAllowedFlag.Name)
 	}
+	if ctx.IsSet(WasmCacheDirFlag.Name) {
+		cfg.WasmCacheDir = ctx.String(WasmCacheDirFlag.Name)
+	}
 	if ctx.IsSet(DBEngineFlag.Name) {
 		dbEngine := ctx.String(DBEngineFlag.Name)
 		if dbEngine != "leveldb" && dbEngine != "pebble" {
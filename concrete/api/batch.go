@@ -0,0 +1,141 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete/utils"
+)
+
+// EncodeOp packs an opcode and its arguments into a single ManyOps argument.
+func EncodeOp(op OpCode, args [][]byte) []byte {
+	return append(op.Encode(), utils.EncodeValues(args)...)
+}
+
+// DecodeOp unpacks a ManyOps argument produced by EncodeOp.
+func DecodeOp(data []byte) (OpCode, [][]byte, error) {
+	if len(data) == 0 {
+		return 0, nil, ErrInvalidInput
+	}
+	var op OpCode
+	op.Decode(data)
+	args, err := utils.DecodeValues(data[1:])
+	if err != nil {
+		return 0, nil, ErrInvalidInput
+	}
+	return op, args, nil
+}
+
+// BatchResult holds the output of an operation queued in a Batch. The output
+// is only available after the batch has been executed.
+type BatchResult struct {
+	output [][]byte
+}
+
+func (r *BatchResult) Output() [][]byte {
+	return r.output
+}
+
+func (r *BatchResult) Hash() common.Hash {
+	if len(r.output) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(r.output[0])
+}
+
+// Batch queues environment operations and executes them with a single
+// ManyOps call, which saves a host round-trip per operation when running as
+// a WASM precompile. Operations are executed in order and are metered
+// individually; execution stops at the first error.
+type Batch struct {
+	env     *Env
+	ops     [][]byte
+	results []*BatchResult
+}
+
+func (env *Env) Batch() *Batch {
+	return &Batch{env: env}
+}
+
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+func (b *Batch) Add(op OpCode, args [][]byte) *BatchResult {
+	result := &BatchResult{}
+	b.ops = append(b.ops, EncodeOp(op, args))
+	b.results = append(b.results, result)
+	return result
+}
+
+func (b *Batch) Execute() error {
+	if len(b.ops) == 0 {
+		return nil
+	}
+	ops, results := b.ops, b.results
+	b.ops, b.results = nil, nil
+	output, err := b.env.execute(ManyOps_OpCode, ops)
+	if err != nil {
+		return err
+	}
+	if len(output) != len(results) {
+		return ErrInvalidInput
+	}
+	for i, encOutput := range output {
+		values, err := utils.DecodeValues(encOutput)
+		if err != nil {
+			return err
+		}
+		results[i].output = values
+	}
+	return nil
+}
+
+func (b *Batch) PersistentLoad(key common.Hash) *BatchResult {
+	return b.StorageLoad(key)
+}
+
+func (b *Batch) PersistentStore(key common.Hash, value common.Hash) {
+	b.StorageStore(key, value)
+}
+
+func (b *Batch) EphemeralLoad_Unsafe(key common.Hash) *BatchResult {
+	return b.Add(EphemeralLoad_OpCode, [][]byte{key.Bytes()})
+}
+
+func (b *Batch) EphemeralStore_Unsafe(key common.Hash, value common.Hash) {
+	b.Add(EphemeralStore_OpCode, [][]byte{key.Bytes(), value.Bytes()})
+}
+
+func (b *Batch) StorageLoad(key common.Hash) *BatchResult {
+	return b.Add(StorageLoad_OpCode, [][]byte{key.Bytes()})
+}
+
+func (b *Batch) StorageStore(key common.Hash, value common.Hash) {
+	b.Add(StorageStore_OpCode, [][]byte{key.Bytes(), value.Bytes()})
+}
+
+func (b *Batch) IsolatedLoad(key common.Hash) *BatchResult {
+	return b.Add(IsolatedLoad_OpCode, [][]byte{key.Bytes()})
+}
+
+func (b *Batch) IsolatedStore(key common.Hash, value common.Hash) {
+	b.Add(IsolatedStore_OpCode, [][]byte{key.Bytes(), value.Bytes()})
+}
+
+func (b *Batch) Keccak256(data []byte) *BatchResult {
+	return b.Add(Keccak256_OpCode, [][]byte{data})
+}
@@ -28,6 +28,7 @@ type Environment interface {
 	Execute(op OpCode, args [][]byte) ([][]byte, error)
 
 	// Meta
+	Batch() *Batch
 	EnableGasMetering(meter bool)
 	Debug(msg string)
 	TimeNow() uint64
@@ -39,6 +40,15 @@ type Environment interface {
 	// Utils
 	Keccak256(data []byte) common.Hash
 
+	// Cryptography
+	Sha256(data []byte) common.Hash
+	Ecrecover(hash common.Hash, sig []byte) (common.Address, error)
+	P256Verify(hash common.Hash, sig []byte, pubKey []byte) bool
+	Bn256Add(a []byte, b []byte) ([]byte, error)
+	Bn256ScalarMul(point []byte, scalar common.Hash) ([]byte, error)
+	Bn256Pairing(input []byte) (bool, error)
+	KZGPointEvaluation(commitment []byte, point common.Hash, claim common.Hash, proof []byte) error
+
 	// Ephemeral
 	EphemeralLoad_Unsafe(key common.Hash) common.Hash
 	EphemeralStore_Unsafe(key common.Hash, value common.Hash)
@@ -70,6 +80,7 @@ type Environment interface {
 	GetCallValue() *uint256.Int
 	// Storage
 	StorageLoad(key common.Hash) common.Hash
+	IsolatedLoad(key common.Hash) common.Hash
 	// Code
 	GetCode(address common.Address) []byte
 	GetCodeSize() int
@@ -79,6 +90,7 @@ type Environment interface {
 	UseGas(amount uint64)
 	// Storage
 	StorageStore(key common.Hash, value common.Hash)
+	IsolatedStore(key common.Hash, value common.Hash)
 	// Log
 	Log(topics []common.Hash, data []byte)
 
@@ -99,12 +111,17 @@ type Environment interface {
 	// Create
 	Create(data []byte, value *uint256.Int) (common.Address, error)
 	Create2(data []byte, salt common.Hash, endowment *uint256.Int) (common.Address, error)
+	// Schedule
+	ScheduleCall(address common.Address, block uint64, data []byte, gas uint64) error
 }
 
 type EnvConfig struct {
 	Static    bool
 	Ephemeral bool
 	Trusted   bool
+	// Logs are disabled outside of transactions, e.g. in block hooks, as they
+	// would not be part of any receipt.
+	NoLogs bool
 }
 
 type logger struct{}
@@ -198,6 +215,11 @@ func execute(op OpCode, env *Env, args [][]byte) ([][]byte, error) {
 		return nil, env.Error()
 	}
 
+	if env.config.NoLogs && op == Log_OpCode {
+		env.setError(ErrLogsDisabled)
+		return nil, env.Error()
+	}
+
 	if env.meterGas {
 		gasConst := operation.constantGas
 		if ok := env.useGas(gasConst); !ok {
@@ -270,6 +292,10 @@ func (env *Env) Gas() uint64 {
 	return env.gas
 }
 
+func (env *Env) GasMetered() bool {
+	return env.meterGas
+}
+
 func (env *Env) Error() error {
 	return env.envErr
 }
@@ -317,6 +343,82 @@ func (env *Env) Keccak256(data []byte) common.Hash {
 	return hash
 }
 
+// Sha256 returns the SHA-256 hash of the data.
+func (env *Env) Sha256(data []byte) common.Hash {
+	input := [][]byte{data}
+	output, err := env.execute(Sha256_OpCode, input)
+	if err != nil {
+		return common.Hash{}
+	}
+	return common.BytesToHash(output[0])
+}
+
+// Ecrecover returns the address of the signer of a hash, from a 65 byte
+// [R || S || V] signature where V is 0 or 1.
+func (env *Env) Ecrecover(hash common.Hash, sig []byte) (common.Address, error) {
+	input := [][]byte{hash.Bytes(), sig}
+	output, err := env.execute(Ecrecover_OpCode, input)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return common.BytesToAddress(output[0]), utils.DecodeError(output[1])
+}
+
+// P256Verify verifies a 64 byte [R || S] secp256r1 signature of a hash against
+// a 64 byte [X || Y] public key.
+func (env *Env) P256Verify(hash common.Hash, sig []byte, pubKey []byte) bool {
+	input := [][]byte{hash.Bytes(), sig, pubKey}
+	output, err := env.execute(P256Verify_OpCode, input)
+	if err != nil {
+		return false
+	}
+	return output[0][0] == 0x01
+}
+
+// Bn256Add adds two 64 byte bn256 G1 points.
+func (env *Env) Bn256Add(a []byte, b []byte) ([]byte, error) {
+	input := [][]byte{a, b}
+	output, err := env.execute(Bn256Add_OpCode, input)
+	if err != nil {
+		return nil, err
+	}
+	return output[0], utils.DecodeError(output[1])
+}
+
+// Bn256ScalarMul multiplies a 64 byte bn256 G1 point by a scalar.
+func (env *Env) Bn256ScalarMul(point []byte, scalar common.Hash) ([]byte, error) {
+	input := [][]byte{point, scalar.Bytes()}
+	output, err := env.execute(Bn256ScalarMul_OpCode, input)
+	if err != nil {
+		return nil, err
+	}
+	return output[0], utils.DecodeError(output[1])
+}
+
+// Bn256Pairing runs a bn256 pairing check on pairs of 64 byte G1 and 128 byte
+// G2 points, encoded as for the EVM precompile.
+func (env *Env) Bn256Pairing(input []byte) (bool, error) {
+	output, err := env.execute(Bn256Pairing_OpCode, [][]byte{input})
+	if err != nil {
+		return false, err
+	}
+	if err := utils.DecodeError(output[1]); err != nil {
+		return false, err
+	}
+	return output[0][0] == 0x01, nil
+}
+
+// KZGPointEvaluation verifies a KZG proof that the polynomial of a 48 byte
+// commitment evaluates to claim at point, as in EIP-4844.
+func (env *Env) KZGPointEvaluation(commitment []byte, point common.Hash, claim common.Hash, proof []byte) error {
+	input := [][]byte{commitment, point.Bytes(), claim.Bytes(), proof}
+	output, err := env.execute(KZGPointEvaluation_OpCode, input)
+	if err != nil {
+		return err
+	}
+	return utils.DecodeError(output[0])
+}
+
 func (env *Env) EphemeralLoad_Unsafe(key common.Hash) common.Hash {
 	input := [][]byte{key.Bytes()}
 	output, err := env.execute(EphemeralLoad_OpCode, input)
@@ -479,6 +581,15 @@ func (env *Env) StorageLoad(key common.Hash) common.Hash {
 	return common.BytesToHash(output[0])
 }
 
+func (env *Env) IsolatedLoad(key common.Hash) common.Hash {
+	input := [][]byte{key.Bytes()}
+	output, err := env.execute(IsolatedLoad_OpCode, input)
+	if err != nil {
+		return common.Hash{}
+	}
+	return common.BytesToHash(output[0])
+}
+
 func (env *Env) GetCode(address common.Address) []byte {
 	input := [][]byte{address.Bytes()}
 	output, err := env.execute(GetCode_OpCode, input)
@@ -506,6 +617,11 @@ func (env *Env) StorageStore(key common.Hash, value common.Hash) {
 	env.execute(StorageStore_OpCode, input)
 }
 
+func (env *Env) IsolatedStore(key common.Hash, value common.Hash) {
+	input := [][]byte{key.Bytes(), value.Bytes()}
+	env.execute(IsolatedStore_OpCode, input)
+}
+
 func (env *Env) Log(topics []common.Hash, data []byte) {
 	input := make([][]byte, len(topics)+1)
 	for i := 0; i < len(topics); i++ {
@@ -596,4 +712,15 @@ func (env *Env) Create2(data []byte, salt common.Hash, endowment *uint256.Int) (
 	return common.BytesToAddress(output[0]), utils.DecodeError(output[1])
 }
 
+// ScheduleCall schedules a call from the precompile to address at the start of
+// the given future block. The gas of the call is charged immediately.
+func (env *Env) ScheduleCall(address common.Address, block uint64, data []byte, gas uint64) error {
+	input := [][]byte{address.Bytes(), utils.Uint64ToBytes(block), utils.Uint64ToBytes(gas), data}
+	output, err := env.execute(ScheduleCall_OpCode, input)
+	if err != nil {
+		return err
+	}
+	return utils.DecodeError(output[0])
+}
+
 var _ Environment = (*Env)(nil)
@@ -21,9 +21,18 @@
 package api
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/bn256"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/stretchr/testify/require"
 )
 
@@ -135,3 +144,167 @@ func TestTrustAndWriteProtection(t *testing.T) {
 		}
 	}
 }
+
+func TestNoLogs(t *testing.T) {
+	var (
+		r        = require.New(t)
+		address  = common.HexToAddress("0xc0ffee0001")
+		config   = EnvConfig{Ephemeral: true, NoLogs: true}
+		meterGas = false
+		gas      = uint64(0)
+	)
+	env := NewMockEnvironment(address, config, meterGas, gas)
+	env.PersistentStore(common.Hash{1}, common.Hash{2})
+	r.NoError(env.Error())
+	env.Log([]common.Hash{{1}}, nil)
+	r.Equal(ErrLogsDisabled, env.Error())
+}
+
+func TestIsolatedStorageRootProtected(t *testing.T) {
+	var (
+		r        = require.New(t)
+		address  = common.HexToAddress("0xc0ffee0001")
+		config   = EnvConfig{Ephemeral: true}
+		meterGas = false
+		gas      = uint64(0)
+	)
+	env := NewMockEnvironment(address, config, meterGas, gas)
+	env.PersistentStore(IsolatedStorageRootKey, common.Hash{2})
+	r.Equal(ErrWriteProtection, env.Error())
+}
+
+func TestManyOps(t *testing.T) {
+	var (
+		r       = require.New(t)
+		address = common.HexToAddress("0xc0ffee0001")
+		config  = EnvConfig{
+			Static:    false,
+			Ephemeral: false,
+			Trusted:   false,
+		}
+		meterGas = true
+		gas      = uint64(1e6)
+		data     = []byte("concrete")
+	)
+
+	env := NewMockEnvironment(address, config, meterGas, gas)
+	expHash := env.Keccak256(data)
+	expAddress := env.GetAddress()
+	expGasUsed := gas - env.Gas()
+
+	env = NewMockEnvironment(address, config, meterGas, gas)
+	batch := env.Batch()
+	hash := batch.Keccak256(data)
+	addr := batch.Add(GetAddress_OpCode, nil)
+	r.Equal(2, batch.Len())
+	r.NoError(batch.Execute())
+	r.Equal(0, batch.Len())
+	r.Equal(expHash, hash.Hash())
+	r.Equal([][]byte{expAddress.Bytes()}, addr.Output())
+	r.Equal(expGasUsed, gas-env.Gas())
+	r.NoError(env.Error())
+
+	// Execution stops at the first failing operation
+	env = NewMockEnvironment(address, config, meterGas, gas)
+	batch = env.Batch()
+	batch.Add(EphemeralLoad_OpCode, [][]byte{common.Hash{}.Bytes()})
+	hash = batch.Keccak256(data)
+	r.Equal(ErrEnvNotTrusted, batch.Execute())
+	r.Equal(ErrEnvNotTrusted, env.Error())
+	r.Nil(hash.Output())
+	r.Equal(gas, env.Gas())
+
+	// Nested batches are not allowed
+	env = NewMockEnvironment(address, config, meterGas, gas)
+	_, err := env.Execute(ManyOps_OpCode, [][]byte{EncodeOp(ManyOps_OpCode, nil)})
+	r.Equal(ErrInvalidInput, err)
+}
+
+func TestCryptoOps(t *testing.T) {
+	var (
+		r       = require.New(t)
+		address = common.HexToAddress("0xc0ffee0001")
+		data    = []byte("concrete")
+		hash    = crypto.Keccak256Hash(data)
+	)
+	newEnv := func() *Env {
+		return NewMockEnvironment(address, EnvConfig{Static: true}, true, 1e7)
+	}
+
+	env := newEnv()
+	r.Equal(common.Hash(sha256.Sum256(data)), env.Sha256(data))
+	r.Equal(params.Sha256BaseGas+params.Sha256PerWordGas, 1e7-env.Gas())
+
+	// secp256k1
+	key, _ := crypto.GenerateKey()
+	sig, err := crypto.Sign(hash.Bytes(), key)
+	r.NoError(err)
+	signer, err := env.Ecrecover(hash, sig)
+	r.NoError(err)
+	r.Equal(crypto.PubkeyToAddress(key.PublicKey), signer)
+	sig[64] = 2
+	_, err = env.Ecrecover(hash, sig)
+	r.Error(err)
+	r.NoError(env.Error())
+
+	// secp256r1
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	sr, ss, err := ecdsa.Sign(rand.Reader, p256Key, hash.Bytes())
+	r.NoError(err)
+	p256Sig := append(common.BigToHash(sr).Bytes(), common.BigToHash(ss).Bytes()...)
+	p256PubKey := append(common.BigToHash(p256Key.X).Bytes(), common.BigToHash(p256Key.Y).Bytes()...)
+	r.True(env.P256Verify(hash, p256Sig, p256PubKey))
+	r.False(env.P256Verify(common.Hash{}, p256Sig, p256PubKey))
+
+	// bn256
+	g1 := new(bn256.G1).ScalarBaseMult(big.NewInt(1)).Marshal()
+	sum, err := env.Bn256Add(g1, g1)
+	r.NoError(err)
+	product, err := env.Bn256ScalarMul(g1, common.BigToHash(big.NewInt(2)))
+	r.NoError(err)
+	r.Equal(product, sum)
+	_, err = env.Bn256Add(g1, make([]byte, 63))
+	r.Equal(ErrInvalidInput, err)
+
+	env = newEnv()
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1)).Marshal()
+	negG1 := new(bn256.G1).Neg(new(bn256.G1).ScalarBaseMult(big.NewInt(1))).Marshal()
+	ok, err := env.Bn256Pairing(append(append(append(g1, g2...), negG1...), g2...))
+	r.NoError(err)
+	r.True(ok)
+	r.Equal(params.Bn256PairingBaseGasIstanbul+2*params.Bn256PairingPerPointGasIstanbul, 1e7-env.Gas())
+	ok, err = env.Bn256Pairing(append(append(append(g1, g2...), g1...), g2...))
+	r.NoError(err)
+	r.False(ok)
+	_, err = env.Bn256Pairing(append(make([]byte, 63), 1))
+	r.Equal(ErrInvalidInput, err)
+
+	// KZG point evaluation
+	env = newEnv()
+	var blob kzg4844.Blob
+	blob[31] = 1
+	commitment, err := kzg4844.BlobToCommitment(blob)
+	r.NoError(err)
+	point := kzg4844.Point{31: 2}
+	proof, claim, err := kzg4844.ComputeProof(blob, point)
+	r.NoError(err)
+	r.NoError(env.KZGPointEvaluation(commitment[:], common.Hash(point), common.Hash(claim), proof[:]))
+	r.Error(env.KZGPointEvaluation(commitment[:], common.Hash(point), common.Hash{}, proof[:]))
+	r.NoError(env.Error())
+}
+
+func TestScheduleCallInput(t *testing.T) {
+	var (
+		r       = require.New(t)
+		address = common.HexToAddress("0xc0ffee0001")
+	)
+	// Input is validated when gas is not metered too
+	for _, meterGas := range []bool{true, false} {
+		env := NewMockEnvironment(address, EnvConfig{Trusted: true}, meterGas, 1e6)
+		_, err := env.Execute(ScheduleCall_OpCode, [][]byte{{0x01}})
+		r.Equal(ErrInvalidInput, err)
+		env = NewMockEnvironment(address, EnvConfig{Trusted: true}, meterGas, 1e6)
+		_, err = env.Execute(ScheduleCall_OpCode, [][]byte{address.Bytes(), {0x01}, {0x02}, nil})
+		r.Equal(ErrInvalidInput, err)
+	}
+}
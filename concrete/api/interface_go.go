@@ -51,4 +51,6 @@ type StateDB interface {
 	GetPersistentState(addr common.Address, key common.Hash) common.Hash
 	SetEphemeralState(addr common.Address, key common.Hash, value common.Hash)
 	GetEphemeralState(addr common.Address, key common.Hash) common.Hash
+	SetIsolatedState(addr common.Address, key common.Hash, value common.Hash)
+	GetIsolatedState(addr common.Address, key common.Hash) common.Hash
 }
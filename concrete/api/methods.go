@@ -27,6 +27,7 @@ var (
 	ErrInvalidInput      = errors.New("invalid input")
 	ErrNoData            = errors.New("no data")
 	ErrExecutionReverted = errors.New("execution reverted")
+	ErrLogsDisabled      = errors.New("logs disabled")
 )
 
 const (
@@ -21,20 +21,30 @@
 package api
 
 import (
+	"crypto/sha256"
 	"errors"
+	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
-	"github.com/ethereum/go-ethereum/concrete/crypto"
+	"github.com/ethereum/go-ethereum/concrete/scheduler"
 	"github.com/ethereum/go-ethereum/concrete/utils"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/bn256"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/crypto/secp256r1"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/holiman/uint256"
 )
 
 func newEnvironmentMethods() JumpTable {
 	tbl := JumpTable{
+		ManyOps_OpCode: {
+			execute: opManyOps,
+			static:  true,
+		},
 		EnableGasMetering_OpCode: {
 			execute: opEnableGasMetering,
 			trusted: true,
@@ -56,6 +66,43 @@ func newEnvironmentMethods() JumpTable {
 			dynamicGas:  gasKeccak256,
 			static:      true,
 		},
+		Sha256_OpCode: {
+			execute:     opSha256,
+			constantGas: params.Sha256BaseGas,
+			dynamicGas:  gasSha256,
+			static:      true,
+		},
+		Ecrecover_OpCode: {
+			execute:     opEcrecover,
+			constantGas: params.EcrecoverGas,
+			static:      true,
+		},
+		P256Verify_OpCode: {
+			execute:     opP256Verify,
+			constantGas: params.P256VerifyGas,
+			static:      true,
+		},
+		Bn256Add_OpCode: {
+			execute:     opBn256Add,
+			constantGas: params.Bn256AddGasIstanbul,
+			static:      true,
+		},
+		Bn256ScalarMul_OpCode: {
+			execute:     opBn256ScalarMul,
+			constantGas: params.Bn256ScalarMulGasIstanbul,
+			static:      true,
+		},
+		Bn256Pairing_OpCode: {
+			execute:     opBn256Pairing,
+			constantGas: params.Bn256PairingBaseGasIstanbul,
+			dynamicGas:  gasBn256Pairing,
+			static:      true,
+		},
+		KZGPointEvaluation_OpCode: {
+			execute:     opKZGPointEvaluation,
+			constantGas: params.BlobTxPointEvaluationPrecompileGas,
+			static:      true,
+		},
 		UseGas_OpCode: {
 			execute:    opUseGas,
 			dynamicGas: gasUseGas,
@@ -175,6 +222,16 @@ func newEnvironmentMethods() JumpTable {
 			constantGas: 0,
 			static:      true,
 		},
+		IsolatedLoad_OpCode: {
+			execute:     opIsolatedLoad,
+			constantGas: params.SloadGasEIP2200,
+			static:      true,
+		},
+		IsolatedStore_OpCode: {
+			execute:     opIsolatedStore,
+			constantGas: params.SstoreResetGasEIP2200,
+			static:      false,
+		},
 		StorageStore_OpCode: {
 			execute:    opStorageStore,
 			dynamicGas: gasStorageStore,
@@ -239,6 +296,11 @@ func newEnvironmentMethods() JumpTable {
 			dynamicGas:  gasCreate2,
 			static:      false,
 		},
+		ScheduleCall_OpCode: {
+			execute:    opScheduleCall,
+			dynamicGas: gasScheduleCall,
+			static:     false,
+		},
 	}
 
 	for i, entry := range tbl {
@@ -288,6 +350,27 @@ func opUndefined(env *Env, args [][]byte) ([][]byte, error) {
 	return nil, ErrInvalidOpCode
 }
 
+func opManyOps(env *Env, args [][]byte) ([][]byte, error) {
+	// Each operation goes through the regular execution path, so trust, write
+	// protection and gas are checked per operation.
+	output := make([][]byte, 0, len(args))
+	for _, arg := range args {
+		op, opArgs, err := DecodeOp(arg)
+		if err != nil {
+			return nil, err
+		}
+		if op == ManyOps_OpCode {
+			return nil, ErrInvalidInput
+		}
+		opOutput, err := execute(op, env, opArgs)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, utils.EncodeValues(opOutput))
+	}
+	return output, nil
+}
+
 func opEnableGasMetering(env *Env, args [][]byte) ([][]byte, error) {
 	if len(args) != 1 || len(args[0]) != 1 {
 		return nil, ErrInvalidInput
@@ -332,10 +415,165 @@ func gasKeccak256(env *Env, args [][]byte) (uint64, error) {
 }
 
 func opKeccak256(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 1 {
+		return nil, ErrInvalidInput
+	}
 	hash := crypto.Keccak256(args[0])
 	return [][]byte{hash}, nil
 }
 
+func gasSha256(env *Env, args [][]byte) (uint64, error) {
+	if len(args) != 1 {
+		return 0, ErrInvalidInput
+	}
+	wordSize := toWordSize(len(args[0]))
+	gas := wordSize * params.Sha256PerWordGas
+	return gas, nil
+}
+
+func opSha256(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 1 {
+		return nil, ErrInvalidInput
+	}
+	hash := sha256.Sum256(args[0])
+	return [][]byte{hash[:]}, nil
+}
+
+var (
+	errInvalidSignature  = errors.New("invalid signature")
+	errInvalidCurvePoint = errors.New("invalid curve point")
+	errInvalidProof      = errors.New("invalid proof")
+)
+
+// The following operations return invalid signatures, points and proofs as an
+// encoded error in their last output, as they are expected to be handled by
+// the precompile rather than abort its execution.
+
+func opEcrecover(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 2 || len(args[0]) != 32 || len(args[1]) != crypto.SignatureLength {
+		return nil, ErrInvalidInput
+	}
+	hash, sig := args[0], args[1]
+	r, s, v := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64]), sig[64]
+	if !crypto.ValidateSignatureValues(v, r, s, false) {
+		return [][]byte{nil, utils.EncodeError(errInvalidSignature)}, nil
+	}
+	pubKey, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		return [][]byte{nil, utils.EncodeError(errInvalidSignature)}, nil
+	}
+	address := common.BytesToAddress(crypto.Keccak256(pubKey[1:])[12:])
+	return [][]byte{address.Bytes(), utils.EncodeError(nil)}, nil
+}
+
+func opP256Verify(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 3 || len(args[0]) != 32 || len(args[1]) != 64 || len(args[2]) != 64 {
+		return nil, ErrInvalidInput
+	}
+	var (
+		hash   = args[0]
+		r, s   = new(big.Int).SetBytes(args[1][:32]), new(big.Int).SetBytes(args[1][32:])
+		x, y   = new(big.Int).SetBytes(args[2][:32]), new(big.Int).SetBytes(args[2][32:])
+		result = []byte{0x00}
+	)
+	if secp256r1.Verify(hash, r, s, x, y) {
+		result[0] = 0x01
+	}
+	return [][]byte{result}, nil
+}
+
+func newBn256G1(data []byte) (*bn256.G1, error) {
+	p := new(bn256.G1)
+	if _, err := p.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func opBn256Add(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 2 || len(args[0]) != 64 || len(args[1]) != 64 {
+		return nil, ErrInvalidInput
+	}
+	a, err := newBn256G1(args[0])
+	if err != nil {
+		return [][]byte{nil, utils.EncodeError(errInvalidCurvePoint)}, nil
+	}
+	b, err := newBn256G1(args[1])
+	if err != nil {
+		return [][]byte{nil, utils.EncodeError(errInvalidCurvePoint)}, nil
+	}
+	res := new(bn256.G1).Add(a, b)
+	return [][]byte{res.Marshal(), utils.EncodeError(nil)}, nil
+}
+
+func opBn256ScalarMul(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 2 || len(args[0]) != 64 || len(args[1]) != 32 {
+		return nil, ErrInvalidInput
+	}
+	p, err := newBn256G1(args[0])
+	if err != nil {
+		return [][]byte{nil, utils.EncodeError(errInvalidCurvePoint)}, nil
+	}
+	res := new(bn256.G1).ScalarMult(p, new(big.Int).SetBytes(args[1]))
+	return [][]byte{res.Marshal(), utils.EncodeError(nil)}, nil
+}
+
+func gasBn256Pairing(env *Env, args [][]byte) (uint64, error) {
+	if len(args) != 1 || len(args[0])%192 != 0 {
+		return 0, ErrInvalidInput
+	}
+	pairs := uint64(len(args[0]) / 192)
+	return pairs * params.Bn256PairingPerPointGasIstanbul, nil
+}
+
+func opBn256Pairing(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 1 || len(args[0])%192 != 0 {
+		return nil, ErrInvalidInput
+	}
+	var (
+		input = args[0]
+		g1s   []*bn256.G1
+		g2s   []*bn256.G2
+	)
+	for ii := 0; ii < len(input); ii += 192 {
+		g1, err := newBn256G1(input[ii : ii+64])
+		if err != nil {
+			return [][]byte{nil, utils.EncodeError(errInvalidCurvePoint)}, nil
+		}
+		g2 := new(bn256.G2)
+		if _, err := g2.Unmarshal(input[ii+64 : ii+192]); err != nil {
+			return [][]byte{nil, utils.EncodeError(errInvalidCurvePoint)}, nil
+		}
+		g1s = append(g1s, g1)
+		g2s = append(g2s, g2)
+	}
+	result := []byte{0x00}
+	if bn256.PairingCheck(g1s, g2s) {
+		result[0] = 0x01
+	}
+	return [][]byte{result, utils.EncodeError(nil)}, nil
+}
+
+func opKZGPointEvaluation(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 4 || len(args[0]) != 48 || len(args[1]) != 32 || len(args[2]) != 32 || len(args[3]) != 48 {
+		return nil, ErrInvalidInput
+	}
+	var (
+		commitment kzg4844.Commitment
+		point      kzg4844.Point
+		claim      kzg4844.Claim
+		proof      kzg4844.Proof
+	)
+	copy(commitment[:], args[0])
+	copy(point[:], args[1])
+	copy(claim[:], args[2])
+	copy(proof[:], args[3])
+	if err := kzg4844.VerifyProof(commitment, point, claim, proof); err != nil {
+		return [][]byte{utils.EncodeError(errInvalidProof)}, nil
+	}
+	return [][]byte{utils.EncodeError(nil)}, nil
+}
+
 func gasUseGas(env *Env, args [][]byte) (uint64, error) {
 	if len(args) != 1 {
 		return 0, ErrInvalidInput
@@ -656,13 +894,49 @@ func gasStorageStore(env *Env, args [][]byte) (uint64, error) {
 	return cost + params.WarmStorageReadCostEIP2929, nil
 }
 
+// IsolatedStorageRootKey is the storage slot of a precompile holding the root
+// of its isolated storage. It is only written by the state, never by the
+// precompile itself.
+var IsolatedStorageRootKey = crypto.Keccak256Hash([]byte("concrete.isolated.root"))
+
 func opStorageStore(env *Env, args [][]byte) ([][]byte, error) {
 	key := common.BytesToHash(args[0])
+	if key == IsolatedStorageRootKey {
+		return nil, ErrWriteProtection
+	}
 	value := common.BytesToHash(args[1])
 	env.statedb.SetPersistentState(env.address, key, value)
 	return nil, nil
 }
 
+// Isolated storage is not hashed into the state on every write, so it is
+// charged a flat cost with no cold access or refunds.
+
+func opIsolatedLoad(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 1 {
+		return nil, ErrInvalidInput
+	}
+	if len(args[0]) != 32 {
+		return nil, ErrInvalidInput
+	}
+	key := common.BytesToHash(args[0])
+	value := env.statedb.GetIsolatedState(env.address, key)
+	return [][]byte{value.Bytes()}, nil
+}
+
+func opIsolatedStore(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 2 {
+		return nil, ErrInvalidInput
+	}
+	if len(args[0]) != 32 || len(args[1]) != 32 {
+		return nil, ErrInvalidInput
+	}
+	key := common.BytesToHash(args[0])
+	value := common.BytesToHash(args[1])
+	env.statedb.SetIsolatedState(env.address, key, value)
+	return nil, nil
+}
+
 func gasLog(env *Env, args [][]byte) (uint64, error) {
 	if len(args) == 0 || len(args) > 5 {
 		return 0, ErrInvalidInput
@@ -925,3 +1199,55 @@ func opCreate2(env *Env, args [][]byte) ([][]byte, error) {
 	env.gas += gasLeft
 	return [][]byte{address.Bytes(), utils.EncodeError(err)}, nil
 }
+
+func validateScheduleCallArgs(args [][]byte) error {
+	if len(args) != 4 {
+		return ErrInvalidInput
+	}
+	if len(args[0]) != 20 || len(args[1]) != 8 || len(args[2]) != 8 {
+		return ErrInvalidInput
+	}
+	if len(args[3]) > scheduler.MaxDataSize {
+		return ErrInvalidInput
+	}
+	if utils.BytesToUint64(args[2]) > scheduler.MaxGasPerBlock {
+		return ErrInvalidInput
+	}
+	return nil
+}
+
+func gasScheduleCall(env *Env, args [][]byte) (uint64, error) {
+	if err := validateScheduleCallArgs(args); err != nil {
+		return 0, err
+	}
+	// The gas of the scheduled call is paid upfront, as the call is executed
+	// without a gas price. The data size is bounded so this cannot overflow.
+	var (
+		wordSize   = toWordSize(len(args[3]))
+		storageGas = (6 + wordSize) * params.SstoreSetGasEIP2200
+		callGas    = utils.BytesToUint64(args[2])
+	)
+	return storageGas + callGas, nil
+}
+
+func opScheduleCall(env *Env, args [][]byte) ([][]byte, error) {
+	if err := validateScheduleCallArgs(args); err != nil {
+		return nil, err
+	}
+	if env.block == nil {
+		return nil, ErrNoData
+	}
+	var (
+		to    = common.BytesToAddress(args[0])
+		block = utils.BytesToUint64(args[1])
+		gas   = utils.BytesToUint64(args[2])
+		data  = common.CopyBytes(args[3])
+	)
+	err := scheduler.Enqueue(env.statedb, env.block.BlockNumber(), block, scheduler.Call{
+		From: env.address,
+		To:   to,
+		Gas:  gas,
+		Data: data,
+	})
+	return [][]byte{utils.EncodeError(err)}, nil
+}
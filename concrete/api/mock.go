@@ -66,6 +66,10 @@ func (m *mockStateDB) SetEphemeralState(addr common.Address, key common.Hash, va
 func (m *mockStateDB) GetEphemeralState(addr common.Address, key common.Hash) common.Hash {
 	return common.Hash{}
 }
+func (m *mockStateDB) SetIsolatedState(addr common.Address, key common.Hash, value common.Hash) {}
+func (m *mockStateDB) GetIsolatedState(addr common.Address, key common.Hash) common.Hash {
+	return common.Hash{}
+}
 
 func (m *mockStateDB) AddRefund(uint64)  {}
 func (m *mockStateDB) SubRefund(uint64)  {}
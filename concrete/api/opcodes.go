@@ -36,6 +36,14 @@ const (
 	// Utils
 	Keccak256_OpCode OpCode = 0x10
 	UseGas_OpCode    OpCode = 0x50
+	// Cryptography
+	Sha256_OpCode             OpCode = 0x11
+	Ecrecover_OpCode          OpCode = 0x12
+	P256Verify_OpCode         OpCode = 0x13
+	Bn256Add_OpCode           OpCode = 0x14
+	Bn256ScalarMul_OpCode     OpCode = 0x15
+	Bn256Pairing_OpCode       OpCode = 0x16
+	KZGPointEvaluation_OpCode OpCode = 0x17
 	// Ephemeral
 	EphemeralStore_OpCode OpCode = 0x20
 	EphemeralLoad_OpCode  OpCode = 0x21
@@ -60,9 +68,11 @@ const (
 	StorageLoad_OpCode        OpCode = 0x41
 	GetCode_OpCode            OpCode = 0x42
 	GetCodeSize_OpCode        OpCode = 0x43
+	IsolatedLoad_OpCode       OpCode = 0x44
 	// Internal writes
-	StorageStore_OpCode OpCode = 0x51
-	Log_OpCode          OpCode = 0x52
+	StorageStore_OpCode  OpCode = 0x51
+	Log_OpCode           OpCode = 0x52
+	IsolatedStore_OpCode OpCode = 0x53
 	// External reads
 	GetExternalBalance_OpCode  OpCode = 0x60
 	CallStatic_OpCode          OpCode = 0x61
@@ -74,4 +84,5 @@ const (
 	CallDelegate_OpCode OpCode = 0x71
 	Create_OpCode       OpCode = 0x72
 	Create2_OpCode      OpCode = 0x73
+	ScheduleCall_OpCode OpCode = 0x74
 )
@@ -23,6 +23,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/concrete/codegen/datamod"
+	"github.com/ethereum/go-ethereum/concrete/codegen/dispatchgen"
 	"github.com/ethereum/go-ethereum/concrete/codegen/solgen"
 	"github.com/ethereum/go-ethereum/internal/version"
 	"github.com/spf13/cobra"
@@ -83,6 +84,8 @@ func main() {
 	cmdSolgen.Flags().String("solidity", "", "path to the solidity file")
 	cmdSolgen.Flags().StringP("name", "n", "", "name for the generated library")
 	cmdSolgen.Flags().StringP("address", "a", "", "precompile address")
+	cmdSolgen.Flags().String("go-out", "", "path to write go bindings for the precompile to (optional)")
+	cmdSolgen.Flags().String("go-pkg", "main", "package name for the go bindings")
 	rootCmd.AddCommand(cmdSolgen)
 
 	var cmdDatamod = &cobra.Command{
@@ -95,8 +98,32 @@ func main() {
 	cmdDatamod.Flags().String("out", "./", "dir to write the generated files to")
 	cmdDatamod.Flags().String("pkg", "main", "package name for the generated files")
 	cmdDatamod.Flags().Bool("table-type-experimental", false, "whether to enable experimental table value type")
+	cmdDatamod.Flags().String("sol", "", "dir to write the generated solidity libraries to (optional)")
+	cmdDatamod.Flags().String("migrate-from", "", "previous json definition to generate migration helpers from (optional)")
 	rootCmd.AddCommand(cmdDatamod)
 
+	var cmdDatamodDiff = &cobra.Command{
+		Use:   "datamod-diff <old path> <new path>",
+		Short: "Compare two versions of a data model json definition and classify the changes",
+		Args:  cobra.ExactArgs(2),
+		Run:   runDatamodDiff,
+	}
+
+	cmdDatamodDiff.Flags().Bool("table-type-experimental", false, "whether to enable experimental table value type")
+	rootCmd.AddCommand(cmdDatamodDiff)
+
+	var cmdDispatchgen = &cobra.Command{
+		Use:   "dispatchgen <package dir>",
+		Short: "Generate the ABI dispatcher, ABI JSON and solidity interface of a precompile from its annotated go methods",
+		Args:  cobra.ExactArgs(1),
+		Run:   runDispatchgen,
+	}
+
+	cmdDispatchgen.Flags().String("type", "", "name of the precompile type")
+	cmdDispatchgen.Flags().StringP("name", "n", "", "name for the generated interface and ABI (defaults to the type name without the Precompile suffix)")
+	cmdDispatchgen.Flags().String("out", "./", "dir to write the ABI and solidity interface to")
+	rootCmd.AddCommand(cmdDispatchgen)
+
 	if err := rootCmd.Execute(); err != nil {
 		exit(err.Error())
 	}
@@ -113,6 +140,10 @@ func runSolgen(cmd *cobra.Command, args []string) {
 	checkErr(err)
 	address, err := cmd.Flags().GetString("address")
 	checkErr(err)
+	goOutPath, err := cmd.Flags().GetString("go-out")
+	checkErr(err)
+	goPkg, err := cmd.Flags().GetString("go-pkg")
+	checkErr(err)
 
 	if abiPath == "" {
 		exit("ABI file path (--abi) must be provided")
@@ -146,12 +177,20 @@ func runSolgen(cmd *cobra.Command, args []string) {
 		outPath = filepath.Join(outPath, name+".sol")
 	}
 
+	if goOutPath != "" {
+		if info, err := os.Stat(goOutPath); err == nil && info.IsDir() {
+			goOutPath = filepath.Join(goOutPath, strings.ToLower(name)+".go")
+		}
+	}
+
 	config := solgen.Config{
-		Name:    name,
-		Address: common.HexToAddress(address),
-		ABI:     abiPath,
-		Out:     outPath,
-		Sol:     solPath,
+		Name:      name,
+		Address:   common.HexToAddress(address),
+		ABI:       abiPath,
+		Out:       outPath,
+		Sol:       solPath,
+		GoOut:     goOutPath,
+		GoPackage: goPkg,
 	}
 
 	fmt.Printf(`Generating solidity library
@@ -166,6 +205,12 @@ Solidity : %s
 	checkErr(err)
 
 	fmt.Printf("Library generated successfully.\nLibrary written to: %s\n", outPath)
+
+	if goOutPath != "" {
+		err = solgen.GenerateGoBindings(config)
+		checkErr(err)
+		fmt.Println("Go bindings written to:", goOutPath)
+	}
 }
 
 func runDatamod(cmd *cobra.Command, args []string) {
@@ -176,6 +221,10 @@ func runDatamod(cmd *cobra.Command, args []string) {
 	checkErr(err)
 	allowTableTypes, err := cmd.Flags().GetBool("table-type-experimental")
 	checkErr(err)
+	solPath, err := cmd.Flags().GetString("sol")
+	checkErr(err)
+	prevPath, err := cmd.Flags().GetString("migrate-from")
+	checkErr(err)
 
 	jsonIsDir, err := isDir(jsonPath)
 	checkErr(err)
@@ -189,10 +238,20 @@ func runDatamod(cmd *cobra.Command, args []string) {
 		exit("Output path must be a directory")
 	}
 
+	if solPath != "" {
+		solIsDir, err := isDir(solPath)
+		checkErr(err)
+		if !solIsDir {
+			exit("Solidity output path must be a directory")
+		}
+	}
+
 	config := datamod.Config{
-		JSON:    jsonPath,
-		Out:     outPath,
-		Package: pkg,
+		JSON:     jsonPath,
+		Out:      outPath,
+		Package:  pkg,
+		Solidity: solPath,
+		Previous: prevPath,
 	}
 
 	fmt.Println("Generating data model wrappers for:", jsonPath)
@@ -201,4 +260,69 @@ func runDatamod(cmd *cobra.Command, args []string) {
 	checkErr(err)
 
 	fmt.Println("Data model wrappers generated successfully.\nFiles written to:", outPath)
+	if solPath != "" {
+		fmt.Println("Solidity libraries written to:", solPath)
+	}
+}
+
+func runDatamodDiff(cmd *cobra.Command, args []string) {
+	allowTableTypes, err := cmd.Flags().GetBool("table-type-experimental")
+	checkErr(err)
+
+	oldContent, err := os.ReadFile(args[0])
+	checkErr(err)
+	newContent, err := os.ReadFile(args[1])
+	checkErr(err)
+
+	changes, err := datamod.DiffDataModels(oldContent, newContent, allowTableTypes)
+	checkErr(err)
+
+	if len(changes) == 0 {
+		fmt.Println("No changes.")
+		return
+	}
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	if datamod.HasBreakingChanges(changes) {
+		exit("Breaking changes found, bump the version of the affected tables.")
+	}
+}
+
+func runDispatchgen(cmd *cobra.Command, args []string) {
+	dir := args[0]
+	typeName, err := cmd.Flags().GetString("type")
+	checkErr(err)
+	name, err := cmd.Flags().GetString("name")
+	checkErr(err)
+	outPath, err := cmd.Flags().GetString("out")
+	checkErr(err)
+
+	if typeName == "" {
+		exit("Precompile type (--type) must be provided")
+	}
+	dirIsDir, err := isDir(dir)
+	checkErr(err)
+	if !dirIsDir {
+		exit("Package path must be a directory")
+	}
+	outIsDir, err := isDir(outPath)
+	checkErr(err)
+	if !outIsDir {
+		exit("Output path must be a directory")
+	}
+
+	config := dispatchgen.Config{
+		Dir:  dir,
+		Type: typeName,
+		Name: name,
+		Out:  outPath,
+	}
+
+	fmt.Println("Generating dispatcher for:", typeName)
+
+	err = dispatchgen.Generate(config)
+	checkErr(err)
+
+	fmt.Println("Dispatcher generated successfully.\nABI and solidity interface written to:", outPath)
 }
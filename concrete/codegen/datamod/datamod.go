@@ -20,6 +20,7 @@ import (
 	_ "embed"
 	"encoding/json"
 	"fmt"
+	"go/format"
 	"os"
 	"path/filepath"
 	"strings"
@@ -32,23 +33,27 @@ import (
 var tableTpl string
 
 type FieldSchema struct {
-	Name  string
-	Title string
-	Index int
-	Type  FieldType
+	Name   string
+	Title  string
+	Index  int
+	Offset int // Index of the first struct field the field is packed into
+	Type   FieldType
 }
 
 type TableSchema struct {
-	Name   string
-	Keys   []FieldSchema
-	Values []FieldSchema
+	Name     string
+	Version  int // Version of the storage layout, part of the table slot
+	Keys     []FieldSchema
+	Values   []FieldSchema
+	Iterable bool // Whether the keys in use are indexed
+	Events   bool // Whether writes emit store events
 }
 
-func newFieldSchema(name string, index int, typeStr string) (FieldSchema, error) {
+func newFieldSchema(types *typeSet, name string, index int, typeStr string) (FieldSchema, error) {
 	if !isValidName(name) {
 		return FieldSchema{}, fmt.Errorf("invalid field name '%s'", name)
 	}
-	fieldType, err := nameToFieldType(typeStr)
+	fieldType, err := types.fieldType(typeStr)
 	if err != nil {
 		return FieldSchema{}, fmt.Errorf("invalid type '%s' for field '%s': %w", typeStr, name, err)
 	}
@@ -60,15 +65,96 @@ func newFieldSchema(name string, index int, typeStr string) (FieldSchema, error)
 	}, nil
 }
 
-func unmarshalTableSchemas(jsonContent []byte, allowTableTypes bool) ([]TableSchema, error) {
+// The enums and structs of a data model are declared under these top level
+// keys, which therefore cannot be used as table names.
+const (
+	enumsKey   = "enums"
+	structsKey = "structs"
+)
+
+func unmarshalTypes(jsonSchemas *orderedmap.OrderedMap) (*typeSet, error) {
+	types := newTypeSet()
+
+	if _jsonEnums, ok := jsonSchemas.Get(enumsKey); ok {
+		jsonEnums, ok := _jsonEnums.(orderedmap.OrderedMap)
+		if !ok {
+			return nil, fmt.Errorf("invalid enums")
+		}
+		for _, enumName := range jsonEnums.Keys() {
+			_jsonMembers, _ := jsonEnums.Get(enumName)
+			jsonMembers, ok := _jsonMembers.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid members for enum '%s'", enumName)
+			}
+			members := make([]string, len(jsonMembers))
+			for ii, _member := range jsonMembers {
+				member, ok := _member.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid members for enum '%s'", enumName)
+				}
+				members[ii] = member
+			}
+			if err := types.declareEnum(enumName, members); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Structs can only use the structs declared before them, which rules out
+	// recursive structs.
+	if _jsonStructs, ok := jsonSchemas.Get(structsKey); ok {
+		jsonStructs, ok := _jsonStructs.(orderedmap.OrderedMap)
+		if !ok {
+			return nil, fmt.Errorf("invalid structs")
+		}
+		for _, structName := range jsonStructs.Keys() {
+			_jsonFields, _ := jsonStructs.Get(structName)
+			jsonFields, ok := _jsonFields.(orderedmap.OrderedMap)
+			if !ok {
+				return nil, fmt.Errorf("invalid schema for struct '%s'", structName)
+			}
+			var fields []FieldSchema
+			for _, fieldName := range jsonFields.Keys() {
+				_fieldType, _ := jsonFields.Get(fieldName)
+				fieldType, ok := _fieldType.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid schema for field '%s' in struct '%s'", fieldName, structName)
+				}
+				fieldSchema, err := newFieldSchema(types, fieldName, len(fields), fieldType)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, fieldSchema)
+			}
+			if err := types.declareStruct(structName, fields); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return types, nil
+}
+
+func unmarshalTableSchemas(jsonContent []byte, allowTableTypes bool) ([]TableSchema, *typeSet, error) {
 	jsonSchemas := orderedmap.New()
 	err := json.Unmarshal(jsonContent, &jsonSchemas)
 	if err != nil {
-		return []TableSchema{}, err
+		return []TableSchema{}, nil, err
+	}
+	types, err := unmarshalTypes(jsonSchemas)
+	if err != nil {
+		return []TableSchema{}, nil, err
 	}
+	tableSchemas, err := unmarshalTables(jsonSchemas, types, allowTableTypes)
+	return tableSchemas, types, err
+}
 
+func unmarshalTables(jsonSchemas *orderedmap.OrderedMap, types *typeSet, allowTableTypes bool) ([]TableSchema, error) {
 	var tableSchemas []TableSchema
 	for _, tableName := range jsonSchemas.Keys() {
+		if tableName == enumsKey || tableName == structsKey {
+			continue
+		}
 		_jsonTableSchema, _ := jsonSchemas.Get(tableName)
 		jsonTableSchema, ok := _jsonTableSchema.(orderedmap.OrderedMap)
 		if !ok {
@@ -82,7 +168,16 @@ func unmarshalTableSchemas(jsonContent []byte, allowTableTypes bool) ([]TableSch
 			return []TableSchema{}, fmt.Errorf("no schema for table '%s'", tableName)
 		}
 
-		tableSchema := TableSchema{Name: upperFirstLetter(tableName)}
+		tableSchema := TableSchema{Name: upperFirstLetter(tableName), Version: 1}
+
+		_jsonVersion, ok := jsonTableSchema.Get("version")
+		if ok {
+			version, ok := _jsonVersion.(float64)
+			if !ok || version < 1 || version != float64(int(version)) {
+				return []TableSchema{}, fmt.Errorf("invalid version for table '%s'", tableName)
+			}
+			tableSchema.Version = int(version)
+		}
 
 		_jsonKeySchema, ok := jsonTableSchema.Get("keySchema")
 		if ok {
@@ -96,13 +191,16 @@ func unmarshalTableSchemas(jsonContent []byte, allowTableTypes bool) ([]TableSch
 				if !ok {
 					return []TableSchema{}, fmt.Errorf("invalid schema for key '%s' in table '%s'", keyName, tableName)
 				}
-				fieldSchema, err := newFieldSchema(keyName, len(tableSchema.Keys), keyType)
+				fieldSchema, err := newFieldSchema(types, keyName, len(tableSchema.Keys), keyType)
 				if err != nil {
 					return []TableSchema{}, err
 				}
 				if fieldSchema.Type.Type == TableType {
 					return []TableSchema{}, fmt.Errorf("table '%s' cannot have table keys", tableName)
 				}
+				if fieldSchema.Type.Type != ValueType && fieldSchema.Type.Type != BytesType {
+					return []TableSchema{}, fmt.Errorf("invalid type '%s' for key '%s' in table '%s': keys cannot be arrays or structs", fieldSchema.Type.Name, keyName, tableName)
+				}
 				tableSchema.Keys = append(tableSchema.Keys, fieldSchema)
 			}
 		}
@@ -115,13 +213,14 @@ func unmarshalTableSchemas(jsonContent []byte, allowTableTypes bool) ([]TableSch
 		if !ok {
 			return []TableSchema{}, fmt.Errorf("invalid value schema for table '%s'", tableName)
 		}
+		offset := 0
 		for _, valueName := range jsonValueSchema.Keys() {
 			_valueType, _ := jsonValueSchema.Get(valueName)
 			valueType, ok := _valueType.(string)
 			if !ok {
 				return []TableSchema{}, fmt.Errorf("invalid schema for value '%s' in table '%s'", valueName, tableName)
 			}
-			fieldSchema, err := newFieldSchema(valueName, len(tableSchema.Values), valueType)
+			fieldSchema, err := newFieldSchema(types, valueName, len(tableSchema.Values), valueType)
 			if err != nil {
 				return []TableSchema{}, err
 			}
@@ -134,17 +233,105 @@ func unmarshalTableSchemas(jsonContent []byte, allowTableTypes bool) ([]TableSch
 					return []TableSchema{}, fmt.Errorf("table '%s' does not exist", fieldSchema.Type.Name)
 				}
 			}
+			fieldSchema.Offset = offset
+			offset += len(fieldSchema.Type.Sizes)
 			tableSchema.Values = append(tableSchema.Values, fieldSchema)
 		}
+
+		_jsonIterable, ok := jsonTableSchema.Get("iterable")
+		if ok {
+			iterable, ok := _jsonIterable.(bool)
+			if !ok {
+				return []TableSchema{}, fmt.Errorf("invalid iterable flag for table '%s'", tableName)
+			}
+			if iterable && len(tableSchema.Keys) == 0 {
+				return []TableSchema{}, fmt.Errorf("keyless table '%s' cannot be iterable", tableName)
+			}
+			tableSchema.Iterable = iterable
+		}
+
+		_jsonEvents, ok := jsonTableSchema.Get("events")
+		if ok {
+			events, ok := _jsonEvents.(bool)
+			if !ok {
+				return []TableSchema{}, fmt.Errorf("invalid events flag for table '%s'", tableName)
+			}
+			if events {
+				if err := checkEventSchema(tableSchema); err != nil {
+					return []TableSchema{}, err
+				}
+			}
+			tableSchema.Events = events
+		}
 		tableSchemas = append(tableSchemas, tableSchema)
 	}
+
+	// Only the writes to the rows of top level tables can be tagged with a
+	// key tuple
+	evented := make(map[string]bool)
+	for _, schema := range tableSchemas {
+		evented[schema.Name] = schema.Events
+	}
+	for _, schema := range tableSchemas {
+		for _, field := range schema.Values {
+			if field.Type.Type == TableType && evented[upperFirstLetter(field.Type.Name)] {
+				return []TableSchema{}, fmt.Errorf("table '%s' with events cannot be the type of field '%s' in table '%s'", field.Type.Name, field.Name, schema.Name)
+			}
+		}
+	}
 	return tableSchemas, nil
 }
 
+// checkEventSchema returns an error if the writes to a table cannot be
+// represented as store events.
+func checkEventSchema(schema TableSchema) error {
+	for _, key := range schema.Keys {
+		if key.Type.Type != ValueType {
+			return fmt.Errorf("invalid type '%s' for key '%s' in table '%s' with events: keys must be static", key.Type.Name, key.Name, schema.Name)
+		}
+	}
+	for _, field := range schema.Values {
+		if field.Type.IsRef() {
+			return fmt.Errorf("invalid type '%s' for field '%s' in table '%s' with events: fields cannot be tables or dynamic arrays", field.Type.Name, field.Name, schema.Name)
+		}
+	}
+	return nil
+}
+
+// eventFields returns the struct fields holding the static and dynamic data
+// of the records of a table, in store event order.
+func eventFields(schema TableSchema) (static []int, dynamic []int) {
+	for _, field := range schema.Values {
+		if field.Type.Type == BytesType {
+			dynamic = append(dynamic, field.Offset)
+			continue
+		}
+		for ii := range field.Type.Sizes {
+			static = append(static, field.Offset+ii)
+		}
+	}
+	return static, dynamic
+}
+
+// storeKeyFunc returns the function encoding a key of the given type as a
+// store event key tuple element.
+func storeKeyFunc(fieldType FieldType) string {
+	switch {
+	case strings.HasPrefix(fieldType.SolType, "int"):
+		return "lib.StoreKeySigned"
+	case strings.HasPrefix(fieldType.SolType, "bytes"):
+		return "lib.StoreKeyFixedBytes"
+	default:
+		return "lib.StoreKeyUnsigned"
+	}
+}
+
 type Config struct {
-	JSON    string
-	Out     string
-	Package string
+	JSON     string
+	Out      string
+	Package  string
+	Solidity string // Output directory of the Solidity bindings, none if empty
+	Previous string // Previous version of the data model to migrate from, none if empty
 }
 
 func GenerateDataModel(config Config, allowTableTypes bool) error {
@@ -156,13 +343,14 @@ func GenerateDataModel(config Config, allowTableTypes bool) error {
 	if err != nil {
 		return err
 	}
-	schemas, err := unmarshalTableSchemas(jsonContent, allowTableTypes)
+	schemas, types, err := unmarshalTableSchemas(jsonContent, allowTableTypes)
 	if err != nil {
 		return err
 	}
 
 	funcMap := template.FuncMap{
-		"sub": func(a, b int) int { return a - b },
+		"sub":      func(a, b int) int { return a - b },
+		"storeKey": storeKeyFunc,
 	}
 	tpl, err := template.New("table").Funcs(funcMap).Parse(tableTpl)
 	if err != nil {
@@ -173,16 +361,12 @@ func GenerateDataModel(config Config, allowTableTypes bool) error {
 		tableName := formatTableName(schema.Name)
 		rowName := formatRowName(schema.Name)
 
-		_sizes := make([]string, len(schema.Values))
-		for i, field := range schema.Values {
-			_sizes[i] = fmt.Sprint(field.Type.Size)
-		}
-		sizesStr := fmt.Sprintf("[]int{%s}", strings.Join(_sizes, ", "))
-
-		_keys := make([]string, len(schema.Keys))
-		for i, field := range schema.Keys {
-			_keys[i] = fmt.Sprint(field.Type.Size)
+		var sizes []int
+		for _, field := range schema.Values {
+			sizes = append(sizes, field.Type.Sizes...)
 		}
+		sizesStr := formatSizes(sizes)
+		staticFields, dynamicFields := eventFields(schema)
 
 		data := map[string]interface{}{
 			"Package":         config.Package,
@@ -190,18 +374,46 @@ func GenerateDataModel(config Config, allowTableTypes bool) error {
 			"TableStructName": tableName,
 			"RowStructName":   rowName,
 			"SizesStr":        sizesStr,
+			"StaticFields":    formatSizes(staticFields),
+			"DynamicFields":   formatSizes(dynamicFields),
 		}
 
 		var buf bytes.Buffer
 		if err := tpl.Execute(&buf, data); err != nil {
 			return err
 		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return err
+		}
 		filename := camelToSnake(lowerFirstLetter(tableName)) + ".go"
 		outPath := filepath.Join(config.Out, filename)
-		err := os.WriteFile(outPath, buf.Bytes(), 0644)
+		err = os.WriteFile(outPath, src, 0644)
 		if err != nil {
 			return err
 		}
 	}
+
+	if err := generateTypes(config, schemas, types); err != nil {
+		return err
+	}
+
+	if config.Previous != "" {
+		prevContent, err := os.ReadFile(config.Previous)
+		if err != nil {
+			return err
+		}
+		prevSchemas, _, err := unmarshalTableSchemas(prevContent, allowTableTypes)
+		if err != nil {
+			return fmt.Errorf("invalid previous data model: %w", err)
+		}
+		if err := generateMigrations(config, prevSchemas, schemas); err != nil {
+			return err
+		}
+	}
+
+	if config.Solidity != "" {
+		return generateSolidity(schemas, config.Solidity)
+	}
 	return nil
 }
@@ -16,16 +16,28 @@
 package datamod
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"math/big"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/compiler"
 	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/concrete/codegen/datamod/codec"
 	"github.com/ethereum/go-ethereum/concrete/codegen/datamod/testdata"
+	"github.com/ethereum/go-ethereum/concrete/codegen/datamod/testdata/migration"
+	"github.com/ethereum/go-ethereum/concrete/crypto"
 	"github.com/ethereum/go-ethereum/concrete/lib"
 	"github.com/ethereum/go-ethereum/concrete/mock"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
 	"github.com/stretchr/testify/require"
 )
 
@@ -167,3 +179,480 @@ func TestTables(t *testing.T) {
 		})
 	})
 }
+
+func TestSolidity(t *testing.T) {
+	r := require.New(t)
+	solDir := t.TempDir()
+	err := GenerateDataModel(Config{
+		JSON:     "./testdata/good-datamod.json",
+		Out:      t.TempDir(),
+		Package:  "test",
+		Solidity: solDir,
+	}, true)
+	r.NoError(err)
+
+	_, err = os.Stat(filepath.Join(solDir, "DatamodStorage.sol"))
+	r.NoError(err)
+
+	keyed, err := os.ReadFile(filepath.Join(solDir, "KeyedTable.sol"))
+	r.NoError(err)
+	for _, snippet := range []string{
+		`keccak256("datamod.v1.KeyedTable")`,
+		"rowSlot = keccak256(abi.encodePacked(keyBytes16, rowSlot));",
+		// bool and address share slot 4, bytes16 does not fit and moves to slot 5
+		"DatamodStorage.storeBits(DatamodStorage.offset(rowSlot, 4), 248, 8, (value ? 1 : 0));",
+		"DatamodStorage.storeBits(DatamodStorage.offset(rowSlot, 4), 88, 160, uint256(uint160(value)));",
+		"DatamodStorage.storeBits(DatamodStorage.offset(rowSlot, 5), 128, 128, (uint256(bytes32(value)) >> 128));",
+	} {
+		r.Contains(string(keyed), snippet)
+	}
+
+	nested, err := os.ReadFile(filepath.Join(solDir, "KeylessWithKeyedTableValue.sol"))
+	r.NoError(err)
+	r.Contains(string(nested), "function getValueTable(bytes32 rowSlot) internal pure returns (bytes32 value)")
+	r.NotContains(string(nested), "function set(")
+
+	// Arrays and structs have no Solidity bindings yet
+	err = GenerateDataModel(Config{
+		JSON:     "./testdata/typed-datamod.json",
+		Out:      t.TempDir(),
+		Package:  "test",
+		Solidity: t.TempDir(),
+	}, false)
+	r.ErrorContains(err, "solidity bindings do not support")
+}
+
+// solidityReader reads the tables written by the Go bindings through the
+// generated Solidity libraries.
+const solidityReader = `// SPDX-License-Identifier: MIT
+pragma solidity >=0.8.0;
+
+import "./KeyedTable.sol";
+import "./KeylessTable.sol";
+
+contract Reader {
+    function keyless() external view returns (uint256, int256, string memory, bytes memory, bool, address, bytes16) {
+        return KeylessTable.get(KeylessTable.row());
+    }
+
+    function keyedBytes(uint256 keyUint, int256 keyInt, string memory keyString, bytes memory keyBytes, bool keyBool, address keyAddress, bytes16 keyBytes16) external view returns (bytes memory) {
+        return KeyedTable.getValueBytes(KeyedTable.row(keyUint, keyInt, keyString, keyBytes, keyBool, keyAddress, keyBytes16));
+    }
+}
+`
+
+func TestSolidityReadsGoStorage(t *testing.T) {
+	solc, err := exec.LookPath("solc")
+	if err != nil {
+		t.Skip("solc is required to compile the Solidity bindings")
+	}
+	r := require.New(t)
+	solDir := t.TempDir()
+	err = GenerateDataModel(Config{
+		JSON:     "./testdata/good-datamod.json",
+		Out:      t.TempDir(),
+		Package:  "test",
+		Solidity: solDir,
+	}, true)
+	r.NoError(err)
+	r.NoError(os.WriteFile(filepath.Join(solDir, "Reader.sol"), []byte(solidityReader), 0o644))
+
+	cmd := exec.Command(solc, "--combined-json", "abi,bin-runtime", "--optimize", "Reader.sol")
+	cmd.Dir = solDir
+	output, err := cmd.Output()
+	r.NoError(err, "solc failed: %s", output)
+	contracts, err := compiler.ParseCombinedJSON(output, solidityReader, "", "", "")
+	r.NoError(err)
+	contract := contracts["Reader.sol:Reader"]
+	r.NotNil(contract)
+	abiJSON, err := json.Marshal(contract.Info.AbiDefinition)
+	r.NoError(err)
+	readerABI, err := abi.JSON(bytes.NewReader(abiJSON))
+	r.NoError(err)
+
+	var (
+		addr      = common.HexToAddress("0xc0ffee0001")
+		statedb   = mock.NewMockStateDB()
+		env       = api.NewEnvironment(addr, api.EnvConfig{}, statedb, api.NewMockBlockContext(), api.NewMockCallContext(), api.NewMockCaller(), false, 0)
+		ds        = lib.NewDatastore(env)
+		longStr   = strings.Repeat("a string longer than a slot ", 3)
+		longBytes = []byte(strings.Repeat("bytes longer than a slot ", 5))
+	)
+	statedb.(*state.StateDB).SetCode(addr, common.FromHex(contract.RuntimeCode))
+	testdata.NewKeylessTable(ds).Set(uintVal, intVal, longStr, longBytes, boolVal, addrVal, bytes16Val)
+	testdata.NewKeyedTable(ds).Get(uintVal, intVal, stringVal, bytesVal, boolVal, addrVal, bytes16Val).SetValueBytes(bytesVal)
+
+	call := func(method string, args ...interface{}) []interface{} {
+		input, err := readerABI.Pack(method, args...)
+		r.NoError(err)
+		ret, _, err := runtime.Call(addr, input, &runtime.Config{State: statedb.(*state.StateDB)})
+		r.NoError(err)
+		values, err := readerABI.Unpack(method, ret)
+		r.NoError(err)
+		return values
+	}
+
+	var bytes16 [16]byte
+	copy(bytes16[:], bytes16Val)
+	values := call("keyless")
+	r.Equal(uintVal, values[0])
+	r.Equal(intVal, values[1])
+	r.Equal(longStr, values[2])
+	r.Equal(longBytes, values[3])
+	r.Equal(boolVal, values[4])
+	r.Equal(addrVal, values[5])
+	r.Equal(bytes16, values[6])
+
+	values = call("keyedBytes", uintVal, intVal, stringVal, bytesVal, boolVal, addrVal, bytes16)
+	r.Equal(bytesVal, values[0])
+}
+
+func TestTypedTable(t *testing.T) {
+	var (
+		r     = require.New(t)
+		addr  = common.HexToAddress("0x1234567890123456789012345678901234567890")
+		env   = mock.NewMockEnvironment(addr, api.EnvConfig{}, false, 0)
+		ds    = lib.NewDatastore(env)
+		table = testdata.NewTypedTable(ds)
+		row   = table.Get(1, testdata.ColorGreen)
+	)
+
+	color, position, scores, shape, path, tags, name := row.Get()
+	r.Equal(testdata.ColorRed, color)
+	r.Equal(testdata.Point{}, position)
+	r.Equal([3]uint16{}, scores)
+	r.Equal(testdata.Shape{}, shape)
+	r.Equal(uint64(0), path.Length())
+	r.Equal(uint64(0), tags.Length())
+	r.Equal("", name)
+
+	var (
+		newPosition = testdata.Point{X: -1, Y: 2}
+		newScores   = [3]uint16{1, 2, 3}
+		newShape    = testdata.Shape{
+			Origin:  testdata.Point{X: 3, Y: -4},
+			Color:   testdata.ColorBlue,
+			Corners: [4]testdata.Point{{X: 1}, {Y: 2}, {X: -3}, {Y: -4}},
+			Flags:   [3]bool{true, false, true},
+		}
+	)
+	row.Set(testdata.ColorGreen, newPosition, newScores, newShape, "name")
+
+	row = table.Get(1, testdata.ColorGreen)
+	color, position, scores, shape, _, _, name = row.Get()
+	r.Equal(testdata.ColorGreen, color)
+	r.Equal(newPosition, position)
+	r.Equal(newScores, scores)
+	r.Equal(newShape, shape)
+	r.Equal("name", name)
+
+	// Packed fields do not overwrite their neighbours
+	row.SetScores([3]uint16{4, 5, 6})
+	r.Equal(newPosition, row.GetPosition())
+	r.Equal([3]uint16{4, 5, 6}, row.GetScores())
+	r.Equal(newShape, row.GetShape())
+
+	// Rows with other keys are not affected
+	r.Equal(testdata.ColorRed, table.Get(1, testdata.ColorBlue).GetColor())
+
+	path = row.GetPath()
+	path.Push(testdata.Point{X: 1, Y: 1})
+	path.Push(testdata.Point{X: 2, Y: 2})
+	r.Equal(uint64(2), row.GetPath().Length())
+	r.Equal(testdata.Point{X: 2, Y: 2}, row.GetPath().Get(1))
+	path.Set(0, testdata.Point{X: -1, Y: -1})
+	r.Equal(testdata.Point{X: -1, Y: -1}, row.GetPath().Get(0))
+	r.Equal(testdata.Point{}, path.Get(2))
+	r.Equal(testdata.Point{X: 2, Y: 2}, path.Pop())
+	r.Equal(uint64(1), path.Length())
+
+	tags = row.GetTags()
+	tags.Push(common.Hex2Bytes("0102030405060708"))
+	r.Equal(common.Hex2Bytes("0102030405060708"), row.GetTags().Get(0))
+}
+
+func TestBadTypes(t *testing.T) {
+	for name, schema := range map[string]string{
+		"undeclaredStruct": `{"structs": {"A": {"b": "B"}, "B": {"x": "uint8"}}, "t": {"schema": {"a": "A"}}}`,
+		"bytesInStruct":    `{"structs": {"A": {"b": "bytes"}}, "t": {"schema": {"a": "A"}}}`,
+		"nestedDynamic":    `{"t": {"schema": {"a": "uint8[][]"}}}`,
+		"dynamicInFixed":   `{"t": {"schema": {"a": "uint8[][2]"}}}`,
+		"zeroLength":       `{"t": {"schema": {"a": "uint8[0]"}}}`,
+		"arrayKey":         `{"t": {"keySchema": {"k": "uint8[2]"}, "schema": {"a": "uint8"}}}`,
+		"structKey":        `{"structs": {"A": {"x": "uint8"}}, "t": {"keySchema": {"k": "A"}, "schema": {"a": "uint8"}}}`,
+		"emptyEnum":        `{"enums": {"E": []}, "t": {"schema": {"a": "E"}}}`,
+		"duplicateMember":  `{"enums": {"E": ["a", "a"]}, "t": {"schema": {"a": "E"}}}`,
+		"builtinName":      `{"enums": {"uint8": ["a"]}, "t": {"schema": {"a": "uint8"}}}`,
+		"iterableKeyless":  `{"t": {"iterable": true, "schema": {"a": "uint8"}}}`,
+		"eventsBytesKey":   `{"t": {"events": true, "keySchema": {"k": "string"}, "schema": {"a": "uint8"}}}`,
+		"eventsDynamic":    `{"t": {"events": true, "schema": {"a": "uint8[]"}}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := unmarshalTableSchemas([]byte(schema), false)
+			require.Error(t, err)
+		})
+	}
+
+	// Rows of nested tables cannot be tagged with a key tuple
+	schema := `{"t": {"schema": {"a": "table u"}}, "u": {"events": true, "schema": {"a": "uint8"}}}`
+	_, _, err := unmarshalTableSchemas([]byte(schema), true)
+	require.ErrorContains(t, err, "with events")
+}
+
+func TestIterableTable(t *testing.T) {
+	var (
+		r     = require.New(t)
+		addr  = common.HexToAddress("0x1234567890123456789012345678901234567890")
+		env   = mock.NewMockEnvironment(addr, api.EnvConfig{}, false, 0)
+		ds    = lib.NewDatastore(env)
+		table = testdata.NewIterableTable(ds)
+		label = strings.Repeat("long label ", 4)
+	)
+
+	r.Zero(table.Len())
+	r.Empty(table.Keys())
+
+	// Reading a row does not add it to the index
+	r.Equal(uint64(0), table.Get(big.NewInt(1), "a").GetValue())
+	r.False(table.Has(big.NewInt(1), "a"))
+
+	table.Get(big.NewInt(1), "a").SetValue(1)
+	table.Get(big.NewInt(2), "b").Set(2, label, testdata.Point{X: 1, Y: 2})
+	table.Get(big.NewInt(3), "c").SetPosition(testdata.Point{X: 3})
+	table.Get(big.NewInt(1), "a").SetLabel("a")
+
+	r.Equal(uint64(3), table.Len())
+	r.True(table.Has(big.NewInt(2), "b"))
+	r.Equal([]testdata.IterableTableKey{
+		{Id: big.NewInt(1), Name: "a"},
+		{Id: big.NewInt(2), Name: "b"},
+		{Id: big.NewInt(3), Name: "c"},
+	}, table.Keys())
+
+	r.True(table.Delete(big.NewInt(2), "b"))
+	r.False(table.Delete(big.NewInt(2), "b"))
+	r.False(table.Has(big.NewInt(2), "b"))
+	r.Equal([]testdata.IterableTableKey{
+		{Id: big.NewInt(1), Name: "a"},
+		{Id: big.NewInt(3), Name: "c"},
+	}, table.Keys())
+
+	// Deleted rows are cleared
+	value, rowLabel, position := table.Get(big.NewInt(2), "b").Get()
+	r.Equal(uint64(0), value)
+	r.Equal("", rowLabel)
+	r.Equal(testdata.Point{}, position)
+	r.Equal(uint64(1), table.Get(big.NewInt(1), "a").GetValue())
+}
+
+func TestEventTable(t *testing.T) {
+	var (
+		r       = require.New(t)
+		statedb = mock.NewMockStateDB()
+		env     = api.NewEnvironment(common.Address{}, api.EnvConfig{}, statedb, api.NewMockBlockContext(), api.NewMockCallContext(), api.NewMockCaller(), false, 0)
+		ds      = lib.NewDatastore(env)
+		table   = testdata.NewEventTable(ds, env)
+		tag     = []byte{1, 2, 3, 4}
+	)
+	logs := func() []common.Hash {
+		var topics []common.Hash
+		for _, log := range statedb.(*state.StateDB).Logs() {
+			topics = append(topics, log.Topics[0])
+		}
+		return topics
+	}
+
+	// Reads emit nothing
+	table.Get(1, -1, tag).Get()
+	r.Empty(logs())
+
+	row := table.Get(1, -1, tag)
+	row.Set(2, "name", testdata.Point{X: 3, Y: 4}, true, []byte{5})
+	row.SetCount(3)
+	row.SetPosition(testdata.Point{})
+	row.SetName("other")
+	table.Delete(1, -1, tag)
+	r.Equal([]common.Hash{
+		lib.StoreSetRecordTopic,
+		lib.StoreSpliceStaticDataTopic,
+		lib.StoreSpliceStaticDataTopic,
+		lib.StoreSpliceDynamicDataTopic,
+		lib.StoreDeleteRecordTopic,
+	}, logs())
+
+	// The record layout matches the MUD encoding of the table schema
+	last := statedb.(*state.StateDB).Logs()[0]
+	r.Equal(testdata.EventTableTableId(), last.Topics[1])
+	r.Equal(append(common.Hex2Bytes(
+		"0000000000000000000000000000000000000000000000000000000000000080"+
+			"0000000000000000000000000000000000000000000000000000000000000100"+
+			"0000000000000000000000000000000000000001000000000400000000000005"+
+			"0000000000000000000000000000000000000000000000000000000000000140"+
+			"0000000000000000000000000000000000000000000000000000000000000003"+
+			"0000000000000000000000000000000000000000000000000000000000000001"+
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"+
+			"0102030400000000000000000000000000000000000000000000000000000000"+
+			"000000000000000000000000000000000000000000000000000000000000000d"+
+			"0000000200000003000000040100000000000000000000000000000000000000"),
+		common.Hex2Bytes(
+			"0000000000000000000000000000000000000000000000000000000000000005"+
+				"6e616d6505000000000000000000000000000000000000000000000000000000")...,
+	), last.Data)
+
+	// Without an environment the table is silent
+	testdata.NewEventTable(ds, nil).Get(2, 0, tag).SetCount(1)
+	testdata.NewEventSingleton(ds, nil).Set(1, "note")
+	r.Len(logs(), 5)
+	r.Equal(uint32(1), table.Get(2, 0, tag).GetCount())
+
+	singleton := testdata.NewEventSingleton(ds, env)
+	singleton.SetNote("note")
+	singleton.Delete()
+	r.Equal("", singleton.GetNote())
+
+	iterable := testdata.NewIterableEventTable(ds, env)
+	iterable.Get(common.Address{1}).SetBalance(big.NewInt(1))
+	r.True(iterable.Delete(common.Address{1}))
+	r.False(iterable.Delete(common.Address{1}))
+	r.Equal([]common.Hash{
+		lib.StoreSpliceDynamicDataTopic,
+		lib.StoreDeleteRecordTopic,
+		lib.StoreSpliceStaticDataTopic,
+		lib.StoreDeleteRecordTopic,
+	}, logs()[5:])
+}
+
+func TestDiffDataModels(t *testing.T) {
+	r := require.New(t)
+
+	oldJSON, err := os.ReadFile("./testdata/migration/datamod.v1.json")
+	r.NoError(err)
+	newJSON, err := os.ReadFile("./testdata/migration/datamod.v2.json")
+	r.NoError(err)
+	changes, err := DiffDataModels(oldJSON, newJSON, false)
+	r.NoError(err)
+	r.False(HasBreakingChanges(changes))
+	r.Contains(changes, SchemaChange{"Items", MigrationChange, "version bumped from 1 to 2"})
+	r.Contains(changes, SchemaChange{"Items", MigrationChange, "changed type of field 'count' from uint16 to uint32"})
+	r.Contains(changes, SchemaChange{"Settings", SafeChange, "appended field 'paused'"})
+
+	for name, tc := range map[string]struct {
+		newJSON string
+		kind    ChangeKind
+	}{
+		"unchanged":     {`{"t": {"keySchema": {"k": "uint8"}, "schema": {"a": "uint8"}}}`, -1},
+		"renamedKey":    {`{"t": {"keySchema": {"j": "uint8"}, "schema": {"a": "uint8"}}}`, -1},
+		"appended":      {`{"t": {"keySchema": {"k": "uint8"}, "schema": {"a": "uint8", "b": "string"}}}`, SafeChange},
+		"addedTable":    {`{"t": {"keySchema": {"k": "uint8"}, "schema": {"a": "uint8"}}, "u": {"schema": {"a": "uint8"}}}`, SafeChange},
+		"events":        {`{"t": {"events": true, "keySchema": {"k": "uint8"}, "schema": {"a": "uint8"}}}`, SafeChange},
+		"inserted":      {`{"t": {"keySchema": {"k": "uint8"}, "schema": {"b": "string", "a": "uint8"}}}`, BreakingChange},
+		"removed":       {`{"t": {"keySchema": {"k": "uint8"}, "schema": {"b": "uint8"}}}`, BreakingChange},
+		"retyped":       {`{"t": {"keySchema": {"k": "uint8"}, "schema": {"a": "uint16"}}}`, BreakingChange},
+		"changedKeys":   {`{"t": {"keySchema": {"k": "uint16"}, "schema": {"a": "uint8"}}}`, BreakingChange},
+		"iterable":      {`{"t": {"iterable": true, "keySchema": {"k": "uint8"}, "schema": {"a": "uint8"}}}`, BreakingChange},
+		"removedTable":  {`{"u": {"schema": {"a": "uint8"}}}`, BreakingChange},
+		"bumpedVersion": {`{"t": {"version": 2, "keySchema": {"k": "uint16"}, "schema": {"a": "uint16"}}}`, MigrationChange},
+	} {
+		t.Run(name, func(t *testing.T) {
+			oldJSON := `{"t": {"keySchema": {"k": "uint8"}, "schema": {"a": "uint8"}}}`
+			changes, err := DiffDataModels([]byte(oldJSON), []byte(tc.newJSON), false)
+			require.NoError(t, err)
+			if tc.kind < 0 {
+				require.Empty(t, changes)
+				return
+			}
+			require.NotEmpty(t, changes)
+			kind := SafeChange
+			for _, change := range changes {
+				if change.Kind > kind {
+					kind = change.Kind
+				}
+			}
+			require.Equal(t, tc.kind, kind)
+		})
+	}
+
+	// Breaking changes prevent generating migrations
+	var (
+		dir     = t.TempDir()
+		oldPath = filepath.Join(dir, "old.json")
+		newPath = filepath.Join(dir, "new.json")
+	)
+	r.NoError(os.WriteFile(oldPath, []byte(`{"t": {"schema": {"a": "uint8"}}}`), 0644))
+	r.NoError(os.WriteFile(newPath, []byte(`{"t": {"schema": {"a": "uint16"}}}`), 0644))
+	err = GenerateDataModel(Config{JSON: newPath, Out: dir, Package: "test", Previous: oldPath}, false)
+	r.ErrorContains(err, "breaking changes")
+
+	// Only widened integers are converted by migrations
+	for _, tc := range []struct {
+		from, to string
+		ok       bool
+	}{
+		{"uint8", "uint16", true},
+		{"uint64", "uint", true},
+		{"int8", "int256", true},
+		{"uint8", "int16", true},
+		{"uint16", "uint8", false},
+		{"uint8", "int8", false},
+		{"int8", "uint16", false},
+		{"uint8", "address", false},
+		{"bytes4", "bytes8", false},
+	} {
+		r.NoError(os.WriteFile(oldPath, []byte(fmt.Sprintf(`{"t": {"schema": {"a": "%s"}}}`, tc.from)), 0644))
+		r.NoError(os.WriteFile(newPath, []byte(fmt.Sprintf(`{"t": {"version": 2, "schema": {"a": "%s"}}}`, tc.to)), 0644))
+		err = GenerateDataModel(Config{JSON: newPath, Out: dir, Package: "test", Previous: oldPath}, false)
+		if tc.ok {
+			r.NoError(err, "%s to %s", tc.from, tc.to)
+		} else {
+			r.ErrorContains(err, "must be written by hand", "%s to %s", tc.from, tc.to)
+		}
+	}
+
+	_, _, err = unmarshalTableSchemas([]byte(`{"t": {"version": 0, "schema": {"a": "uint8"}}}`), false)
+	r.Error(err)
+	_, _, err = unmarshalTableSchemas([]byte(`{"t": {"version": 1.5, "schema": {"a": "uint8"}}}`), false)
+	r.Error(err)
+}
+
+func TestMigration(t *testing.T) {
+	var (
+		r     = require.New(t)
+		addr  = common.HexToAddress("0x1234567890123456789012345678901234567890")
+		env   = mock.NewMockEnvironment(addr, api.EnvConfig{}, false, 0)
+		ds    = lib.NewDatastore(env)
+		owner = common.HexToAddress("0x01")
+		tag   = common.Hex2Bytes("0102030405060708")
+	)
+
+	// Write rows with the layout of version 1
+	oldTable := ds.Get(migration.ItemsV1DefaultKey())
+	for _, id := range []uint64{1, 2} {
+		key := codec.EncodeSmallUint64(8, id)
+		old := lib.NewDatastoreStruct(oldTable.Mapping().GetNested(key), []int{20, 32, 1, 2, 32})
+		old.SetField(0, codec.EncodeAddress(20, owner))
+		old.SetField_bytes(1, []byte("item"))
+		old.SetField(2, []byte{byte(migration.KindTool)})
+		old.SetField(3, codec.EncodeSmallUint16(2, 5))
+		tags := migration.NewBytes8ArrayFromSlot(old.GetField_slot(4))
+		tags.Push(tag)
+		lib.NewKeyIndex(oldTable, 1).Insert(key)
+	}
+
+	items := migration.NewItems(ds)
+	r.Zero(items.Len())
+	migration.MigrateItemsFromV1(ds)
+	r.Equal([]migration.ItemsKey{{Id: 1}, {Id: 2}}, items.Keys())
+
+	kind, count, name, tags, rowOwner, label := items.Get(2).Get()
+	r.Equal(migration.KindTool, kind)
+	r.Equal(uint32(5), count)
+	r.Equal("item", name)
+	r.Equal(uint64(1), tags.Length())
+	r.Equal(tag, tags.Get(0))
+	r.Equal(owner, rowOwner)
+	r.Equal("", label)
+
+	// Appended fields do not move the table
+	r.Equal(crypto.Keccak256([]byte("datamod.v1.Settings")), migration.SettingsDefaultKey())
+	r.Equal(crypto.Keccak256([]byte("datamod.v2.Items")), migration.ItemsDefaultKey())
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package datamod
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type ChangeKind int
+
+const (
+	// SafeChange leaves the data stored with the previous schema readable
+	SafeChange ChangeKind = iota
+	// MigrationChange is covered by a version bump, rows must be migrated to
+	// the new table slot
+	MigrationChange
+	// BreakingChange makes the data stored with the previous schema unreadable
+	BreakingChange
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case SafeChange:
+		return "safe"
+	case MigrationChange:
+		return "migration"
+	default:
+		return "breaking"
+	}
+}
+
+type SchemaChange struct {
+	Table       string
+	Kind        ChangeKind
+	Description string
+}
+
+func (c SchemaChange) String() string {
+	return fmt.Sprintf("%-9s %s: %s", c.Kind, c.Table, c.Description)
+}
+
+// DiffDataModels compares two versions of a data model and returns the
+// changes between them.
+func DiffDataModels(oldJSON, newJSON []byte, allowTableTypes bool) ([]SchemaChange, error) {
+	oldSchemas, _, err := unmarshalTableSchemas(oldJSON, allowTableTypes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid old data model: %w", err)
+	}
+	newSchemas, _, err := unmarshalTableSchemas(newJSON, allowTableTypes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid new data model: %w", err)
+	}
+	return diffSchemas(oldSchemas, newSchemas), nil
+}
+
+// HasBreakingChanges returns whether any of the changes is breaking.
+func HasBreakingChanges(changes []SchemaChange) bool {
+	for _, change := range changes {
+		if change.Kind == BreakingChange {
+			return true
+		}
+	}
+	return false
+}
+
+func findTable(schemas []TableSchema, name string) (TableSchema, bool) {
+	for _, schema := range schemas {
+		if schema.Name == name {
+			return schema, true
+		}
+	}
+	return TableSchema{}, false
+}
+
+func findField(fields []FieldSchema, name string) (FieldSchema, bool) {
+	for _, field := range fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return FieldSchema{}, false
+}
+
+func sameKeys(oldKeys, newKeys []FieldSchema) bool {
+	if len(oldKeys) != len(newKeys) {
+		return false
+	}
+	for ii := range oldKeys {
+		if !reflect.DeepEqual(oldKeys[ii].Type, newKeys[ii].Type) {
+			return false
+		}
+	}
+	return true
+}
+
+func diffSchemas(oldSchemas, newSchemas []TableSchema) []SchemaChange {
+	var changes []SchemaChange
+	for _, oldSchema := range oldSchemas {
+		if _, ok := findTable(newSchemas, oldSchema.Name); !ok {
+			changes = append(changes, SchemaChange{oldSchema.Name, BreakingChange, "removed table"})
+		}
+	}
+	for _, newSchema := range newSchemas {
+		oldSchema, ok := findTable(oldSchemas, newSchema.Name)
+		if !ok {
+			changes = append(changes, SchemaChange{newSchema.Name, SafeChange, "added table"})
+			continue
+		}
+		changes = append(changes, diffTable(oldSchema, newSchema)...)
+	}
+	return changes
+}
+
+// diffTable returns the changes to a table. Changes that would break the
+// layout of the stored rows are covered by bumping the version of the table,
+// which moves it to a new slot.
+func diffTable(oldSchema, newSchema TableSchema) []SchemaChange {
+	var changes []SchemaChange
+	add := func(kind ChangeKind, format string, args ...interface{}) {
+		changes = append(changes, SchemaChange{newSchema.Name, kind, fmt.Sprintf(format, args...)})
+	}
+
+	layoutKind := BreakingChange
+	switch {
+	case newSchema.Version < oldSchema.Version:
+		add(BreakingChange, "version decreased from %d to %d", oldSchema.Version, newSchema.Version)
+	case newSchema.Version > oldSchema.Version:
+		add(MigrationChange, "version bumped from %d to %d", oldSchema.Version, newSchema.Version)
+		layoutKind = MigrationChange
+	}
+
+	if !sameKeys(oldSchema.Keys, newSchema.Keys) {
+		add(layoutKind, "changed keys")
+	}
+
+	appended := true
+	for ii, oldField := range oldSchema.Values {
+		newField, ok := findField(newSchema.Values, oldField.Name)
+		switch {
+		case !ok:
+			add(layoutKind, "removed field '%s'", oldField.Name)
+		case !reflect.DeepEqual(oldField.Type, newField.Type):
+			add(layoutKind, "changed type of field '%s' from %s to %s", oldField.Name, oldField.Type.Name, newField.Type.Name)
+		case newField.Index != ii:
+			add(layoutKind, "moved field '%s'", oldField.Name)
+		default:
+			continue
+		}
+		appended = false
+	}
+	for _, newField := range newSchema.Values {
+		if _, ok := findField(oldSchema.Values, newField.Name); ok {
+			continue
+		}
+		if appended && newField.Index >= len(oldSchema.Values) {
+			add(SafeChange, "appended field '%s'", newField.Name)
+		} else {
+			add(layoutKind, "inserted field '%s'", newField.Name)
+		}
+	}
+
+	if newSchema.Iterable != oldSchema.Iterable {
+		if newSchema.Iterable {
+			// The rows written before are missing from the key index
+			add(layoutKind, "made iterable")
+		} else {
+			add(SafeChange, "made not iterable")
+		}
+	}
+	if newSchema.Events != oldSchema.Events {
+		add(SafeChange, "toggled events")
+	}
+	return changes
+}
@@ -25,6 +25,9 @@ const (
 	ValueType = iota
 	BytesType
 	TableType
+	ArrayType        // Fixed size array, packed inline in the row
+	StructType       // Struct, packed inline in the row
+	DynamicArrayType // Dynamic array, referenced from its own slot in the row
 )
 
 type FieldType struct {
@@ -32,27 +35,245 @@ type FieldType struct {
 	Type       int
 	Size       int
 	GoType     string
+	SolType    string
 	EncodeFunc string
 	DecodeFunc string
+
+	Ident   string        // Identifier used to name generated helpers
+	Sizes   []int         // Sizes of the struct fields the type is packed into
+	Elem    *FieldType    // Element type of arrays
+	Length  int           // Length of fixed size arrays
+	Fields  []FieldSchema // Fields of structs
+	Members []string      // Members of enums
+}
+
+// IsRef returns whether values of the type are referenced by their slot
+// instead of being read and written as a whole.
+func (t FieldType) IsRef() bool {
+	return t.Type == TableType || t.Type == DynamicArrayType
+}
+
+// IsComposite returns whether the type is packed into more than one struct
+// field.
+func (t FieldType) IsComposite() bool {
+	return t.Type == ArrayType || t.Type == StructType
+}
+
+// IsConstant returns whether reading a value of the type does not read
+// storage, i.e. for nested tables only their slot is derived.
+func (t FieldType) IsConstant() bool {
+	return t.Type == TableType
+}
+
+func (t FieldType) isStatic() bool {
+	return t.Type == ValueType || t.IsComposite()
+}
+
+// ReadExpr returns the Go expression decoding a value of a static type from
+// the fields of the lib.DatastoreStruct s starting at index.
+func (t FieldType) ReadExpr(s, index string) string {
+	if t.IsComposite() {
+		return fmt.Sprintf("%s(%s, %s)", t.DecodeFunc, s, index)
+	}
+	return fmt.Sprintf("%s(%d, %s.GetField(%s))", t.DecodeFunc, t.Size, s, index)
+}
+
+// WriteStmt returns the Go statement encoding value of a static type into the
+// fields of the lib.DatastoreStruct s starting at index.
+func (t FieldType) WriteStmt(s, index, value string) string {
+	if t.IsComposite() {
+		return fmt.Sprintf("%s(%s, %s, %s)", t.EncodeFunc, s, index, value)
+	}
+	return fmt.Sprintf("%s.SetField(%s, %s(%d, %s))", s, index, t.EncodeFunc, t.Size, value)
+}
+
+// typeSet holds the enums and structs declared in a data model.
+type typeSet struct {
+	named map[string]FieldType
+	order []string
+}
+
+func newTypeSet() *typeSet {
+	return &typeSet{named: make(map[string]FieldType)}
+}
+
+func (ts *typeSet) declare(name string, fieldType FieldType) error {
+	if !isValidName(name) {
+		return fmt.Errorf("invalid type name '%s'", name)
+	}
+	if _, err := nameToFieldType(name); err == nil {
+		return fmt.Errorf("type '%s' shadows a builtin type", name)
+	}
+	if _, ok := ts.named[name]; ok {
+		return fmt.Errorf("type '%s' declared twice", name)
+	}
+	ts.named[name] = fieldType
+	ts.order = append(ts.order, name)
+	return nil
+}
+
+// Named returns the declared types in declaration order.
+func (ts *typeSet) Named() []FieldType {
+	types := make([]FieldType, len(ts.order))
+	for ii, name := range ts.order {
+		types[ii] = ts.named[name]
+	}
+	return types
+}
+
+func (ts *typeSet) declareEnum(name string, members []string) error {
+	if len(members) == 0 {
+		return fmt.Errorf("enum '%s' has no members", name)
+	}
+	if len(members) > 256 {
+		return fmt.Errorf("enum '%s' has more than 256 members", name)
+	}
+	seen := make(map[string]bool)
+	for _, member := range members {
+		if !isValidName(member) {
+			return fmt.Errorf("invalid member '%s' in enum '%s'", member, name)
+		}
+		if seen[upperFirstLetter(member)] {
+			return fmt.Errorf("duplicate member '%s' in enum '%s'", member, name)
+		}
+		seen[upperFirstLetter(member)] = true
+	}
+	goType := upperFirstLetter(name)
+	return ts.declare(name, FieldType{
+		Name:       name,
+		Type:       ValueType,
+		Size:       1,
+		GoType:     goType,
+		SolType:    "uint8",
+		EncodeFunc: "encode" + goType,
+		DecodeFunc: "decode" + goType,
+		Ident:      goType,
+		Sizes:      []int{1},
+		Members:    members,
+	})
+}
+
+func (ts *typeSet) declareStruct(name string, fields []FieldSchema) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("struct '%s' has no fields", name)
+	}
+	var sizes []int
+	for ii, field := range fields {
+		if !field.Type.isStatic() {
+			return fmt.Errorf("invalid type '%s' for field '%s' in struct '%s': struct fields must be of a static type", field.Type.Name, field.Name, name)
+		}
+		fields[ii].Offset = len(sizes)
+		sizes = append(sizes, field.Type.Sizes...)
+	}
+	goType := upperFirstLetter(name)
+	return ts.declare(name, FieldType{
+		Name:       name,
+		Type:       StructType,
+		GoType:     goType,
+		EncodeFunc: "encode" + goType,
+		DecodeFunc: "decode" + goType,
+		Ident:      goType,
+		Sizes:      sizes,
+		Fields:     fields,
+	})
+}
+
+// fieldType resolves a type name, including arrays and declared types.
+func (ts *typeSet) fieldType(name string) (FieldType, error) {
+	if strings.HasSuffix(name, "]") {
+		return ts.arrayType(name)
+	}
+	if fieldType, ok := ts.named[name]; ok {
+		return fieldType, nil
+	}
+	return nameToFieldType(name)
+}
+
+func (ts *typeSet) arrayType(name string) (FieldType, error) {
+	open := strings.LastIndex(name, "[")
+	if open <= 0 {
+		return FieldType{}, fmt.Errorf("invalid array type %s", name)
+	}
+	elem, err := ts.fieldType(name[:open])
+	if err != nil {
+		return FieldType{}, err
+	}
+	if !elem.isStatic() {
+		return FieldType{}, fmt.Errorf("invalid array type %s: array elements must be of a static type", name)
+	}
+
+	lengthStr := name[open+1 : len(name)-1]
+	if lengthStr == "" {
+		ident := elem.Ident + "Array"
+		return FieldType{
+			Name:   name,
+			Type:   DynamicArrayType,
+			Size:   32,
+			GoType: ident,
+			Ident:  ident,
+			Sizes:  []int{32},
+			Elem:   &elem,
+		}, nil
+	}
+
+	length, err := strconv.Atoi(lengthStr)
+	if err != nil {
+		return FieldType{}, err
+	}
+	if length < 1 {
+		return FieldType{}, fmt.Errorf("invalid array length %d", length)
+	}
+	sizes := make([]int, 0, length*len(elem.Sizes))
+	for ii := 0; ii < length; ii++ {
+		sizes = append(sizes, elem.Sizes...)
+	}
+	ident := fmt.Sprintf("%sArray%d", elem.Ident, length)
+	return FieldType{
+		Name:       name,
+		Type:       ArrayType,
+		GoType:     fmt.Sprintf("[%d]%s", length, elem.GoType),
+		EncodeFunc: "encode" + ident,
+		DecodeFunc: "decode" + ident,
+		Ident:      ident,
+		Sizes:      sizes,
+		Elem:       &elem,
+		Length:     length,
+	}, nil
 }
 
 func nameToFieldType(name string) (FieldType, error) {
+	fieldType, err := builtinFieldType(name)
+	if err != nil {
+		return FieldType{}, err
+	}
+	fieldType.Sizes = []int{fieldType.Size}
+	if fieldType.Type == TableType {
+		fieldType.Ident = fieldType.GoType
+	} else {
+		fieldType.Ident = upperFirstLetter(fieldType.SolType)
+	}
+	return fieldType, nil
+}
+
+func builtinFieldType(name string) (FieldType, error) {
 	switch name {
 	case "address":
 		return FieldType{
 			Name:       "address",
 			Size:       20,
 			GoType:     "common.Address",
-			EncodeFunc: "EncodeAddress",
-			DecodeFunc: "DecodeAddress",
+			SolType:    "address",
+			EncodeFunc: "codec.EncodeAddress",
+			DecodeFunc: "codec.DecodeAddress",
 		}, nil
 	case "bool":
 		return FieldType{
 			Name:       "bool",
 			Size:       1,
 			GoType:     "bool",
-			EncodeFunc: "EncodeBool",
-			DecodeFunc: "DecodeBool",
+			SolType:    "bool",
+			EncodeFunc: "codec.EncodeBool",
+			DecodeFunc: "codec.DecodeBool",
 		}, nil
 	case "uint":
 		break
@@ -63,8 +284,9 @@ func nameToFieldType(name string) (FieldType, error) {
 			Name:       "bytes",
 			Size:       32,
 			GoType:     "[]byte",
-			EncodeFunc: "EncodeBytes",
-			DecodeFunc: "DecodeBytes",
+			SolType:    "bytes",
+			EncodeFunc: "codec.EncodeBytes",
+			DecodeFunc: "codec.DecodeBytes",
 			Type:       BytesType,
 		}, nil
 	case "string":
@@ -72,8 +294,9 @@ func nameToFieldType(name string) (FieldType, error) {
 			Name:       "string",
 			Size:       32,
 			GoType:     "string",
-			EncodeFunc: "EncodeString",
-			DecodeFunc: "DecodeString",
+			SolType:    "string",
+			EncodeFunc: "codec.EncodeString",
+			DecodeFunc: "codec.DecodeString",
 			Type:       BytesType,
 		}, nil
 	default:
@@ -96,13 +319,14 @@ func nameToFieldType(name string) (FieldType, error) {
 			Name:       name,
 			Size:       size,
 			GoType:     "[]byte",
-			EncodeFunc: "EncodeFixedBytes",
-			DecodeFunc: "DecodeFixedBytes",
+			SolType:    name,
+			EncodeFunc: "codec.EncodeFixedBytes",
+			DecodeFunc: "codec.DecodeFixedBytes",
 		}
 		if size == 32 {
 			fieldType.GoType = "common.Hash"
-			fieldType.EncodeFunc = "EncodeHash"
-			fieldType.DecodeFunc = "DecodeHash"
+			fieldType.EncodeFunc = "codec.EncodeHash"
+			fieldType.DecodeFunc = "codec.DecodeHash"
 		}
 		return fieldType, nil
 	}
@@ -132,8 +356,9 @@ func nameToFieldType(name string) (FieldType, error) {
 		}
 
 		fieldType := FieldType{
-			Name: name,
-			Size: size / 8,
+			Name:    name,
+			Size:    size / 8,
+			SolType: noSizeTypeStr + fmt.Sprint(size),
 		}
 		var (
 			goType     string
@@ -147,8 +372,8 @@ func nameToFieldType(name string) (FieldType, error) {
 			codecSufix = fmt.Sprintf("%s256", upperFirstLetter(noSizeTypeStr))
 		}
 		fieldType.GoType = goType
-		fieldType.EncodeFunc = "Encode" + codecSufix
-		fieldType.DecodeFunc = "Decode" + codecSufix
+		fieldType.EncodeFunc = "codec.Encode" + codecSufix
+		fieldType.DecodeFunc = "codec.Decode" + codecSufix
 		return fieldType, nil
 	}
 
@@ -158,10 +383,11 @@ func nameToFieldType(name string) (FieldType, error) {
 			return FieldType{}, fmt.Errorf("invalid table name %s", tableName)
 		}
 		return FieldType{
-			Name:   tableName,
-			Size:   32,
-			GoType: formatTableName(tableName),
-			Type:   TableType,
+			Name:    tableName,
+			Size:    32,
+			GoType:  formatTableName(tableName),
+			SolType: "bytes32",
+			Type:    TableType,
 		}, nil
 	}
 
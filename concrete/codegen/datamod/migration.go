@@ -0,0 +1,178 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package datamod
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+//go:embed migration.tpl
+var migrationTpl string
+
+type migrationField struct {
+	FieldSchema
+	OldType   FieldType
+	OldOffset int // Index of the first struct field of the field in the old row
+}
+
+// ReadOldExpr returns the Go expression reading the field from the old row s,
+// converted to the new type if it was widened.
+func (f migrationField) ReadOldExpr(s string) string {
+	expr := f.OldType.ReadExpr(s, strconv.Itoa(f.OldOffset))
+	if reflect.DeepEqual(f.OldType, f.Type) {
+		return expr
+	}
+	switch {
+	case f.Type.GoType != "*big.Int":
+		return fmt.Sprintf("%s(%s)", f.Type.GoType, expr)
+	case f.OldType.GoType == "*big.Int":
+		return expr
+	case strings.HasPrefix(f.OldType.SolType, "uint"):
+		return fmt.Sprintf("new(big.Int).SetUint64(uint64(%s))", expr)
+	default:
+		return fmt.Sprintf("big.NewInt(int64(%s))", expr)
+	}
+}
+
+// integerBits returns whether a type is a builtin integer and its size in
+// bits.
+func integerBits(t FieldType) (signed bool, bits int, ok bool) {
+	if t.Type != ValueType {
+		return false, 0, false
+	}
+	builtin, err := builtinFieldType(t.Name)
+	if err != nil || builtin.GoType != t.GoType {
+		return false, 0, false
+	}
+	if strings.HasPrefix(t.SolType, "uint") {
+		return false, t.Size * 8, true
+	}
+	if strings.HasPrefix(t.SolType, "int") {
+		return true, t.Size * 8, true
+	}
+	return false, 0, false
+}
+
+// isWidening returns whether every value of the integer type from can be
+// represented by the integer type to.
+func isWidening(from, to FieldType) bool {
+	fromSigned, fromBits, ok := integerBits(from)
+	if !ok {
+		return false
+	}
+	toSigned, toBits, ok := integerBits(to)
+	if !ok {
+		return false
+	}
+	if fromSigned == toSigned {
+		return toBits >= fromBits
+	}
+	return !fromSigned && toBits > fromBits
+}
+
+// migrationFields returns the fields of the new version of a table that can be
+// copied from the old version, i.e. the fields present in both with the same
+// type or an integer type widened. Nested tables are not copied. Other type
+// changes cannot be migrated automatically.
+func migrationFields(oldSchema, newSchema TableSchema) ([]migrationField, error) {
+	var fields []migrationField
+	for _, newField := range newSchema.Values {
+		if newField.Type.Type == TableType {
+			continue
+		}
+		oldField, ok := findField(oldSchema.Values, newField.Name)
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(oldField.Type, newField.Type) && !isWidening(oldField.Type, newField.Type) {
+			return nil, fmt.Errorf("cannot migrate field '%s' of table '%s' from %s to %s, the migration must be written by hand", newField.Name, newSchema.Name, oldField.Type.Name, newField.Type.Name)
+		}
+		fields = append(fields, migrationField{newField, oldField.Type, oldField.Offset})
+	}
+	return fields, nil
+}
+
+// generateMigrations checks the changes from the previous version of a data
+// model and writes the helpers copying the rows of the tables whose version
+// was bumped to their new slot.
+func generateMigrations(config Config, oldSchemas, newSchemas []TableSchema) error {
+	var breaking []string
+	for _, change := range diffSchemas(oldSchemas, newSchemas) {
+		if change.Kind == BreakingChange {
+			breaking = append(breaking, change.String())
+		}
+	}
+	if len(breaking) > 0 {
+		return fmt.Errorf("breaking changes from previous data model, bump the version of the tables:\n%s", strings.Join(breaking, "\n"))
+	}
+
+	tpl, err := template.New("migration").Parse(migrationTpl)
+	if err != nil {
+		return err
+	}
+
+	for _, newSchema := range newSchemas {
+		oldSchema, ok := findTable(oldSchemas, newSchema.Name)
+		if !ok || oldSchema.Version == newSchema.Version {
+			continue
+		}
+		if !sameKeys(oldSchema.Keys, newSchema.Keys) {
+			return fmt.Errorf("cannot migrate table '%s': keys changed", newSchema.Name)
+		}
+
+		fields, err := migrationFields(oldSchema, newSchema)
+		if err != nil {
+			return err
+		}
+
+		var oldSizes []int
+		for _, field := range oldSchema.Values {
+			oldSizes = append(oldSizes, field.Type.Sizes...)
+		}
+		tableName := formatTableName(newSchema.Name)
+		data := map[string]interface{}{
+			"Package":         config.Package,
+			"Schema":          newSchema,
+			"OldSchema":       oldSchema,
+			"TableStructName": tableName,
+			"OldSizesStr":     formatSizes(oldSizes),
+			"Fields":          fields,
+		}
+
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, data); err != nil {
+			return err
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return err
+		}
+		filename := camelToSnake(lowerFirstLetter(tableName)) + "_migration.go"
+		if err := os.WriteFile(filepath.Join(config.Out, filename), src, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
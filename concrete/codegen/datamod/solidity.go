@@ -0,0 +1,205 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package datamod
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// The Solidity bindings are libraries operating on the storage of the calling
+// contract with the same layout as the Go table wrappers operate on a
+// lib.Datastore, so a contract can read and write tables shared with Go code
+// running against its storage.
+
+//go:embed table.sol.tpl
+var tableSolTpl string
+
+//go:embed DatamodStorage.sol
+var datamodStorageSol string
+
+const datamodStorageSolFile = "DatamodStorage.sol"
+
+type solField struct {
+	FieldSchema
+	Param  string // Parameter declaration, with data location if needed
+	Return string // Return type, with data location if needed
+	Get    string // Statements reading the field into `value`
+	Set    string // Statements writing `value` to the field
+}
+
+func solParam(fieldType FieldType) string {
+	if fieldType.Type == BytesType {
+		return fieldType.SolType + " memory"
+	}
+	return fieldType.SolType
+}
+
+// newSolField returns the accessors of a row field at the given byte offset
+// from the start of the row, as laid out by lib.DatastoreStruct.
+func newSolField(field FieldSchema, offset int) solField {
+	var (
+		size      = field.Type.Size
+		slotIndex = offset / 32
+		slotByte  = offset % 32
+		shift     = (32 - slotByte - size) * 8
+		slot      = fmt.Sprintf("DatamodStorage.offset(rowSlot, %d)", slotIndex)
+		sf        = solField{
+			FieldSchema: field,
+			Param:       solParam(field.Type),
+			Return:      solParam(field.Type),
+		}
+	)
+
+	switch {
+	case field.Type.Type == TableType:
+		sf.Get = fmt.Sprintf("value = %s;", slot)
+		return sf
+	case field.Type.SolType == "bytes":
+		sf.Get = fmt.Sprintf("value = DatamodStorage.loadBytes(%s);", slot)
+		sf.Set = fmt.Sprintf("DatamodStorage.storeBytes(%s, value);", slot)
+		return sf
+	case field.Type.SolType == "string":
+		sf.Get = fmt.Sprintf("value = string(DatamodStorage.loadBytes(%s));", slot)
+		sf.Set = fmt.Sprintf("DatamodStorage.storeBytes(%s, bytes(value));", slot)
+		return sf
+	}
+
+	var (
+		solType = field.Type.SolType
+		bits    = size * 8
+		word    string
+		decode  string
+		encoded string
+	)
+	switch {
+	case solType == "address":
+		decode = "address(uint160(word))"
+		encoded = "uint256(uint160(value))"
+	case solType == "bool":
+		decode = "word & 1 == 1"
+		encoded = "(value ? 1 : 0)"
+	case strings.HasPrefix(solType, "bytes"):
+		// Fixed bytes are left aligned in the field
+		decode = fmt.Sprintf("%s(bytes32(word << %d))", solType, 256-bits)
+		encoded = fmt.Sprintf("(uint256(bytes32(value)) >> %d)", 256-bits)
+	case strings.HasPrefix(solType, "uint"):
+		decode = fmt.Sprintf("%s(word)", solType)
+		encoded = "uint256(value)"
+	default:
+		// Signed integers are stored in two's complement
+		decode = fmt.Sprintf("%s(uint%d(word))", solType, bits)
+		encoded = fmt.Sprintf("uint256(uint%d(value))", bits)
+	}
+
+	if size == 32 {
+		word = fmt.Sprintf("uint256(DatamodStorage.load(%s))", slot)
+		sf.Get = fmt.Sprintf("uint256 word = %s;\n        value = %s;", word, decode)
+		sf.Set = fmt.Sprintf("DatamodStorage.store(%s, bytes32(%s));", slot, encoded)
+		return sf
+	}
+
+	mask := fmt.Sprintf("(uint256(1) << %d) - 1", bits)
+	sf.Get = fmt.Sprintf(
+		"uint256 word = (uint256(DatamodStorage.load(%s)) >> %d) & (%s);\n        value = %s;",
+		slot, shift, mask, decode,
+	)
+	sf.Set = fmt.Sprintf(
+		"DatamodStorage.storeBits(%s, %d, %d, %s);",
+		slot, shift, bits, encoded,
+	)
+	return sf
+}
+
+// solRowFields returns the accessors of the values of a table, packing the
+// fields in the same way as lib.NewDatastoreStruct.
+func solRowFields(schema TableSchema) ([]solField, error) {
+	if schema.Iterable {
+		// The key index is only maintained by the Go wrappers
+		return nil, fmt.Errorf("solidity bindings do not support iterable table '%s'", schema.Name)
+	}
+	if schema.Events {
+		// Store events are only emitted by the Go wrappers
+		return nil, fmt.Errorf("solidity bindings do not support table '%s' with events", schema.Name)
+	}
+	fields := make([]solField, len(schema.Values))
+	offset := 0
+	for ii, field := range schema.Values {
+		if field.Type.IsComposite() || field.Type.Type == DynamicArrayType {
+			return nil, fmt.Errorf("solidity bindings do not support type '%s' of field '%s' in table '%s'", field.Type.Name, field.Name, schema.Name)
+		}
+		size := field.Type.Size
+		if offset/32 != (offset+size-1)/32 {
+			offset = (offset/32 + 1) * 32
+		}
+		fields[ii] = newSolField(field, offset)
+		offset += size
+	}
+	return fields, nil
+}
+
+func solKeyFields(schema TableSchema) []solField {
+	keys := make([]solField, len(schema.Keys))
+	for ii, key := range schema.Keys {
+		keys[ii] = solField{
+			FieldSchema: key,
+			Param:       solParam(key.Type),
+		}
+	}
+	return keys
+}
+
+func generateSolidity(schemas []TableSchema, outDir string) error {
+	tpl, err := template.New("table.sol").Parse(tableSolTpl)
+	if err != nil {
+		return err
+	}
+
+	for _, schema := range schemas {
+		fields, err := solRowFields(schema)
+		if err != nil {
+			return err
+		}
+		settable := false
+		for _, field := range fields {
+			settable = settable || field.Set != ""
+		}
+		data := map[string]interface{}{
+			"Name":        formatTableName(schema.Name),
+			"Version":     schema.Version,
+			"Keys":        solKeyFields(schema),
+			"Fields":      fields,
+			"Settable":    settable,
+			"StorageFile": datamodStorageSolFile,
+		}
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, data); err != nil {
+			return err
+		}
+		outPath := filepath.Join(outDir, formatTableName(schema.Name)+".sol")
+		if err := os.WriteFile(outPath, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+
+	outPath := filepath.Join(outDir, datamodStorageSolFile)
+	return os.WriteFile(outPath, []byte(datamodStorageSol), 0644)
+}
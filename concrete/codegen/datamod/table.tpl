@@ -1,4 +1,8 @@
 /* Autogenerated file. Do not edit manually. */
+{{- $env := "" }}
+{{- if .Schema.Events }}
+{{- $env = ", env api.Environment" }}
+{{- end }}
 
 package {{.Package}}
 
@@ -6,6 +10,9 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+{{- if .Schema.Events }}
+	"github.com/ethereum/go-ethereum/concrete/api"
+{{- end }}
 	"github.com/ethereum/go-ethereum/concrete/codegen/datamod/codec"
 	"github.com/ethereum/go-ethereum/concrete/crypto"
 	"github.com/ethereum/go-ethereum/concrete/lib"
@@ -19,13 +26,63 @@ var (
 )
 
 // var (
-//	{{.TableStructName}}DefaultKey = crypto.Keccak256([]byte("datamod.v1.{{.TableStructName}}"))
+//	{{.TableStructName}}DefaultKey = crypto.Keccak256([]byte("datamod.v{{.Schema.Version}}.{{.TableStructName}}"))
 // )
 
 func {{.TableStructName}}DefaultKey() []byte {
-	return crypto.Keccak256([]byte("datamod.v1.{{.TableStructName}}"))
+	return crypto.Keccak256([]byte("datamod.v{{.Schema.Version}}.{{.TableStructName}}"))
+}
+{{- if .Schema.Events }}
+
+// {{.TableStructName}}TableId returns the ID of the table in the store events
+// emitted on writes.
+func {{.TableStructName}}TableId() common.Hash {
+	return lib.StoreTableId("", "{{.TableStructName}}")
 }
 
+func new{{.TableStructName}}Events(env api.Environment) *lib.StoreEvents {
+	return lib.NewStoreEvents(env, {{.TableStructName}}TableId(), {{.StaticFields}}, {{.DynamicFields}})
+}
+{{- end }}
+
+{{- if .Schema.Iterable }}
+// Rows are added to the key index of the table when a field is set through
+// the row, and removed from it when deleted from the table.
+{{- end }}
+{{- if or .Schema.Iterable .Schema.Events }}
+type {{.RowStructName}} struct {
+	lib.DatastoreStruct
+{{- if .Schema.Iterable }}
+	onWrite func()
+{{- end }}
+{{- if .Schema.Events }}
+	events   *lib.StoreEvents
+	keyTuple []common.Hash
+{{- end }}
+}
+
+func New{{.RowStructName}}(dsSlot lib.DatastoreSlot) *{{.RowStructName}} {
+	sizes := {{.SizesStr}}
+	return &{{.RowStructName}}{DatastoreStruct: *lib.NewDatastoreStruct(dsSlot, sizes)}
+}
+{{- if .Schema.Iterable }}
+
+func (v *{{.RowStructName}}) touch() {
+	if v.onWrite != nil {
+		v.onWrite()
+	}
+}
+{{- end }}
+
+func (v *{{.RowStructName}}) clear() {
+{{- range .Schema.Values }}
+{{- if eq .Type.Type 1 }}
+	v.ClearField_bytes({{.Offset}})
+{{- end }}
+{{- end }}
+	v.Clear()
+}
+{{- else }}
 type {{.RowStructName}} struct {
 	lib.DatastoreStruct
 }
@@ -34,17 +91,20 @@ func New{{.RowStructName}}(dsSlot lib.DatastoreSlot) *{{.RowStructName}} {
 	sizes := {{.SizesStr}}
 	return &{{.RowStructName}}{*lib.NewDatastoreStruct(dsSlot, sizes)}
 }
+{{- end }}
 
 func (v *{{$.RowStructName}}) Get() (
 {{- range .Schema.Values }}
-	{{if eq .Type.Type 2}}*{{end}}{{.Type.GoType}},
+	{{if .Type.IsRef}}*{{end}}{{.Type.GoType}},
 {{- end }}
 ) {
 	return {{ range .Schema.Values }}
-		{{- if lt .Type.Type 2 -}}
-		codec.{{.Type.DecodeFunc}}({{.Type.Size}}, {{if eq .Type.Type 0}}v.GetField{{else}}v.GetField_bytes{{end}}({{.Index}}))
+		{{- if .Type.IsRef -}}
+		New{{.Type.GoType}}FromSlot(v.GetField_slot({{.Offset}}))
+		{{- else if .Type.IsComposite -}}
+		{{.Type.DecodeFunc}}(&v.DatastoreStruct, {{.Offset}})
 		{{- else -}}
-		New{{.Type.GoType}}FromSlot(v.GetField_slot({{.Index}}))
+		{{.Type.DecodeFunc}}({{.Type.Size}}, {{if eq .Type.Type 0}}v.GetField{{else}}v.GetField_bytes{{end}}({{.Offset}}))
 		{{- end }}
 		{{- if ne .Index (sub (len $.Schema.Values) 1) }},
 		{{end}}
@@ -53,37 +113,218 @@ func (v *{{$.RowStructName}}) Get() (
 
 func (v *{{$.RowStructName}}) Set(
 {{- range .Schema.Values }}
-{{- if lt .Type.Type 2 }}
+{{- if not .Type.IsRef }}
 	{{.Name}} {{.Type.GoType}},
 {{- end }}
 {{- end }}
 ) {
+{{- if .Schema.Iterable }}
+	v.touch()
+{{- end }}
 {{- range .Schema.Values }}
-{{- if lt .Type.Type 2 }}
+{{- if .Type.IsComposite }}
+	{{.Type.EncodeFunc}}(&v.DatastoreStruct, {{.Offset}}, {{.Name}})
+{{- else if not .Type.IsRef }}
 	{{if eq .Type.Type 0}}v.SetField{{else if eq .Type.Type 1}}v.SetField_bytes{{end -}}
-	({{ .Index }}, codec.{{.Type.EncodeFunc}}({{.Type.Size}}, {{.Name}}))
+	({{ .Offset }}, {{.Type.EncodeFunc}}({{.Type.Size}}, {{.Name}}))
+{{- end }}
 {{- end }}
+{{- if .Schema.Events }}
+	v.events.SetRecord(v.keyTuple, &v.DatastoreStruct)
 {{- end }}
 }
 {{range .Schema.Values}}
-{{- if lt .Type.Type 2 }}
+{{- if .Type.IsComposite }}
+func (v *{{$.RowStructName}}) Get{{.Title}}() {{.Type.GoType}} {
+	return {{.Type.DecodeFunc}}(&v.DatastoreStruct, {{.Offset}})
+}
+
+func (v *{{$.RowStructName}}) Set{{.Title}}(value {{.Type.GoType}}) {
+{{- if $.Schema.Iterable }}
+	v.touch()
+{{- end }}
+	{{.Type.EncodeFunc}}(&v.DatastoreStruct, {{.Offset}}, value)
+{{- if $.Schema.Events }}
+	v.events.SpliceStaticData(v.keyTuple, &v.DatastoreStruct, {{.Offset}}, {{len .Type.Sizes}})
+{{- end }}
+}
+{{ else if not .Type.IsRef }}
 func (v *{{$.RowStructName}}) Get{{.Title}}() {{.Type.GoType}} {
-	data := {{if eq .Type.Type 0}}v.GetField{{else}}v.GetField_bytes{{end}}({{.Index}})
-	return codec.{{.Type.DecodeFunc}}({{.Type.Size}}, data)
+	data := {{if eq .Type.Type 0}}v.GetField{{else}}v.GetField_bytes{{end}}({{.Offset}})
+	return {{.Type.DecodeFunc}}({{.Type.Size}}, data)
 }
 
 func (v *{{$.RowStructName}}) Set{{.Title}}(value {{.Type.GoType}}) {
-	data := codec.{{.Type.EncodeFunc}}({{.Type.Size}}, value)
-	{{if eq .Type.Type 0}}v.SetField{{else}}v.SetField_bytes{{end}}({{.Index}}, data)
+{{- if $.Schema.Iterable }}
+	v.touch()
+{{- end }}
+{{- if and $.Schema.Events (eq .Type.Type 1) }}
+	deleteCount := v.events.DynamicLength(&v.DatastoreStruct, {{.Offset}})
+{{- end }}
+	data := {{.Type.EncodeFunc}}({{.Type.Size}}, value)
+	{{if eq .Type.Type 0}}v.SetField{{else}}v.SetField_bytes{{end}}({{.Offset}}, data)
+{{- if $.Schema.Events }}
+{{- if eq .Type.Type 0 }}
+	v.events.SpliceStaticData(v.keyTuple, &v.DatastoreStruct, {{.Offset}}, 1)
+{{- else }}
+	v.events.SpliceDynamicData(v.keyTuple, &v.DatastoreStruct, {{.Offset}}, deleteCount)
+{{- end }}
+{{- end }}
 }
 {{ else }}
 func (v *{{$.RowStructName}}) Get{{.Title}}() *{{.Type.GoType}} {
-	dsSlot := v.GetField_slot({{.Index}})
+	dsSlot := v.GetField_slot({{.Offset}})
 	return New{{.Type.GoType}}FromSlot(dsSlot)
 }
 {{ end}}
 {{- end}}
-{{- if .Schema.Keys }}
+{{- define "keyParams" }}
+{{- range .Schema.Keys }}
+	{{.Name}} {{.Type.GoType}},
+{{- end }}
+{{- end }}
+{{- define "keyArgs" }}
+{{- range $i, $key := .Schema.Keys }}{{if $i}}, {{end}}{{$key.Name}}{{end}}
+{{- end }}
+{{- define "encodeKeys" }}
+
+func (m *{{.TableStructName}}) encodeKeys(
+{{- template "keyParams" . }}
+) [][]byte {
+	return [][]byte{
+		{{- range .Schema.Keys }}
+		{{.Type.EncodeFunc}}({{.Type.Size}}, {{.Name}}),
+		{{- end }}
+	}
+}
+{{- if .Schema.Events }}
+
+func (m *{{.TableStructName}}) keyTuple(keys [][]byte) []common.Hash {
+	return []common.Hash{
+		{{- range $i, $key := .Schema.Keys }}
+		{{storeKey $key.Type}}(keys[{{$i}}]),
+		{{- end }}
+	}
+}
+{{- end }}
+{{- end }}
+{{- if and .Schema.Keys .Schema.Iterable }}
+type {{.TableStructName}}Key struct {
+{{- range .Schema.Keys }}
+	{{.Title}} {{.Type.GoType}}
+{{- end }}
+}
+
+type {{.TableStructName}} struct {
+	dsSlot lib.DatastoreSlot
+	index  *lib.KeyIndex
+{{- if .Schema.Events }}
+	events *lib.StoreEvents
+{{- end }}
+}
+
+func New{{.TableStructName}}(ds lib.Datastore{{$env}}) *{{.TableStructName}} {
+	dsSlot := ds.Get({{.TableStructName}}DefaultKey())
+	return New{{.TableStructName}}FromSlot(dsSlot{{if .Schema.Events}}, env{{end}})
+}
+
+func New{{.TableStructName}}FromSlot(dsSlot lib.DatastoreSlot{{$env}}) *{{.TableStructName}} {
+	return &{{.TableStructName}}{dsSlot, lib.NewKeyIndex(dsSlot, {{len .Schema.Keys}}){{if .Schema.Events}}, new{{.TableStructName}}Events(env){{end}}}
+}
+{{- template "encodeKeys" . }}
+
+func (m *{{.TableStructName}}) Get(
+{{- template "keyParams" . }}
+) *{{.RowStructName}} {
+	keys := m.encodeKeys({{template "keyArgs" .}})
+	row := New{{.RowStructName}}(m.dsSlot.Mapping().GetNested(keys...))
+	row.onWrite = func() { m.index.Insert(keys...) }
+{{- if .Schema.Events }}
+	row.events, row.keyTuple = m.events, m.keyTuple(keys)
+{{- end }}
+	return row
+}
+
+func (m *{{.TableStructName}}) Has(
+{{- template "keyParams" . }}
+) bool {
+	keys := m.encodeKeys({{template "keyArgs" .}})
+	return m.index.Has(keys...)
+}
+
+// Delete clears the row with the given keys and removes it from the key index.
+// Nested tables and dynamic array elements of the row are not cleared.
+func (m *{{.TableStructName}}) Delete(
+{{- template "keyParams" . }}
+) bool {
+	keys := m.encodeKeys({{template "keyArgs" .}})
+	if !m.index.Delete(keys...) {
+		return false
+	}
+	New{{.RowStructName}}(m.dsSlot.Mapping().GetNested(keys...)).clear()
+{{- if .Schema.Events }}
+	m.events.DeleteRecord(m.keyTuple(keys))
+{{- end }}
+	return true
+}
+
+func (m *{{.TableStructName}}) Len() uint64 {
+	return m.index.Len()
+}
+
+func (m *{{.TableStructName}}) KeyAt(index uint64) {{.TableStructName}}Key {
+	keys := m.index.Get(index)
+	if keys == nil {
+		return {{.TableStructName}}Key{}
+	}
+	return {{.TableStructName}}Key{
+		{{- range $i, $key := .Schema.Keys }}
+		{{$key.Title}}: {{$key.Type.DecodeFunc}}({{$key.Type.Size}}, keys[{{$i}}]),
+		{{- end }}
+	}
+}
+
+func (m *{{.TableStructName}}) Keys() []{{.TableStructName}}Key {
+	keys := make([]{{.TableStructName}}Key, m.index.Len())
+	for ii := range keys {
+		keys[ii] = m.KeyAt(uint64(ii))
+	}
+	return keys
+}
+{{- else if and .Schema.Keys .Schema.Events }}
+type {{.TableStructName}} struct {
+	dsSlot lib.DatastoreSlot
+	events *lib.StoreEvents
+}
+
+func New{{.TableStructName}}(ds lib.Datastore, env api.Environment) *{{.TableStructName}} {
+	dsSlot := ds.Get({{.TableStructName}}DefaultKey())
+	return New{{.TableStructName}}FromSlot(dsSlot, env)
+}
+
+func New{{.TableStructName}}FromSlot(dsSlot lib.DatastoreSlot, env api.Environment) *{{.TableStructName}} {
+	return &{{.TableStructName}}{dsSlot, new{{.TableStructName}}Events(env)}
+}
+{{- template "encodeKeys" . }}
+
+func (m *{{.TableStructName}}) Get(
+{{- template "keyParams" . }}
+) *{{.RowStructName}} {
+	keys := m.encodeKeys({{template "keyArgs" .}})
+	row := New{{.RowStructName}}(m.dsSlot.Mapping().GetNested(keys...))
+	row.events, row.keyTuple = m.events, m.keyTuple(keys)
+	return row
+}
+
+// Delete clears the row with the given keys.
+func (m *{{.TableStructName}}) Delete(
+{{- template "keyParams" . }}
+) {
+	keys := m.encodeKeys({{template "keyArgs" .}})
+	New{{.RowStructName}}(m.dsSlot.Mapping().GetNested(keys...)).clear()
+	m.events.DeleteRecord(m.keyTuple(keys))
+}
+{{- else if .Schema.Keys }}
 type {{.TableStructName}} struct {
 	dsSlot lib.DatastoreSlot
 }
@@ -104,11 +345,30 @@ func (m *{{.TableStructName}}) Get(
 ) *{{.RowStructName}} {
 	dsSlot := m.dsSlot.Mapping().GetNested(
 		{{- range .Schema.Keys }}
-		codec.{{.Type.EncodeFunc}}({{.Type.Size}}, {{.Name}}),
+		{{.Type.EncodeFunc}}({{.Type.Size}}, {{.Name}}),
 		{{- end }}
 	)
 	return New{{.RowStructName}}(dsSlot)
 }
+{{- else if .Schema.Events }}
+type {{.TableStructName}} = {{.RowStructName}}
+
+func New{{.TableStructName}}(ds lib.Datastore, env api.Environment) *{{.RowStructName}} {
+	dsSlot := ds.Get({{.TableStructName}}DefaultKey())
+	return New{{.TableStructName}}FromSlot(dsSlot, env)
+}
+
+func New{{.TableStructName}}FromSlot(dsSlot lib.DatastoreSlot, env api.Environment) *{{.RowStructName}} {
+	row := New{{.RowStructName}}(dsSlot)
+	row.events = new{{.TableStructName}}Events(env)
+	return row
+}
+
+// Delete clears the row.
+func (v *{{.RowStructName}}) Delete() {
+	v.clear()
+	v.events.DeleteRecord(v.keyTuple)
+}
 {{- else }}
 type {{.TableStructName}} = {{.RowStructName}}
 
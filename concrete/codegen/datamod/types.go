@@ -0,0 +1,117 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package datamod
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+//go:embed types.tpl
+var typesTpl string
+
+const typesFile = "types.go"
+
+// collectArrays appends the array types used by a type, including nested
+// ones, to arrays if not there yet.
+func collectArrays(fieldType FieldType, arrays []FieldType, seen map[string]bool) []FieldType {
+	switch fieldType.Type {
+	case StructType:
+		for _, field := range fieldType.Fields {
+			arrays = collectArrays(field.Type, arrays, seen)
+		}
+	case ArrayType, DynamicArrayType:
+		arrays = collectArrays(*fieldType.Elem, arrays, seen)
+		if !seen[fieldType.Ident] {
+			seen[fieldType.Ident] = true
+			arrays = append(arrays, fieldType)
+		}
+	}
+	return arrays
+}
+
+// generateTypes writes the enums, structs and array helpers used by the
+// tables of a data model, if any.
+func generateTypes(config Config, schemas []TableSchema, types *typeSet) error {
+	var (
+		named  = types.Named()
+		arrays []FieldType
+		seen   = make(map[string]bool)
+	)
+	for _, fieldType := range named {
+		arrays = collectArrays(fieldType, arrays, seen)
+	}
+	for _, schema := range schemas {
+		for _, field := range schema.Values {
+			arrays = collectArrays(field.Type, arrays, seen)
+		}
+	}
+	if len(named) == 0 && len(arrays) == 0 {
+		return nil
+	}
+
+	var enums, structs, fixedArrays, dynamicArrays []FieldType
+	for _, fieldType := range named {
+		if fieldType.Type == StructType {
+			structs = append(structs, fieldType)
+		} else {
+			enums = append(enums, fieldType)
+		}
+	}
+	for _, fieldType := range arrays {
+		if fieldType.Type == ArrayType {
+			fixedArrays = append(fixedArrays, fieldType)
+		} else {
+			dynamicArrays = append(dynamicArrays, fieldType)
+		}
+	}
+
+	funcMap := template.FuncMap{
+		"sizes": formatSizes,
+		"title": upperFirstLetter,
+		"at": func(offset int) string {
+			if offset == 0 {
+				return "index"
+			}
+			return fmt.Sprintf("index+%d", offset)
+		},
+	}
+	tpl, err := template.New("types").Funcs(funcMap).Parse(typesTpl)
+	if err != nil {
+		return err
+	}
+	data := map[string]interface{}{
+		"Package":       config.Package,
+		"Enums":         enums,
+		"Structs":       structs,
+		"FixedArrays":   fixedArrays,
+		"DynamicArrays": dynamicArrays,
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(config.Out, typesFile), src, 0644)
+}
@@ -17,6 +17,7 @@ package datamod
 
 import (
 	"bytes"
+	"fmt"
 	"regexp"
 	"strings"
 	"unicode"
@@ -73,3 +74,11 @@ func isValidName(name string) bool {
 	re := regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
 	return re.MatchString(name) && len(strings.TrimSpace(name)) == len(name)
 }
+
+func formatSizes(sizes []int) string {
+	strs := make([]string, len(sizes))
+	for ii, size := range sizes {
+		strs[ii] = fmt.Sprint(size)
+	}
+	return fmt.Sprintf("[]int{%s}", strings.Join(strs, ", "))
+}
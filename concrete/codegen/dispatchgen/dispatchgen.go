@@ -0,0 +1,539 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package dispatchgen generates the ABI of a precompile from the Go methods
+// implementing it. Methods are exposed by annotating them with a directive:
+//
+//	//concrete:abi [pure|view|nonpayable] [name(type,...) [returns (type,...)]]
+//
+// The Solidity types of the parameters and results are derived from their Go
+// types unless given explicitly. A method may take the environment as its first
+// parameter and return an error as its last result.
+//
+// From the annotated methods it generates Run and IsStatic methods dispatching
+// the calls to the precompile, the ABI JSON and a Solidity interface.
+package dispatchgen
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+//go:embed dispatch.tpl
+var dispatchTpl string
+
+//go:embed interface.sol.tpl
+var interfaceTpl string
+
+const directive = "//concrete:abi"
+
+type Config struct {
+	Dir  string // Directory of the Go package defining the precompile
+	Type string // Name of the precompile type
+	Name string // Name of the Solidity interface and ABI file
+	Out  string // Directory to write the ABI and Solidity interface to
+}
+
+type Arg struct {
+	Name    string
+	GoType  string
+	SolType string
+
+	expr ast.Expr
+}
+
+type Method struct {
+	GoName     string
+	Name       string
+	Mutability string
+	HasEnv     bool
+	HasError   bool
+	Inputs     []Arg
+	Outputs    []Arg
+}
+
+// DecodeExpr returns the Go expression asserting a value decoded by
+// accounts/abi to the type of the argument.
+func (a Arg) DecodeExpr(value string) string {
+	if a.GoType == "common.Hash" {
+		return fmt.Sprintf("common.Hash(%s.([32]byte))", value)
+	}
+	return fmt.Sprintf("%s.(%s)", value, a.GoType)
+}
+
+func (m Method) IsStatic() bool {
+	return m.Mutability == "pure" || m.Mutability == "view"
+}
+
+// Signature returns the canonical signature of the method.
+func (m Method) Signature() string {
+	types := make([]string, len(m.Inputs))
+	for ii, input := range m.Inputs {
+		types[ii] = input.SolType
+	}
+	return fmt.Sprintf("%s(%s)", m.Name, strings.Join(types, ","))
+}
+
+var (
+	intTypeRegex   = regexp.MustCompile(`^u?int(8|16|32|64)$`)
+	signatureRegex = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)\(([^()]*)\)(?:\s*returns\s*\(([^()]*)\))?$`)
+)
+
+// solType returns the Solidity type an argument of the given Go type is
+// encoded as by default.
+func solType(expr ast.Expr) (string, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch {
+		case t.Name == "bool" || t.Name == "string":
+			return t.Name, nil
+		case t.Name == "byte":
+			return "uint8", nil
+		case intTypeRegex.MatchString(t.Name):
+			return t.Name, nil
+		}
+	case *ast.SelectorExpr:
+		switch typeString(t) {
+		case "common.Address":
+			return "address", nil
+		case "common.Hash":
+			return "bytes32", nil
+		}
+	case *ast.StarExpr:
+		if typeString(t) == "*big.Int" {
+			return "uint256", nil
+		}
+	case *ast.ArrayType:
+		elem, err := solType(t.Elt)
+		if err != nil {
+			return "", err
+		}
+		if t.Len == nil {
+			if elem == "uint8" {
+				return "bytes", nil
+			}
+			return elem + "[]", nil
+		}
+		length, ok := t.Len.(*ast.BasicLit)
+		if !ok || length.Kind != token.INT {
+			break
+		}
+		if elem == "uint8" && len(length.Value) <= 2 {
+			return "bytes" + length.Value, nil
+		}
+		return fmt.Sprintf("%s[%s]", elem, length.Value), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", typeString(expr))
+}
+
+func typeString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, token.NewFileSet(), expr)
+	return buf.String()
+}
+
+// normalizeGoType returns the type an argument of the given Go type is
+// decoded as by accounts/abi, which is the same type but for common.Hash that
+// is decoded as an array.
+func normalizeGoType(goType string) string {
+	if goType == "common.Hash" {
+		goType = "[32]byte"
+	}
+	return regexp.MustCompile(`\bbyte\b`).ReplaceAllString(goType, "uint8")
+}
+
+// checkType returns an error if an argument of the given Go type cannot be
+// decoded from the given Solidity type.
+func checkType(goType, solType string) error {
+	typ, err := abi.NewType(solType, "", nil)
+	if err != nil {
+		return err
+	}
+	if normalizeGoType(goType) != typ.GetType().String() {
+		return fmt.Errorf("type %s cannot be used as %s", goType, solType)
+	}
+	return nil
+}
+
+func isEnvironment(expr ast.Expr) bool {
+	t := typeString(expr)
+	return t == "api.Environment" || t == "concrete.Environment"
+}
+
+func splitTypes(types string) []string {
+	if strings.TrimSpace(types) == "" {
+		return nil
+	}
+	split := strings.Split(types, ",")
+	for ii := range split {
+		split[ii] = strings.TrimSpace(split[ii])
+	}
+	return split
+}
+
+func lowerFirstLetter(str string) string {
+	if str == "" {
+		return str
+	}
+	runes := []rune(str)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+func parseDirective(doc *ast.CommentGroup) (string, bool) {
+	if doc == nil {
+		return "", false
+	}
+	for _, comment := range doc.List {
+		if comment.Text == directive {
+			return "", true
+		}
+		if strings.HasPrefix(comment.Text, directive+" ") {
+			return strings.TrimSpace(strings.TrimPrefix(comment.Text, directive)), true
+		}
+	}
+	return "", false
+}
+
+func receiverType(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) != 1 {
+		return ""
+	}
+	expr := decl.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+func fieldArgs(fields *ast.FieldList) []Arg {
+	var args []Arg
+	if fields == nil {
+		return args
+	}
+	for _, field := range fields.List {
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{nil}
+		}
+		for _, name := range names {
+			arg := Arg{GoType: typeString(field.Type), expr: field.Type}
+			if name != nil && name.Name != "_" {
+				arg.Name = name.Name
+			}
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// parseMethod returns the ABI method of an annotated Go method.
+func parseMethod(decl *ast.FuncDecl, spec string) (Method, error) {
+	method := Method{
+		GoName:     decl.Name.Name,
+		Name:       lowerFirstLetter(decl.Name.Name),
+		Mutability: "nonpayable",
+	}
+	fail := func(format string, args ...interface{}) (Method, error) {
+		return Method{}, fmt.Errorf("method %s: %s", decl.Name.Name, fmt.Sprintf(format, args...))
+	}
+
+	if fields := strings.Fields(spec); len(fields) > 0 {
+		switch fields[0] {
+		case "pure", "view", "nonpayable":
+			method.Mutability = fields[0]
+			spec = strings.TrimSpace(strings.TrimPrefix(spec, fields[0]))
+		}
+	}
+
+	params := decl.Type.Params
+	if params != nil && len(params.List) > 0 && isEnvironment(params.List[0].Type) {
+		if len(params.List[0].Names) > 1 {
+			return fail("invalid environment parameter")
+		}
+		method.HasEnv = true
+		params = &ast.FieldList{List: params.List[1:]}
+	}
+	results := decl.Type.Results
+	if results != nil && len(results.List) > 0 {
+		last := results.List[len(results.List)-1]
+		if typeString(last.Type) == "error" {
+			if len(last.Names) > 1 {
+				return fail("invalid error result")
+			}
+			method.HasError = true
+			results = &ast.FieldList{List: results.List[:len(results.List)-1]}
+		}
+	}
+	method.Inputs = fieldArgs(params)
+	method.Outputs = fieldArgs(results)
+	for ii := range method.Inputs {
+		if method.Inputs[ii].Name == "" {
+			method.Inputs[ii].Name = fmt.Sprintf("arg%d", ii)
+		}
+	}
+
+	// Derive the Solidity types from the Go ones, unless given explicitly
+	var inputTypes, outputTypes []string
+	if spec != "" {
+		match := signatureRegex.FindStringSubmatch(spec)
+		if match == nil {
+			return fail("invalid directive '%s'", spec)
+		}
+		method.Name = match[1]
+		inputTypes = splitTypes(match[2])
+		outputTypes = splitTypes(match[3])
+		if len(inputTypes) != len(method.Inputs) {
+			return fail("directive has %d inputs, method has %d", len(inputTypes), len(method.Inputs))
+		}
+		if len(outputTypes) != len(method.Outputs) {
+			return fail("directive has %d outputs, method has %d", len(outputTypes), len(method.Outputs))
+		}
+	}
+	for ii, args := range [][]Arg{method.Inputs, method.Outputs} {
+		types := inputTypes
+		if ii == 1 {
+			types = outputTypes
+		}
+		for jj := range args {
+			var err error
+			if types != nil {
+				args[jj].SolType = types[jj]
+			} else {
+				args[jj].SolType, err = solType(args[jj].expr)
+				if err != nil {
+					return fail("%v", err)
+				}
+			}
+			if err := checkType(args[jj].GoType, args[jj].SolType); err != nil {
+				return fail("%v", err)
+			}
+		}
+	}
+	return method, nil
+}
+
+// ParseMethods returns the annotated methods of a type defined in the Go
+// package in a directory, sorted by name.
+func ParseMethods(dir string, typeName string) (string, []Method, error) {
+	fset := token.NewFileSet()
+	filter := func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}
+	pkgs, err := parser.ParseDir(fset, dir, filter, parser.ParseComments)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for pkgName, pkg := range pkgs {
+		var (
+			found   bool
+			methods []Method
+		)
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				switch decl := decl.(type) {
+				case *ast.GenDecl:
+					for _, spec := range decl.Specs {
+						if spec, ok := spec.(*ast.TypeSpec); ok && spec.Name.Name == typeName {
+							found = true
+						}
+					}
+				case *ast.FuncDecl:
+					if receiverType(decl) != typeName {
+						continue
+					}
+					spec, ok := parseDirective(decl.Doc)
+					if !ok {
+						continue
+					}
+					method, err := parseMethod(decl, spec)
+					if err != nil {
+						return "", nil, err
+					}
+					methods = append(methods, method)
+				}
+			}
+		}
+		if !found {
+			continue
+		}
+		if len(methods) == 0 {
+			return "", nil, fmt.Errorf("type %s has no %s methods", typeName, directive)
+		}
+		sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+		seen := make(map[string]bool)
+		for _, method := range methods {
+			if seen[method.Signature()] {
+				return "", nil, fmt.Errorf("duplicate method %s", method.Signature())
+			}
+			seen[method.Signature()] = true
+		}
+		return pkgName, methods, nil
+	}
+	return "", nil, fmt.Errorf("type %s not found in %s", typeName, dir)
+}
+
+type abiArgJSON struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type abiMethodJSON struct {
+	Type            string       `json:"type"`
+	Name            string       `json:"name"`
+	Inputs          []abiArgJSON `json:"inputs"`
+	Outputs         []abiArgJSON `json:"outputs"`
+	StateMutability string       `json:"stateMutability"`
+}
+
+// ABIJSON returns the JSON ABI of the given methods.
+func ABIJSON(methods []Method) ([]byte, error) {
+	toJSON := func(args []Arg) []abiArgJSON {
+		jsonArgs := make([]abiArgJSON, len(args))
+		for ii, arg := range args {
+			jsonArgs[ii] = abiArgJSON{Name: arg.Name, Type: arg.SolType}
+		}
+		return jsonArgs
+	}
+	jsonMethods := make([]abiMethodJSON, len(methods))
+	for ii, method := range methods {
+		jsonMethods[ii] = abiMethodJSON{
+			Type:            "function",
+			Name:            method.Name,
+			Inputs:          toJSON(method.Inputs),
+			Outputs:         toJSON(method.Outputs),
+			StateMutability: method.Mutability,
+		}
+	}
+	return json.Marshal(jsonMethods)
+}
+
+func solParam(arg Arg) string {
+	param := arg.SolType
+	if arg.SolType == "bytes" || arg.SolType == "string" || strings.HasSuffix(arg.SolType, "]") {
+		param += " memory"
+	}
+	if arg.Name != "" {
+		param += " " + arg.Name
+	}
+	return param
+}
+
+func solParams(args []Arg) string {
+	params := make([]string, len(args))
+	for ii, arg := range args {
+		params[ii] = solParam(arg)
+	}
+	return strings.Join(params, ", ")
+}
+
+func camelToSnake(str string) string {
+	var buf bytes.Buffer
+	for ii, r := range str {
+		if unicode.IsUpper(r) {
+			if ii > 0 {
+				buf.WriteRune('_')
+			}
+			buf.WriteRune(unicode.ToLower(r))
+		} else {
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
+
+// Generate writes the dispatcher of the precompile to its package directory,
+// and its ABI JSON and Solidity interface to the output directory.
+func Generate(config Config) error {
+	if config.Name == "" {
+		config.Name = strings.TrimSuffix(config.Type, "Precompile")
+	}
+	pkgName, methods, err := ParseMethods(config.Dir, config.Type)
+	if err != nil {
+		return err
+	}
+	abiJSON, err := ABIJSON(methods)
+	if err != nil {
+		return err
+	}
+
+	funcMap := template.FuncMap{
+		"params": solParams,
+	}
+	data := map[string]interface{}{
+		"Package": pkgName,
+		"Type":    config.Type,
+		"Name":    config.Name,
+		"ABI":     string(abiJSON),
+		"Methods": methods,
+	}
+
+	tpl, err := template.New("dispatch").Funcs(funcMap).Parse(dispatchTpl)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	goPath := filepath.Join(config.Dir, camelToSnake(config.Type)+"_dispatch.go")
+	if err := os.WriteFile(goPath, src, 0644); err != nil {
+		return err
+	}
+
+	tpl, err = template.New("interface").Funcs(funcMap).Parse(interfaceTpl)
+	if err != nil {
+		return err
+	}
+	buf.Reset()
+	if err := tpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	solPath := filepath.Join(config.Out, "I"+config.Name+".sol")
+	if err := os.WriteFile(solPath, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	var abiBuf bytes.Buffer
+	if err := json.Indent(&abiBuf, abiJSON, "", "  "); err != nil {
+		return err
+	}
+	abiPath := filepath.Join(config.Out, config.Name+".abi.json")
+	return os.WriteFile(abiPath, abiBuf.Bytes(), 0644)
+}
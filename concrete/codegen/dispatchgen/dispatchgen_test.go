@@ -0,0 +1,145 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package dispatchgen
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/concrete/codegen/dispatchgen/testdata"
+	"github.com/ethereum/go-ethereum/concrete/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	src, err := os.ReadFile("./testdata/calculator.go")
+	r.NoError(err)
+	r.NoError(os.WriteFile(filepath.Join(dir, "calculator.go"), src, 0644))
+
+	err = Generate(Config{Dir: dir, Type: "CalculatorPrecompile", Out: dir})
+	r.NoError(err)
+
+	// The checked in dispatcher is up to date
+	for _, name := range []string{"calculator_precompile_dispatch.go", "Calculator.abi.json", "ICalculator.sol"} {
+		generated, err := os.ReadFile(filepath.Join(dir, name))
+		r.NoError(err)
+		expected, err := os.ReadFile(filepath.Join("./testdata", name))
+		r.NoError(err)
+		r.Equal(string(expected), string(generated), name)
+	}
+
+	sol, err := os.ReadFile(filepath.Join(dir, "ICalculator.sol"))
+	r.NoError(err)
+	r.Contains(string(sol), "function div(uint64 x, uint64 y) external pure returns (uint64 quotient, uint64 remainder);")
+	r.Contains(string(sol), "function store(bytes32 key, bytes32 value) external;")
+
+	_, err = abi.JSON(openFile(t, filepath.Join(dir, "Calculator.abi.json")))
+	r.NoError(err)
+}
+
+func openFile(t *testing.T, path string) *os.File {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { file.Close() })
+	return file
+}
+
+func TestDispatch(t *testing.T) {
+	var (
+		r    = require.New(t)
+		env  = mock.NewMockEnvironment(common.Address{}, api.EnvConfig{}, false, 0)
+		pc   = &testdata.CalculatorPrecompile{}
+		abi_ = testdata.CalculatorABI
+	)
+	call := func(name string, args ...interface{}) ([]interface{}, error) {
+		input, err := abi_.Pack(name, args...)
+		r.NoError(err)
+		output, err := pc.Run(env, input)
+		if err != nil {
+			return nil, err
+		}
+		return abi_.Unpack(name, output)
+	}
+
+	out, err := call("add", big.NewInt(1), big.NewInt(2))
+	r.NoError(err)
+	r.Equal([]interface{}{big.NewInt(3)}, out)
+
+	out, err = call("div", uint64(7), uint64(2))
+	r.NoError(err)
+	r.Equal([]interface{}{uint64(3), uint64(1)}, out)
+	_, err = call("div", uint64(7), uint64(0))
+	r.ErrorIs(err, testdata.ErrDivisionByZero)
+
+	out, err = call("negate", big.NewInt(5))
+	r.NoError(err)
+	r.Equal([]interface{}{big.NewInt(-5)}, out)
+
+	out, err = call("sum", []uint32{1, 2, 3})
+	r.NoError(err)
+	r.Equal([]interface{}{uint64(6)}, out)
+
+	out, err = call("concat", "ab", []byte("cd"))
+	r.NoError(err)
+	r.Equal([]interface{}{[]byte("abcd")}, out)
+
+	key, value := common.Hash{1}, common.Hash{2}
+	_, err = call("store", key, value)
+	r.NoError(err)
+	out, err = call("load", key)
+	r.NoError(err)
+	r.Equal([]interface{}{[32]byte(value)}, out)
+
+	r.True(pc.IsStatic(abi_.Methods["load"].ID))
+	r.True(pc.IsStatic(abi_.Methods["add"].ID))
+	r.False(pc.IsStatic(abi_.Methods["store"].ID))
+	r.False(pc.IsStatic([]byte{1, 2, 3, 4}))
+
+	_, err = pc.Run(env, []byte{1, 2, 3, 4})
+	r.Error(err)
+	_, err = pc.Run(env, abi_.Methods["add"].ID)
+	r.Error(err)
+}
+
+func TestBadMethods(t *testing.T) {
+	for name, src := range map[string]string{
+		"unsupportedType": "//concrete:abi\nfunc (p *P) M(x float64) {}",
+		"uintSize":        "//concrete:abi\nfunc (p *P) M(x uint) {}",
+		"badMutability":   "//concrete:abi payable\nfunc (p *P) M(x *big.Int) {}",
+		"badDirective":    "//concrete:abi m(uint256\nfunc (p *P) M(x *big.Int) {}",
+		"inputCount":      "//concrete:abi m(uint256,uint256)\nfunc (p *P) M(x *big.Int) {}",
+		"typeMismatch":    "//concrete:abi m(uint64)\nfunc (p *P) M(x *big.Int) {}",
+		"nestedHash":      "//concrete:abi\nfunc (p *P) M(x [2]common.Hash) {}",
+		"duplicate":       "//concrete:abi\nfunc (p *P) M(x uint8) {}\n\n//concrete:abi m(uint8)\nfunc (p *P) N(x uint8) {}",
+		"noMethods":       "func (p *P) helper() {}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			src = "package p\n\nimport \"math/big\"\n\nvar _ = big.NewInt\n\ntype P struct{}\n\n" + src + "\n"
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0644))
+			_, _, err := ParseMethods(dir, "P")
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testdata
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/concrete/lib"
+)
+
+var ErrDivisionByZero = errors.New("division by zero")
+
+type CalculatorPrecompile struct {
+	lib.BlankPrecompile
+}
+
+// Add returns the sum of x and y.
+//
+//concrete:abi pure
+func (c *CalculatorPrecompile) Add(x, y *big.Int) *big.Int {
+	return new(big.Int).Add(x, y)
+}
+
+//concrete:abi pure
+func (c *CalculatorPrecompile) Div(x uint64, y uint64) (quotient uint64, remainder uint64, err error) {
+	if y == 0 {
+		return 0, 0, ErrDivisionByZero
+	}
+	return x / y, x % y, nil
+}
+
+//concrete:abi pure negate(int256) returns (int256)
+func (c *CalculatorPrecompile) Negate(x *big.Int) *big.Int {
+	return new(big.Int).Neg(x)
+}
+
+//concrete:abi pure
+func (c *CalculatorPrecompile) Sum(values []uint32) (sum uint64) {
+	for _, value := range values {
+		sum += uint64(value)
+	}
+	return sum
+}
+
+//concrete:abi pure
+func (c *CalculatorPrecompile) Concat(a string, b []byte) []byte {
+	return append([]byte(a), b...)
+}
+
+//concrete:abi view
+func (c *CalculatorPrecompile) Load(env api.Environment, key common.Hash) common.Hash {
+	return lib.NewDatastore(env).Get(key.Bytes()).Bytes32()
+}
+
+//concrete:abi
+func (c *CalculatorPrecompile) Store(env api.Environment, key common.Hash, value common.Hash) {
+	lib.NewDatastore(env).Get(key.Bytes()).SetBytes32(value)
+}
+
+// Unexported helpers are not part of the ABI.
+func (c *CalculatorPrecompile) helper() {}
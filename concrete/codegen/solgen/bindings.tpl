@@ -0,0 +1,29 @@
+
+// {{.Name}}Address is the address the {{.Name}} precompile is registered at.
+var {{.Name}}Address = common.HexToAddress("{{.Address}}")
+
+// Bind{{.Name}} creates a new instance of {{.Name}}, bound to the
+// precompile address.
+func Bind{{.Name}}(backend bind.ContractBackend) (*{{.Name}}, error) {
+	return New{{.Name}}({{.Name}}Address, &{{.Backend}}{backend})
+}
+
+// {{.Backend}} reports code at the precompile address, as bound contracts
+// refuse to estimate gas for or read empty results from addresses without code.
+type {{.Backend}} struct {
+	bind.ContractBackend
+}
+
+func (b *{{.Backend}}) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	if account == {{.Name}}Address {
+		return []byte{0}, nil
+	}
+	return b.ContractBackend.CodeAt(ctx, account, blockNumber)
+}
+
+func (b *{{.Backend}}) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	if account == {{.Name}}Address {
+		return []byte{0}, nil
+	}
+	return b.ContractBackend.PendingCodeAt(ctx, account)
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package solgen
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	calculator "github.com/ethereum/go-ethereum/concrete/codegen/dispatchgen/testdata"
+	"github.com/ethereum/go-ethereum/concrete/codegen/solgen/testdata"
+	"github.com/ethereum/go-ethereum/concrete/mock"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+const calculatorABIPath = "../dispatchgen/testdata/Calculator.abi.json"
+
+func TestGenerateGoBindings(t *testing.T) {
+	r := require.New(t)
+	out := filepath.Join(t.TempDir(), "calculator.go")
+	config := Config{
+		Name:      "Calculator",
+		Address:   common.BytesToAddress([]byte{0x80}),
+		ABI:       calculatorABIPath,
+		GoOut:     out,
+		GoPackage: "testdata",
+	}
+	r.NoError(GenerateGoBindings(config))
+
+	// The checked in bindings are up to date
+	generated, err := os.ReadFile(out)
+	r.NoError(err)
+	expected, err := os.ReadFile("./testdata/calculator.go")
+	r.NoError(err)
+	r.Equal(string(expected), string(generated))
+
+	config.GoPackage = ""
+	r.Error(GenerateGoBindings(config))
+}
+
+// precompileBackend runs the calls and transactions sent to a precompile
+// directly, without a chain.
+type precompileBackend struct {
+	bind.ContractBackend
+	address common.Address
+	pc      concrete.Precompile
+	env     api.Environment
+	sent    []*types.Transaction
+}
+
+func (b *precompileBackend) run(to *common.Address, data []byte) ([]byte, error) {
+	if to == nil || *to != b.address {
+		return nil, nil
+	}
+	return b.pc.Run(b.env, data)
+}
+
+func (b *precompileBackend) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (b *precompileBackend) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return nil, nil
+}
+
+func (b *precompileBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return b.run(call.To, call.Data)
+}
+
+func (b *precompileBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{BaseFee: big.NewInt(1)}, nil
+}
+
+func (b *precompileBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (b *precompileBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return uint64(len(b.sent)), nil
+}
+
+func (b *precompileBackend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	return 1e5, nil
+}
+
+func (b *precompileBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.sent = append(b.sent, tx)
+	_, err := b.run(tx.To(), tx.Data())
+	return err
+}
+
+func TestGoBindings(t *testing.T) {
+	r := require.New(t)
+	backend := &precompileBackend{
+		address: testdata.CalculatorAddress,
+		pc:      &calculator.CalculatorPrecompile{},
+		env:     mock.NewMockEnvironment(testdata.CalculatorAddress, api.EnvConfig{}, false, 0),
+	}
+	contract, err := testdata.BindCalculator(backend)
+	r.NoError(err)
+
+	sum, err := contract.Add(nil, big.NewInt(1), big.NewInt(2))
+	r.NoError(err)
+	r.Equal(big.NewInt(3), sum)
+
+	div, err := contract.Div(nil, 7, 2)
+	r.NoError(err)
+	r.Equal(uint64(3), div.Quotient)
+	r.Equal(uint64(1), div.Remainder)
+	_, err = contract.Div(nil, 7, 0)
+	r.ErrorIs(err, calculator.ErrDivisionByZero)
+
+	// Gas is estimated even though the precompile address has no code
+	key, err := crypto.GenerateKey()
+	r.NoError(err)
+	opts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1))
+	r.NoError(err)
+	_, err = contract.Store(opts, common.Hash{1}, common.Hash{2})
+	r.NoError(err)
+	r.Len(backend.sent, 1)
+	r.Equal(uint64(1e5), backend.sent[0].Gas())
+
+	value, err := contract.Load(nil, common.Hash{1})
+	r.NoError(err)
+	r.Equal([32]byte(common.Hash{2}), value)
+}
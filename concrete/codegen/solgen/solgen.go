@@ -19,6 +19,7 @@ import (
 	"bytes"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -27,18 +28,22 @@ import (
 	"text/template"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/tools/imports"
 )
 
 //go:embed solgen.tpl
 var solgenTpl string
 
 type Config struct {
-	Name    string
-	Address common.Address
-	ABI     string
-	Sol     string
-	Out     string
+	Name      string
+	Address   common.Address
+	ABI       string
+	Sol       string
+	Out       string
+	GoOut     string // Path to write the go bindings to (optional)
+	GoPackage string
 }
 
 func isValidSolidityContractName(name string) bool {
@@ -163,6 +168,7 @@ type customMethod struct {
 type customABI struct {
 	Methods       []customMethod           `json:"methods"`
 	MethodsByName map[string]*customMethod `json:"-"`
+	JSON          json.RawMessage          `json:"-"` // The raw ABI array
 }
 
 func (c *customABI) UnmarshalJSON(data []byte) error {
@@ -171,6 +177,7 @@ func (c *customABI) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &a); err != nil {
 		return err
 	}
+	c.JSON = append(json.RawMessage{}, data...)
 	c.Methods = a
 	c.MethodsByName = make(map[string]*customMethod)
 	for i := range c.Methods {
@@ -229,3 +236,57 @@ func GenerateSolidityLibrary(config Config) error {
 	}
 	return nil
 }
+
+//go:embed bindings.tpl
+var bindingsTpl string
+
+func generateGoBindings(cABI customABI, config Config) (string, error) {
+	if !isValidSolidityContractName(config.Name) {
+		return "", fmt.Errorf("invalid contract name: '%s'", config.Name)
+	}
+	if config.GoPackage == "" {
+		return "", errors.New("go package name must be provided")
+	}
+
+	// Use the raw ABI so the struct names of tuple arguments are taken from
+	// their internal types, as in the solidity library
+	code, err := bind.Bind([]string{config.Name}, []string{string(cABI.JSON)}, []string{""}, nil, config.GoPackage, bind.LangGo, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := template.New("bindings").Parse(bindingsTpl)
+	if err != nil {
+		return "", err
+	}
+	data := map[string]interface{}{
+		"Name":    config.Name,
+		"Address": config.Address.Hex(),
+		"Backend": strings.ToLower(config.Name[:1]) + config.Name[1:] + "Backend",
+	}
+	buf := bytes.NewBufferString(code)
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+
+	// Add the imports used by the precompile backend wrapper
+	src, err := imports.Process("", buf.Bytes(), nil)
+	if err != nil {
+		return "", err
+	}
+	return string(src), nil
+}
+
+// GenerateGoBindings writes go bindings for calling the precompile through a
+// bind.ContractBackend, e.g. an ethclient.Client, from the same ABI as the
+// solidity library.
+func GenerateGoBindings(config Config) error {
+	_, cABI, err := GetABI(config.ABI)
+	if err != nil {
+		return err
+	}
+	code, err := generateGoBindings(cABI, config)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(config.GoOut, []byte(code), 0644)
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package concrete
+
+import "fmt"
+
+// CompilingPrecompile is implemented by precompiles that compile code before
+// running it, e.g. WASM precompiles. Compilation happens on first use unless
+// Compile is called before. Compiled code can be kept in cacheDir across
+// restarts, or not kept if cacheDir is empty.
+type CompilingPrecompile interface {
+	Precompile
+	Compile(cacheDir string) error
+}
+
+// CompilingPrecompileRegistry is implemented by registries that can compile
+// their precompiles ahead of their first use.
+type CompilingPrecompileRegistry interface {
+	PrecompileRegistry
+	Compile(cacheDir string) error
+}
+
+// CompilePrecompiles compiles all the precompiles in the registry, so invalid
+// code is reported when the node starts rather than when it is first called.
+func CompilePrecompiles(registry PrecompileRegistry, cacheDir string) error {
+	if cr, ok := registry.(CompilingPrecompileRegistry); ok {
+		return cr.Compile(cacheDir)
+	}
+	return nil
+}
+
+var _ CompilingPrecompileRegistry = (*GenericPrecompileRegistry)(nil)
+
+// Compile compiles every precompile registered at any activation. Errors are
+// reported for the first activation a precompile is registered at.
+func (c *GenericPrecompileRegistry) Compile(cacheDir string) error {
+	if err := c.blocks.compile("block", cacheDir); err != nil {
+		return err
+	}
+	return c.times.compile("time", cacheDir)
+}
+
+func (s *activationSchedule) compile(kind string, cacheDir string) error {
+	for ii, start := range s.starts {
+		for _, address := range s.addresses[ii] {
+			cp, ok := s.precompiles[ii][address].(CompilingPrecompile)
+			if !ok {
+				continue
+			}
+			if err := cp.Compile(cacheDir); err != nil {
+				return fmt.Errorf("precompile %v at %s %d: %w", address, kind, start, err)
+			}
+		}
+	}
+	return nil
+}
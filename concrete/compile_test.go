@@ -0,0 +1,55 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package concrete
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type pcCompiling struct {
+	pcBlank
+	err      error
+	cacheDir string
+	compiled int
+}
+
+func (pc *pcCompiling) Compile(cacheDir string) error {
+	pc.cacheDir = cacheDir
+	pc.compiled++
+	return pc.err
+}
+
+func TestCompilePrecompiles(t *testing.T) {
+	var (
+		r        = require.New(t)
+		registry = NewRegistry()
+		valid    = &pcCompiling{}
+		invalid  = &pcCompiling{err: errors.New("invalid module")}
+	)
+	registry.AddPrecompile(0, addrIncl1, valid)
+	registry.AddPrecompile(0, addrIncl2, &pcBlank{})
+	registry.AddPrecompile(10, addrExcl, valid)
+	r.NoError(CompilePrecompiles(registry, "/cache"))
+	r.Equal("/cache", valid.cacheDir)
+	// Precompiles are compiled for every activation they are registered at
+	r.Equal(2, valid.compiled)
+
+	registry.AddPrecompileAtTime(100, addrExcl, invalid)
+	r.EqualError(CompilePrecompiles(registry, ""), "precompile 0x0000000000000000000000000000000000000082 at time 100: invalid module")
+}
@@ -16,6 +16,8 @@
 package concrete
 
 import (
+	"sync"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/concrete/api"
 )
@@ -29,6 +31,62 @@ type Precompile interface {
 	Run(env Environment, input []byte) ([]byte, error)
 }
 
+// UntrustedPrecompile is implemented by precompiles that can be executed in an
+// untrusted environment, i.e. without access to trusted operations.
+type UntrustedPrecompile interface {
+	Precompile
+	Untrusted() bool
+}
+
+// MigratingPrecompile is implemented by precompiles that need to rewrite the
+// persistent storage left by the implementation they replace. Migrate is called
+// once with a trusted environment at the block the precompile is activated, and
+// cannot emit logs.
+type MigratingPrecompile interface {
+	Precompile
+	Migrate(env Environment) error
+}
+
+// BeginBlocker is implemented by precompiles that run at the start of every
+// block they are active in, before any transaction. Block hooks cannot emit
+// logs.
+type BeginBlocker interface {
+	Precompile
+	BeginBlock(env Environment) error
+}
+
+// EndBlocker is implemented by precompiles that run at the end of every block
+// they are active in, after all transactions.
+type EndBlocker interface {
+	Precompile
+	EndBlock(env Environment) error
+}
+
+// IsTrusted reports whether a precompile must be given a trusted environment.
+func IsTrusted(p Precompile) bool {
+	if up, ok := p.(UntrustedPrecompile); ok {
+		return !up.Untrusted()
+	}
+	return true
+}
+
+// AlwaysFinalisePrecompile is implemented by precompiles that opt in to have
+// Finalise called after every transaction, rather than only after transactions
+// that called them or wrote to their storage.
+type AlwaysFinalisePrecompile interface {
+	Precompile
+	AlwaysFinalise() bool
+}
+
+// AlwaysFinalise reports whether a precompile must be finalised after every
+// transaction.
+func AlwaysFinalise(p Precompile) bool {
+	if ap, ok := p.(AlwaysFinalisePrecompile); ok {
+		return ap.AlwaysFinalise()
+	}
+	return false
+}
+
 func RunPrecompile(p Precompile, env *api.Env, input []byte, static bool) (ret []byte, remainingGas uint64, err error) {
 	// We can either copy the input or trust the end developer to not modify it
 	inputCopy := make([]byte, len(input))
@@ -47,47 +105,82 @@ func RunPrecompile(p Precompile, env *api.Env, input []byte, static bool) (ret [
 
 type PrecompileMap = map[common.Address]Precompile
 
+// PrecompileRegistry returns the precompiles active at a given block. As with
+// the chain config forks, precompiles can be activated either by block number
+// or by timestamp, and timestamp activations follow block activations.
 type PrecompileRegistry interface {
-	Precompile(address common.Address, blockNumber uint64) (Precompile, bool)
-	Precompiles(blockNumber uint64) PrecompileMap
-	ActivePrecompiles(blockNumber uint64) []common.Address
+	Precompile(address common.Address, blockNumber uint64, time uint64) (Precompile, bool)
+	Precompiles(blockNumber uint64, time uint64) PrecompileMap
+	ActivePrecompiles(blockNumber uint64, time uint64) []common.Address
 }
 
-type GenericPrecompileRegistry struct {
-	startingBlocks []uint64
-	precompiles    []PrecompileMap
-	addresses      [][]common.Address
+// StateReader is the read-only view of the chain state used to resolve
+// precompiles that are deployed on chain.
+type StateReader interface {
+	GetState(addr common.Address, key common.Hash) common.Hash
 }
 
-var _ PrecompileRegistry = (*GenericPrecompileRegistry)(nil)
+// StatefulPrecompileRegistry is implemented by registries where the set of
+// active precompiles depends on the chain state as well as the block.
+type StatefulPrecompileRegistry interface {
+	PrecompileRegistry
+	PrecompilesWithState(blockNumber uint64, time uint64, state StateReader) PrecompileMap
+}
 
-func NewRegistry() *GenericPrecompileRegistry {
-	return &GenericPrecompileRegistry{
-		startingBlocks: make([]uint64, 0),
-		precompiles:    make([]PrecompileMap, 0),
-		addresses:      make([][]common.Address, 0),
+// GetPrecompiles returns the precompiles active at the given block, resolving
+// them from the state if the registry supports it.
+func GetPrecompiles(registry PrecompileRegistry, blockNumber uint64, time uint64, state StateReader) PrecompileMap {
+	if sr, ok := registry.(StatefulPrecompileRegistry); ok && state != nil {
+		return sr.PrecompilesWithState(blockNumber, time, state)
 	}
+	return registry.Precompiles(blockNumber, time)
+}
+
+// ActivatedPrecompiles returns the precompiles activated at the given block,
+// i.e. those that were not active at its parent or had a different
+// implementation.
+func ActivatedPrecompiles(registry PrecompileRegistry, blockNumber uint64, time uint64, parentTime uint64, state StateReader) PrecompileMap {
+	activated := PrecompileMap{}
+	if blockNumber == 0 {
+		return activated
+	}
+	parentPcs := GetPrecompiles(registry, blockNumber-1, parentTime, state)
+	for address, pc := range GetPrecompiles(registry, blockNumber, time, state) {
+		if prev, ok := parentPcs[address]; ok && samePrecompile(prev, pc) {
+			continue
+		}
+		activated[address] = pc
+	}
+	return activated
+}
+
+// activationSchedule holds sets of precompiles sorted by the block number or
+// timestamp they are activated at.
+type activationSchedule struct {
+	starts      []uint64
+	precompiles []PrecompileMap
+	addresses   [][]common.Address
 }
 
-func (c *GenericPrecompileRegistry) index(blockNumber uint64) int {
-	for ii, startingBlock := range c.startingBlocks {
-		if blockNumber < startingBlock {
+func (s *activationSchedule) index(start uint64) int {
+	for ii, activation := range s.starts {
+		if start < activation {
 			continue
 		}
-		if ii == len(c.startingBlocks)-1 {
+		if ii == len(s.starts)-1 {
 			return ii
 		}
-		if blockNumber < c.startingBlocks[ii+1] {
+		if start < s.starts[ii+1] {
 			return ii
 		}
 	}
 	return -1
 }
 
-func (c *GenericPrecompileRegistry) AddPrecompiles(startingBlock uint64, precompiles PrecompileMap) {
-	idx := c.index(startingBlock)
-	if idx >= 0 && c.startingBlocks[idx] == startingBlock {
-		panic("precompiles already set for this block")
+func (s *activationSchedule) addPrecompiles(start uint64, precompiles PrecompileMap) {
+	idx := s.index(start)
+	if idx >= 0 && s.starts[idx] == start {
+		panic("precompiles already set for this activation")
 	}
 
 	addresses := []common.Address{}
@@ -95,55 +188,282 @@ func (c *GenericPrecompileRegistry) AddPrecompiles(startingBlock uint64, precomp
 		addresses = append(addresses, address)
 	}
 
-	c.startingBlocks = insert[uint64](c.startingBlocks, idx+1, startingBlock)
-	c.precompiles = insert[PrecompileMap](c.precompiles, idx+1, precompiles)
-	c.addresses = insert[[]common.Address](c.addresses, idx+1, addresses)
+	s.starts = insert[uint64](s.starts, idx+1, start)
+	s.precompiles = insert[PrecompileMap](s.precompiles, idx+1, precompiles)
+	s.addresses = insert[[]common.Address](s.addresses, idx+1, addresses)
 }
 
-func (c *GenericPrecompileRegistry) AddPrecompile(startingBlock uint64, address common.Address, precompile Precompile) {
-	idx := c.index(startingBlock)
-	if idx >= 0 && c.startingBlocks[idx] == startingBlock {
-		// There already are precompiles for this block
-		precompiles := c.precompiles[idx]
+func (s *activationSchedule) addPrecompile(start uint64, address common.Address, precompile Precompile) {
+	idx := s.index(start)
+	if idx >= 0 && s.starts[idx] == start {
+		// There already are precompiles for this activation
+		precompiles := s.precompiles[idx]
 		if _, ok := precompiles[address]; ok {
-			panic("precompile already set at this address for this block")
+			panic("precompile already set at this address for this activation")
 		}
 		precompiles[address] = precompile
-		c.addresses[idx] = append(c.addresses[idx], address)
+		s.addresses[idx] = append(s.addresses[idx], address)
+		return
+	}
+
+	s.starts = insert[uint64](s.starts, idx+1, start)
+	s.precompiles = insert[PrecompileMap](s.precompiles, idx+1, PrecompileMap{address: precompile})
+	s.addresses = insert[[]common.Address](s.addresses, idx+1, []common.Address{address})
+}
+
+// setPrecompile activates a precompile from the given activation onwards,
+// replacing the precompile at the address in it and in every later activation.
+// If there is no set of precompiles for the activation, a new one is created
+// from the set active before it.
+func (s *activationSchedule) setPrecompile(start uint64, address common.Address, precompile Precompile) {
+	idx := s.index(start)
+	if idx < 0 || s.starts[idx] != start {
+		precompiles := make(PrecompileMap)
+		if idx >= 0 {
+			for addr, pc := range s.precompiles[idx] {
+				precompiles[addr] = pc
+			}
+		}
+		idx++
+		s.addPrecompiles(start, precompiles)
+	}
+	for ii := idx; ii < len(s.starts); ii++ {
+		if _, ok := s.precompiles[ii][address]; !ok {
+			s.addresses[ii] = append(s.addresses[ii], address)
+		}
+		s.precompiles[ii][address] = precompile
+	}
+}
+
+// removePrecompile deactivates an address from the given activation onwards.
+// If there is no set of precompiles for the activation, a new one is created
+// from the set active before it.
+func (s *activationSchedule) removePrecompile(start uint64, address common.Address) {
+	idx := s.index(start)
+	if idx >= 0 && s.starts[idx] == start {
+		// There already are precompiles for this activation
+		if _, ok := s.precompiles[idx][address]; !ok {
+			panic("precompile not set at this address for this activation")
+		}
+		delete(s.precompiles[idx], address)
+		s.addresses[idx] = removeAddress(s.addresses[idx], address)
+		return
 	}
 
-	c.startingBlocks = insert[uint64](c.startingBlocks, idx+1, startingBlock)
-	c.precompiles = insert[PrecompileMap](c.precompiles, idx+1, PrecompileMap{address: precompile})
-	c.addresses = insert[[]common.Address](c.addresses, idx+1, []common.Address{address})
+	var prev PrecompileMap
+	if idx >= 0 {
+		prev = s.precompiles[idx]
+	}
+	if _, ok := prev[address]; !ok {
+		panic("precompile not active at this address before this activation")
+	}
+	precompiles := make(PrecompileMap, len(prev)-1)
+	for addr, pc := range prev {
+		if addr != address {
+			precompiles[addr] = pc
+		}
+	}
+	s.addPrecompiles(start, precompiles)
+}
+
+// removeOverlaid deactivates an address from the given activation onwards in
+// a schedule overlaid on base, by setting a nil precompile at the address.
+func (s *activationSchedule) removeOverlaid(start uint64, address common.Address, base PrecompileMap) {
+	idx := s.index(start)
+	var prev PrecompileMap
+	if idx >= 0 {
+		prev = s.precompiles[idx]
+	}
+	if pc, ok := prev[address]; ok && pc == nil {
+		panic("precompile not active at this address before this activation")
+	} else if !ok {
+		if _, ok := base[address]; !ok {
+			panic("precompile not active at this address before this activation")
+		}
+	}
+	if idx >= 0 && s.starts[idx] == start {
+		// There already are precompiles for this activation
+		if _, ok := s.precompiles[idx][address]; !ok {
+			s.addresses[idx] = append(s.addresses[idx], address)
+		}
+		s.precompiles[idx][address] = nil
+		return
+	}
+	precompiles := make(PrecompileMap, len(prev)+1)
+	for addr, pc := range prev {
+		precompiles[addr] = pc
+	}
+	precompiles[address] = nil
+	s.addPrecompiles(start, precompiles)
 }
 
-func (c *GenericPrecompileRegistry) Precompile(address common.Address, blockNumber uint64) (Precompile, bool) {
-	idx := c.index(blockNumber)
+func (s *activationSchedule) last() (PrecompileMap, []common.Address) {
+	if len(s.precompiles) == 0 {
+		return PrecompileMap{}, nil
+	}
+	return s.precompiles[len(s.precompiles)-1], s.addresses[len(s.addresses)-1]
+}
+
+// overlay returns the precompiles of a block activation with those of a
+// timestamp activation overlaid on top, where nil precompiles deactivate their
+// address.
+func overlay(base PrecompileMap, baseAddresses []common.Address, top PrecompileMap, topAddresses []common.Address) (PrecompileMap, []common.Address) {
+	precompiles := make(PrecompileMap, len(base)+len(top))
+	addresses := make([]common.Address, 0, len(base)+len(top))
+	for _, address := range baseAddresses {
+		if _, ok := top[address]; !ok {
+			precompiles[address] = base[address]
+			addresses = append(addresses, address)
+		}
+	}
+	for _, address := range topAddresses {
+		if pc := top[address]; pc != nil {
+			precompiles[address] = pc
+			addresses = append(addresses, address)
+		}
+	}
+	return precompiles, addresses
+}
+
+// GenericPrecompileRegistry activates precompiles by block number and by
+// timestamp. The precompiles active at a block are those of the last block
+// activation, with those of the last timestamp activation overlaid on top, so
+// precompiles activated by block stay active after timestamp activations
+// unless these replace or deactivate them.
+type GenericPrecompileRegistry struct {
+	blocks activationSchedule
+	times  activationSchedule
+
+	lock   sync.Mutex
+	merged map[[2]int]mergedPrecompiles
+}
+
+type mergedPrecompiles struct {
+	precompiles PrecompileMap
+	addresses   []common.Address
+}
+
+var _ PrecompileRegistry = (*GenericPrecompileRegistry)(nil)
+
+func NewRegistry() *GenericPrecompileRegistry {
+	return &GenericPrecompileRegistry{}
+}
+
+// active returns the precompiles active at the given block, and their
+// addresses.
+func (c *GenericPrecompileRegistry) active(blockNumber uint64, time uint64) (PrecompileMap, []common.Address) {
+	blockIdx, timeIdx := c.blocks.index(blockNumber), c.times.index(time)
+	if timeIdx < 0 {
+		if blockIdx < 0 {
+			return PrecompileMap{}, []common.Address{}
+		}
+		return c.blocks.precompiles[blockIdx], c.blocks.addresses[blockIdx]
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := [2]int{blockIdx, timeIdx}
+	if merged, ok := c.merged[key]; ok {
+		return merged.precompiles, merged.addresses
+	}
+	var (
+		base          = PrecompileMap{}
+		baseAddresses []common.Address
+	)
+	if blockIdx >= 0 {
+		base, baseAddresses = c.blocks.precompiles[blockIdx], c.blocks.addresses[blockIdx]
+	}
+	precompiles, addresses := overlay(base, baseAddresses, c.times.precompiles[timeIdx], c.times.addresses[timeIdx])
+	if c.merged == nil {
+		c.merged = make(map[[2]int]mergedPrecompiles)
+	}
+	c.merged[key] = mergedPrecompiles{precompiles, addresses}
+	return precompiles, addresses
+}
+
+// changed must be called whenever the activations change.
+func (c *GenericPrecompileRegistry) changed() {
+	c.lock.Lock()
+	c.merged = nil
+	c.lock.Unlock()
+}
+
+func (c *GenericPrecompileRegistry) AddPrecompiles(startingBlock uint64, precompiles PrecompileMap) {
+	defer c.changed()
+	c.blocks.addPrecompiles(startingBlock, precompiles)
+}
+
+func (c *GenericPrecompileRegistry) AddPrecompile(startingBlock uint64, address common.Address, precompile Precompile) {
+	defer c.changed()
+	c.blocks.addPrecompile(startingBlock, address, precompile)
+}
+
+// AddPrecompilesAtTime sets the precompiles activated from the given
+// timestamp, on top of those activated by block.
+func (c *GenericPrecompileRegistry) AddPrecompilesAtTime(startingTime uint64, precompiles PrecompileMap) {
+	defer c.changed()
+	c.times.addPrecompiles(startingTime, precompiles)
+}
+
+// AddPrecompileAtTime adds a precompile to the set activated from the given
+// timestamp.
+func (c *GenericPrecompileRegistry) AddPrecompileAtTime(startingTime uint64, address common.Address, precompile Precompile) {
+	defer c.changed()
+	c.times.addPrecompile(startingTime, address, precompile)
+}
+
+// SetPrecompile activates a precompile at the given address from the given
+// block onwards, replacing the precompile at that address in every later block
+// activation as well.
+func (c *GenericPrecompileRegistry) SetPrecompile(startingBlock uint64, address common.Address, precompile Precompile) {
+	defer c.changed()
+	c.blocks.setPrecompile(startingBlock, address, precompile)
+}
+
+// RemovePrecompile deactivates the precompile at the given address from the
+// given block onwards. The address must be active right before that block.
+func (c *GenericPrecompileRegistry) RemovePrecompile(startingBlock uint64, address common.Address) {
+	defer c.changed()
+	c.blocks.removePrecompile(startingBlock, address)
+}
+
+// RemovePrecompileAtTime deactivates the precompile at the given address from
+// the given timestamp onwards, whether it was activated by block or by
+// timestamp. As timestamp activations are overlaid on the last block
+// activation, the address must be active in it or in an earlier timestamp
+// activation, and ValidateSchedule rejects block activations changing the
+// address after it is first activated.
+func (c *GenericPrecompileRegistry) RemovePrecompileAtTime(startingTime uint64, address common.Address) {
+	defer c.changed()
+	base, _ := c.blocks.last()
+	c.times.removeOverlaid(startingTime, address, base)
+}
+
+func (c *GenericPrecompileRegistry) Precompile(address common.Address, blockNumber uint64, time uint64) (Precompile, bool) {
+	if idx := c.times.index(time); idx >= 0 {
+		if pc, ok := c.times.precompiles[idx][address]; ok {
+			return pc, pc != nil
+		}
+	}
+	idx := c.blocks.index(blockNumber)
 	if idx < 0 {
 		return nil, false
 	}
-	pc, ok := c.precompiles[idx][address]
+	pc, ok := c.blocks.precompiles[idx][address]
 	if !ok {
 		return nil, false
 	}
 	return pc, true
 }
 
-func (c *GenericPrecompileRegistry) Precompiles(blockNumber uint64) PrecompileMap {
-	idx := c.index(blockNumber)
-	if idx < 0 {
-		return PrecompileMap{}
-	}
-	return c.precompiles[idx]
+func (c *GenericPrecompileRegistry) Precompiles(blockNumber uint64, time uint64) PrecompileMap {
+	precompiles, _ := c.active(blockNumber, time)
+	return precompiles
 }
 
-func (c *GenericPrecompileRegistry) ActivePrecompiles(blockNumber uint64) []common.Address {
-	idx := c.index(blockNumber)
-
-	if idx < 0 {
-		return []common.Address{}
-	}
-	return c.addresses[idx]
+func (c *GenericPrecompileRegistry) ActivePrecompiles(blockNumber uint64, time uint64) []common.Address {
+	_, addresses := c.active(blockNumber, time)
+	return addresses
 }
 
 func insert[T any](slice []T, index int, value T) []T {
@@ -157,3 +477,12 @@ func insert[T any](slice []T, index int, value T) []T {
 		return slice
 	}
 }
+
+func removeAddress(addresses []common.Address, address common.Address) []common.Address {
+	for ii, addr := range addresses {
+		if addr == address {
+			return append(addresses[:ii:ii], addresses[ii+1:]...)
+		}
+	}
+	return addresses
+}
@@ -36,6 +36,11 @@ type pcSingle struct {
 
 type pcBlank struct{}
 
+type pcUncomparable struct {
+	*pcBlank
+	data []byte
+}
+
 func (pc *pcBlank) IsStatic(input []byte) bool {
 	return true
 }
@@ -109,7 +114,7 @@ func verifyPrecompileSet(t *testing.T, registry *GenericPrecompileRegistry, num
 	r := require.New(t)
 	// Assert that all the provided addresses have been returned and all the returned
 	// addresses were provided
-	addresses := registry.ActivePrecompiles(num)
+	addresses := registry.ActivePrecompiles(num, 0)
 	r.Len(addresses, len(p.precompiles))
 	for _, address := range addresses {
 		_, ok := p.precompiles[address]
@@ -120,16 +125,16 @@ func verifyPrecompileSet(t *testing.T, registry *GenericPrecompileRegistry, num
 	}
 	// Assert that all active addresses map to the correct precompile
 	for address, setPc := range p.precompiles {
-		registryPc, ok := registry.Precompile(address, num)
+		registryPc, ok := registry.Precompile(address, num, 0)
 		r.True(ok)
 		r.Equal(setPc, registryPc)
 	}
 	// Assert that inactive addresses do not map to a precompile
-	pc, ok := registry.Precompile(addrExcl, num)
+	pc, ok := registry.Precompile(addrExcl, num, 0)
 	r.Nil(pc)
 	r.False(ok)
 	// Assert that Precompiles returns the correct set of precompiles
-	pcs := registry.Precompiles(num)
+	pcs := registry.Precompiles(num, 0)
 	r.Equal(p.precompiles, pcs)
 }
 
@@ -137,19 +142,19 @@ func verifyPrecompileSingle(t *testing.T, registry *GenericPrecompileRegistry, n
 	r := require.New(t)
 	// Assert that all the provided addresses have been returned and all the returned
 	// addresses were provided
-	addresses := registry.ActivePrecompiles(num)
+	addresses := registry.ActivePrecompiles(num, 0)
 	r.Len(addresses, 1)
 	r.Equal(p.address, addresses[0])
 	// Assert that all active addresses map to the correct precompile
-	registryPc, ok := registry.Precompile(p.address, num)
+	registryPc, ok := registry.Precompile(p.address, num, 0)
 	r.True(ok)
 	r.Equal(p.precompile, registryPc)
 	// Assert that inactive addresses do not map to a precompile
-	pc, ok := registry.Precompile(addrExcl, num)
+	pc, ok := registry.Precompile(addrExcl, num, 0)
 	r.Nil(pc)
 	r.False(ok)
 	// Assert that Precompiles returns the correct set of precompiles
-	pcs := registry.Precompiles(num)
+	pcs := registry.Precompiles(num, 0)
 	r.Len(pcs, 1)
 	r.Equal(p.precompile, pcs[p.address])
 }
@@ -217,4 +222,139 @@ func TestPrecompileRegistry(t *testing.T) {
 			}
 		})
 	})
+	t.Run("AddPrecompilesAtTime", func(t *testing.T) {
+		r := require.New(t)
+		var (
+			blockPc = &pcNamed{name: "block"}
+			timePc  = &pcNamed{name: "time"}
+		)
+		registry := NewRegistry()
+		registry.AddPrecompiles(0, PrecompileMap{addrIncl1: blockPc})
+		registry.AddPrecompilesAtTime(1000, PrecompileMap{addrIncl1: timePc, addrIncl2: timePc})
+		registry.AddPrecompileAtTime(2000, addrIncl2, timePc)
+		r.Panics(func() {
+			registry.AddPrecompilesAtTime(1000, PrecompileMap{})
+		})
+
+		pc, ok := registry.Precompile(addrIncl1, 100, 999)
+		r.True(ok)
+		r.Equal(blockPc, pc)
+		r.Len(registry.ActivePrecompiles(100, 999), 1)
+
+		// Timestamp activations take precedence regardless of the block number
+		for _, blockNumber := range []uint64{0, 100} {
+			pc, ok = registry.Precompile(addrIncl1, blockNumber, 1000)
+			r.True(ok)
+			r.Equal(timePc, pc)
+			r.Len(registry.Precompiles(blockNumber, 1500), 2)
+		}
+
+		// Precompiles activated by block stay active unless replaced
+		pc, ok = registry.Precompile(addrIncl1, 100, 2000)
+		r.True(ok)
+		r.Equal(blockPc, pc)
+		r.Equal([]common.Address{addrIncl1, addrIncl2}, registry.ActivePrecompiles(100, 2000))
+	})
+	t.Run("BlockAndTimeActivations", func(t *testing.T) {
+		r := require.New(t)
+		var (
+			blockPc = &pcNamed{name: "block"}
+			timePc  = &pcNamed{name: "time"}
+		)
+		registry := NewRegistry()
+		registry.AddPrecompiles(0, PrecompileMap{addrIncl1: blockPc})
+		registry.AddPrecompilesAtTime(1000, PrecompileMap{addrIncl2: timePc})
+		registry.AddPrecompiles(10, PrecompileMap{addrIncl1: blockPc, addrExcl: blockPc})
+
+		// Block activations after the timestamp activation are not hidden by it
+		r.Equal(PrecompileMap{addrIncl1: blockPc, addrIncl2: timePc}, registry.Precompiles(5, 1000))
+		r.Equal(PrecompileMap{addrIncl1: blockPc, addrIncl2: timePc, addrExcl: blockPc}, registry.Precompiles(10, 1000))
+		r.ElementsMatch([]common.Address{addrIncl1, addrExcl, addrIncl2}, registry.ActivePrecompiles(10, 1000))
+		pc, ok := registry.Precompile(addrExcl, 10, 1000)
+		r.True(ok)
+		r.Equal(blockPc, pc)
+
+		// Timestamp activations can replace and deactivate block activations
+		registry.AddPrecompilesAtTime(2000, PrecompileMap{addrIncl1: timePc})
+		registry.RemovePrecompileAtTime(2000, addrExcl)
+		r.Equal(PrecompileMap{addrIncl1: timePc}, registry.Precompiles(10, 2000))
+		_, ok = registry.Precompile(addrExcl, 10, 2000)
+		r.False(ok)
+		r.Panics(func() {
+			registry.RemovePrecompileAtTime(3000, addrExcl)
+		})
+	})
+	t.Run("RemovePrecompile", func(t *testing.T) {
+		r := require.New(t)
+		var (
+			pc1 = &pcNamed{name: "1"}
+			pc2 = &pcNamed{name: "2"}
+		)
+		registry := NewRegistry()
+		registry.AddPrecompiles(0, PrecompileMap{addrIncl1: pc1, addrIncl2: pc2})
+		registry.AddPrecompiles(20, PrecompileMap{addrIncl1: pc1, addrIncl2: pc2})
+		registry.RemovePrecompile(10, addrIncl1)
+		registry.RemovePrecompile(20, addrIncl2)
+		registry.RemovePrecompileAtTime(1000, addrIncl1)
+		r.Panics(func() {
+			registry.RemovePrecompile(30, addrIncl2)
+		})
+		r.Panics(func() {
+			registry.RemovePrecompile(20, addrIncl2)
+		})
+
+		r.Equal(PrecompileMap{addrIncl1: pc1, addrIncl2: pc2}, registry.Precompiles(9, 0))
+		r.Equal(PrecompileMap{addrIncl2: pc2}, registry.Precompiles(10, 0))
+		r.Equal([]common.Address{addrIncl2}, registry.ActivePrecompiles(19, 0))
+		r.Equal(PrecompileMap{addrIncl1: pc1}, registry.Precompiles(20, 0))
+		r.Equal([]common.Address{addrIncl1}, registry.ActivePrecompiles(20, 0))
+		r.Empty(registry.Precompiles(20, 1000))
+	})
+	t.Run("SetPrecompile", func(t *testing.T) {
+		r := require.New(t)
+		var (
+			pc1 = &pcNamed{name: "1"}
+			pc2 = &pcNamed{name: "2"}
+			pc3 = &pcNamed{name: "3"}
+		)
+		registry := NewRegistry()
+		registry.AddPrecompiles(0, PrecompileMap{addrIncl1: pc1})
+		registry.AddPrecompiles(20, PrecompileMap{addrIncl1: pc1, addrIncl2: pc1})
+		registry.SetPrecompile(10, addrIncl2, pc2)
+		registry.SetPrecompile(20, addrIncl1, pc3)
+
+		// Set precompiles are active in every later activation
+		r.Equal(PrecompileMap{addrIncl1: pc1}, registry.Precompiles(9, 0))
+		r.Equal(PrecompileMap{addrIncl1: pc1, addrIncl2: pc2}, registry.Precompiles(10, 0))
+		r.Equal(PrecompileMap{addrIncl1: pc3, addrIncl2: pc2}, registry.Precompiles(20, 0))
+		r.ElementsMatch([]common.Address{addrIncl1, addrIncl2}, registry.ActivePrecompiles(20, 0))
+	})
+}
+
+func TestActivatedPrecompiles(t *testing.T) {
+	r := require.New(t)
+	var (
+		pc1 = &pcNamed{name: "1"}
+		pc2 = &pcNamed{name: "2"}
+	)
+	registry := NewRegistry()
+	registry.AddPrecompiles(0, PrecompileMap{addrIncl1: pc1})
+	registry.AddPrecompiles(10, PrecompileMap{addrIncl1: pc1, addrIncl2: pc1})
+	registry.AddPrecompilesAtTime(1000, PrecompileMap{addrIncl1: pc2, addrIncl2: pc1})
+
+	r.Empty(ActivatedPrecompiles(registry, 0, 0, 0, nil))
+	r.Empty(ActivatedPrecompiles(registry, 9, 0, 0, nil))
+	r.Equal(PrecompileMap{addrIncl2: pc1}, ActivatedPrecompiles(registry, 10, 0, 0, nil))
+	r.Empty(ActivatedPrecompiles(registry, 11, 999, 998, nil))
+	r.Equal(PrecompileMap{addrIncl1: pc2}, ActivatedPrecompiles(registry, 12, 1001, 999, nil))
+	r.Empty(ActivatedPrecompiles(registry, 13, 1002, 1001, nil))
+
+	// Precompiles of uncomparable types are compared by value
+	pc3 := pcUncomparable{data: []byte{1}}
+	registry = NewRegistry()
+	registry.AddPrecompiles(0, PrecompileMap{addrIncl1: pc3})
+	registry.AddPrecompiles(10, PrecompileMap{addrIncl1: pc3, addrIncl2: pc1})
+	registry.AddPrecompiles(20, PrecompileMap{addrIncl1: pcUncomparable{data: []byte{2}}, addrIncl2: pc1})
+	r.Equal(PrecompileMap{addrIncl2: pc1}, ActivatedPrecompiles(registry, 10, 0, 0, nil))
+	r.Equal(PrecompileMap{addrIncl1: pcUncomparable{data: []byte{2}}}, ActivatedPrecompiles(registry, 20, 0, 0, nil))
 }
@@ -0,0 +1,107 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package crypto
+
+// The functions in this file run on the host through the environment, so WASM
+// precompiles get native implementations instead of compiling their own.
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete/api"
+)
+
+var (
+	ErrInvalidSignature  = errors.New("invalid signature")
+	ErrInvalidPairing    = errors.New("invalid pairing input")
+	ErrInvalidKZGInput   = errors.New("invalid point evaluation input")
+	ErrMismatchedVersion = errors.New("mismatched versioned hash")
+)
+
+// Sha256 returns the SHA-256 hash of the concatenated data.
+func Sha256(env api.Environment, data ...[]byte) common.Hash {
+	var input []byte
+	for _, d := range data {
+		input = append(input, d...)
+	}
+	return env.Sha256(input)
+}
+
+// Ecrecover returns the signer of a hash from a 65 byte [R || S || V]
+// signature. V can be 0 or 1, or 27 or 28 as in Solidity.
+func Ecrecover(env api.Environment, hash common.Hash, sig []byte) (common.Address, error) {
+	if len(sig) != 65 {
+		return common.Address{}, ErrInvalidSignature
+	}
+	if v := sig[64]; v == 27 || v == 28 {
+		sig = append(sig[:64:64], v-27)
+	}
+	return env.Ecrecover(hash, sig)
+}
+
+// VerifySignature returns whether sig is a valid signature of hash by signer.
+func VerifySignature(env api.Environment, signer common.Address, hash common.Hash, sig []byte) bool {
+	recovered, err := Ecrecover(env, hash, sig)
+	return err == nil && recovered == signer
+}
+
+// P256Verify verifies a secp256r1 signature (r, s) of a hash against the
+// public key (x, y), as in RIP-7212.
+func P256Verify(env api.Environment, hash common.Hash, r, s, x, y common.Hash) bool {
+	sig := append(r.Bytes(), s.Bytes()...)
+	pubKey := append(x.Bytes(), y.Bytes()...)
+	return env.P256Verify(hash, sig, pubKey)
+}
+
+// Bn256Pairing returns whether the product of the pairings of the 64 byte G1
+// and 128 byte G2 bn256 points is one.
+func Bn256Pairing(env api.Environment, g1 [][]byte, g2 [][]byte) (bool, error) {
+	if len(g1) != len(g2) {
+		return false, ErrInvalidPairing
+	}
+	input := make([]byte, 0, len(g1)*192)
+	for ii := range g1 {
+		if len(g1[ii]) != 64 || len(g2[ii]) != 128 {
+			return false, ErrInvalidPairing
+		}
+		input = append(input, g1[ii]...)
+		input = append(input, g2[ii]...)
+	}
+	return env.Bn256Pairing(input)
+}
+
+// KZGPointEvaluation verifies the 192 byte input of the EIP-4844 point
+// evaluation precompile, i.e. versioned hash, point, claim, commitment and
+// proof, including that the versioned hash matches the commitment.
+func KZGPointEvaluation(env api.Environment, input []byte) error {
+	if len(input) != 192 {
+		return ErrInvalidKZGInput
+	}
+	var (
+		versionedHash = input[:32]
+		point         = common.BytesToHash(input[32:64])
+		claim         = common.BytesToHash(input[64:96])
+		commitment    = input[96:144]
+		proof         = input[144:192]
+	)
+	hash := env.Sha256(commitment)
+	hash[0] = 0x01 // Version of KZG commitment hashes
+	if common.BytesToHash(versionedHash) != hash {
+		return ErrMismatchedVersion
+	}
+	return env.KZGPointEvaluation(commitment, point, claim, proof)
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package crypto
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNativeCrypto(t *testing.T) {
+	var (
+		r    = require.New(t)
+		env  = api.NewMockEnvironment(common.Address{}, api.EnvConfig{Static: true}, false, 0)
+		hash = Keccak256Hash([]byte("concrete"))
+	)
+	r.Equal(common.Hash(sha256.Sum256([]byte("concrete"))), Sha256(env, []byte("con"), []byte("crete")))
+
+	// Signatures with V as 27 or 28
+	key, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+	sig, err := crypto.Sign(hash.Bytes(), key)
+	r.NoError(err)
+	r.True(VerifySignature(env, signer, hash, sig))
+	sig[64] += 27
+	r.True(VerifySignature(env, signer, hash, sig))
+	r.False(VerifySignature(env, common.Address{}, hash, sig))
+	r.False(VerifySignature(env, signer, hash, sig[:64]))
+
+	_, err = Bn256Pairing(env, [][]byte{make([]byte, 64)}, nil)
+	r.Equal(ErrInvalidPairing, err)
+
+	// Input of the point evaluation precompile
+	var blob kzg4844.Blob
+	commitment, err := kzg4844.BlobToCommitment(blob)
+	r.NoError(err)
+	point := kzg4844.Point{31: 1}
+	proof, claim, err := kzg4844.ComputeProof(blob, point)
+	r.NoError(err)
+	versionedHash := kzg4844.CalcBlobHashV1(sha256.New(), &commitment)
+	input := append(append(append(append(versionedHash[:], point[:]...), claim[:]...), commitment[:]...), proof[:]...)
+	r.NoError(KZGPointEvaluation(env, input))
+	input[0] = 0x02
+	r.Equal(ErrMismatchedVersion, KZGPointEvaluation(env, input))
+	r.Equal(ErrInvalidKZGInput, KZGPointEvaluation(env, input[:191]))
+	r.NoError(env.Error())
+}
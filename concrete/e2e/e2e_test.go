@@ -32,8 +32,10 @@ import (
 	"github.com/ethereum/go-ethereum/concrete/wasm"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/stretchr/testify/require"
@@ -291,3 +293,185 @@ func TestE2EKkvPrecompile(t *testing.T) {
 		})
 	}
 }
+
+type migratingKkvPrecompile struct {
+	KeyKeyValuePrecompile
+}
+
+var migrationCounterKey = crypto.Keccak256Hash([]byte("migrations"))
+
+func (a *migratingKkvPrecompile) Migrate(env api.Environment) error {
+	count := env.PersistentLoad(migrationCounterKey).Big()
+	env.PersistentStore(migrationCounterKey, common.BigToHash(count.Add(count, common.Big1)))
+	return nil
+}
+
+var _ concrete.MigratingPrecompile = &migratingKkvPrecompile{}
+
+func TestE2EMigration(t *testing.T) {
+	var (
+		r       = require.New(t)
+		address = common.BytesToAddress([]byte{143})
+		gspec   = &core.Genesis{
+			Config:   params.TestChainConfig,
+			GasLimit: 30_000_000,
+		}
+		nBlocks = 4
+	)
+
+	concreteRegistry := concrete.NewRegistry()
+	concreteRegistry.AddPrecompile(0, address, &KeyKeyValuePrecompile{})
+	concreteRegistry.AddPrecompile(2, address, &migratingKkvPrecompile{})
+	concreteRegistry.RemovePrecompile(4, address)
+
+	db, blocks, _ := core.GenerateChainWithGenesisWithConcrete(gspec, ethash.NewFaker(), nBlocks, concreteRegistry, nil)
+
+	counts := make([]uint64, len(blocks))
+	for ii, block := range blocks {
+		statedb, err := state.New(block.Root(), state.NewDatabase(db), nil)
+		r.NoError(err)
+		counts[ii] = statedb.GetState(address, migrationCounterKey).Big().Uint64()
+	}
+	// The migration runs once, at block 2
+	r.Equal([]uint64{0, 1, 1, 1}, counts)
+
+	r.Empty(concreteRegistry.Precompiles(4, 0))
+}
+
+type tickPrecompile struct {
+	lib.BlankPrecompile
+}
+
+var (
+	tickCounterKey  = crypto.Keccak256Hash([]byte("ticks"))
+	tickEndBlockKey = crypto.Keccak256Hash([]byte("end"))
+)
+
+func (a *tickPrecompile) BeginBlock(env api.Environment) error {
+	count := env.PersistentLoad(tickCounterKey).Big()
+	env.PersistentStore(tickCounterKey, common.BigToHash(count.Add(count, common.Big1)))
+	return nil
+}
+
+func (a *tickPrecompile) EndBlock(env api.Environment) error {
+	number := env.GetBlockNumber()
+	env.PersistentStore(tickEndBlockKey, common.BigToHash(new(big.Int).SetUint64(number)))
+	return nil
+}
+
+var (
+	_ concrete.BeginBlocker = &tickPrecompile{}
+	_ concrete.EndBlocker   = &tickPrecompile{}
+)
+
+func TestE2EBlockHooks(t *testing.T) {
+	var (
+		r       = require.New(t)
+		address = common.BytesToAddress([]byte{144})
+		gspec   = &core.Genesis{
+			Config:   params.TestChainConfig,
+			GasLimit: 30_000_000,
+		}
+		nBlocks = 3
+	)
+
+	concreteRegistry := concrete.NewRegistry()
+	concreteRegistry.AddPrecompile(0, address, &tickPrecompile{})
+
+	db, blocks, _ := core.GenerateChainWithGenesisWithConcrete(gspec, ethash.NewFaker(), nBlocks, concreteRegistry, nil)
+	for ii, block := range blocks {
+		statedb, err := state.New(block.Root(), state.NewDatabase(db), nil)
+		r.NoError(err)
+		r.Equal(uint64(ii+1), statedb.GetState(address, tickCounterKey).Big().Uint64())
+		r.Equal(block.NumberU64(), statedb.GetState(address, tickEndBlockKey).Big().Uint64())
+	}
+
+	// The block processor must arrive at the same state roots
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	r.NoError(err)
+	defer chain.Stop()
+	chain.SetConcrete(concreteRegistry)
+	_, err = chain.InsertChain(blocks)
+	r.NoError(err)
+}
+
+type timerPrecompile struct {
+	lib.BlankPrecompile
+}
+
+var (
+	timerSetKey   = crypto.Keccak256Hash([]byte("set"))
+	timerFiredKey = crypto.Keccak256Hash([]byte("fired"))
+)
+
+const timerDelay = 2
+
+func (a *timerPrecompile) IsStatic(input []byte) bool {
+	return false
+}
+
+func (a *timerPrecompile) BeginBlock(env api.Environment) error {
+	if env.PersistentLoad(timerSetKey) != (common.Hash{}) {
+		return nil
+	}
+	env.PersistentStore(timerSetKey, common.BigToHash(common.Big1))
+	return env.ScheduleCall(env.GetAddress(), env.GetBlockNumber()+timerDelay, []byte("fire"), 100_000)
+}
+
+func (a *timerPrecompile) Run(env api.Environment, input []byte) ([]byte, error) {
+	if env.GetCaller() != env.GetAddress() || string(input) != "fire" {
+		return nil, api.ErrInvalidInput
+	}
+	env.PersistentStore(timerFiredKey, common.BigToHash(new(big.Int).SetUint64(env.GetBlockNumber())))
+	return nil, nil
+}
+
+func TestE2EScheduledCall(t *testing.T) {
+	var (
+		r       = require.New(t)
+		address = common.BytesToAddress([]byte{145})
+		gspec   = &core.Genesis{
+			Config:   params.TestChainConfig,
+			GasLimit: 30_000_000,
+		}
+		nBlocks = 4
+	)
+
+	concreteRegistry := concrete.NewRegistry()
+	concreteRegistry.AddPrecompile(0, address, &timerPrecompile{})
+
+	db, blocks, receipts := core.GenerateChainWithGenesisWithConcrete(gspec, ethash.NewFaker(), nBlocks, concreteRegistry, nil)
+
+	// The timer is set at block 1 and fires at the start of block 3
+	for ii, block := range blocks {
+		if block.NumberU64() != 1+timerDelay {
+			r.Empty(block.Transactions(), "block %d", ii+1)
+			continue
+		}
+		r.Len(block.Transactions(), 1)
+		tx := block.Transactions()[0]
+		r.True(tx.IsDepositTx())
+		r.Equal(address, *tx.To())
+		r.Len(receipts[ii], 1)
+		r.Equal(types.ReceiptStatusSuccessful, receipts[ii][0].Status)
+	}
+	statedb, err := state.New(blocks[len(blocks)-1].Root(), state.NewDatabase(db), nil)
+	r.NoError(err)
+	r.Equal(uint64(1+timerDelay), statedb.GetState(address, timerFiredKey).Big().Uint64())
+
+	// The block processor must execute the scheduled call and arrive at the
+	// same state roots
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	r.NoError(err)
+	defer chain.Stop()
+	chain.SetConcrete(concreteRegistry)
+	_, err = chain.InsertChain(blocks)
+	r.NoError(err)
+
+	// Blocks omitting the scheduled call are rejected
+	statedb, err = chain.StateAt(blocks[timerDelay-1].Root())
+	r.NoError(err)
+	tampered := types.NewBlockWithHeader(blocks[timerDelay].Header())
+	_, _, _, err = chain.Processor().Process(tampered, statedb, vm.Config{})
+	r.Error(err)
+}
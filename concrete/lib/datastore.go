@@ -71,6 +71,26 @@ func (kv *envEphemeralKV) Get(key common.Hash) common.Hash {
 
 var _ KeyValueStore = (*envEphemeralKV)(nil)
 
+// envIsolatedKV stores values in the isolated storage of the precompile, a
+// trie of its own separate from the precompile storage.
+type envIsolatedKV struct {
+	env api.Environment
+}
+
+func newEnvIsolatedKeyValueStore(env api.Environment) *envIsolatedKV {
+	return &envIsolatedKV{env: env}
+}
+
+func (kv *envIsolatedKV) Set(key common.Hash, value common.Hash) {
+	kv.env.IsolatedStore(key, value)
+}
+
+func (kv *envIsolatedKV) Get(key common.Hash) common.Hash {
+	return kv.env.IsolatedLoad(key)
+}
+
+var _ KeyValueStore = (*envIsolatedKV)(nil)
+
 type Datastore interface {
 	Get(key []byte) DatastoreSlot
 }
@@ -107,6 +127,11 @@ func NewEphemeralDatastore(env api.Environment) Datastore {
 	return newDatastore(kv)
 }
 
+func NewIsolatedDatastore(env api.Environment) Datastore {
+	kv := newEnvIsolatedKeyValueStore(env)
+	return newDatastore(kv)
+}
+
 func NewDatastore(env api.Environment) Datastore {
 	return NewPersistentDatastore(env)
 }
@@ -136,6 +161,7 @@ type DatastoreSlot interface {
 	SetInt64(value int64)
 	Bytes() []byte
 	SetBytes(value []byte)
+	ClearBytes()
 }
 
 type dsSlot struct {
@@ -180,16 +206,27 @@ func (r *dsSlot) setBytes32(value common.Hash) {
 	r.ds.kv.Set(r.slot, value)
 }
 
+// Byte strings are stored as in Solidity: short ones in the slot along with
+// twice their length in the lowest byte, long ones as twice their length plus
+// one in the slot and their data from keccak256(slot).
+
+// bytesLength returns the length of the byte string stored with the given
+// header, and whether it is stored out of the slot.
+func bytesLength(header common.Hash) (int, bool) {
+	lsb := header[len(header)-1]
+	if lsb&1 == 0 {
+		return int(lsb) / 2, false
+	}
+	return int(new(big.Int).Rsh(header.Big(), 1).Int64()), true
+}
+
 func (r *dsSlot) getBytes() []byte {
 	slotData := r.ds.kv.Get(r.slot)
-	lsb := slotData[len(slotData)-1]
-	isShort := lsb&1 == 0
-	if isShort {
-		length := int(lsb) / 2
+	length, isLong := bytesLength(slotData)
+	if !isLong {
 		return slotData[:length]
 	}
 
-	length := slotData.Big().Int64()
 	ptr := r.getSlotHash().Big()
 
 	data := make([]byte, length)
@@ -201,9 +238,12 @@ func (r *dsSlot) getBytes() []byte {
 	return data
 }
 
+// setBytes stores the value, clearing the data slots of the previous value
+// that it does not overwrite.
 func (r *dsSlot) setBytes(value []byte) {
 	isShort := len(value) <= 31
 	if isShort {
+		r.clearBytesData(0)
 		var data common.Hash
 		copy(data[:], value)
 		data[31] = byte(len(value) * 2)
@@ -211,7 +251,8 @@ func (r *dsSlot) setBytes(value []byte) {
 		return
 	}
 
-	lengthBN := big.NewInt(int64(len(value)))
+	r.clearBytesData(len(value))
+	lengthBN := big.NewInt(int64(len(value))*2 + 1)
 	r.ds.kv.Set(r.slot, common.BigToHash(lengthBN))
 
 	ptr := r.getSlotHash().Big()
@@ -223,6 +264,28 @@ func (r *dsSlot) setBytes(value []byte) {
 	}
 }
 
+// clearBytes zeroes the slot and, for long values, the data slots written by
+// setBytes.
+func (r *dsSlot) clearBytes() {
+	r.clearBytesData(0)
+	r.setBytes32(common.Hash{})
+}
+
+// clearBytesData zeroes the data slots of a long value past the first keep
+// bytes.
+func (r *dsSlot) clearBytesData(keep int) {
+	length, isLong := bytesLength(r.getBytes32())
+	if !isLong {
+		return
+	}
+	start := int64((keep + 31) / 32)
+	ptr := new(big.Int).Add(r.getSlotHash().Big(), big.NewInt(start))
+	for ii := start * 32; ii < int64(length); ii += 32 {
+		r.ds.kv.Set(common.BigToHash(ptr), common.Hash{})
+		ptr = ptr.Add(ptr, common.Big1)
+	}
+}
+
 func (r *dsSlot) Datastore() Datastore {
 	return r.ds
 }
@@ -323,6 +386,10 @@ func (r *dsSlot) SetBytes(value []byte) {
 	r.setBytes(value)
 }
 
+func (r *dsSlot) ClearBytes() {
+	r.clearBytes()
+}
+
 var _ DatastoreSlot = (*dsSlot)(nil)
 
 type SlotArray interface {
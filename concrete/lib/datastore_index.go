@@ -0,0 +1,189 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package lib
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete/crypto"
+)
+
+// KeyIndex keeps track of the keys in use in a mapping so they can be counted
+// and enumerated.
+//
+// The keys are stored in a dynamic array, each entry holding one slot per key
+// with its length, and the key data in the slots from keccak256(slot). The
+// position of every entry is stored in a mapping from the keys, so
+// keys can be looked up and removed in constant time. Removing a key moves the
+// last entry of the array into its place.
+type KeyIndex struct {
+	numKeys   int
+	entries   DynamicArray
+	positions Mapping
+}
+
+// NewKeyIndex returns the index of the keys of the mapping at the given slot.
+// Entries are tuples of numKeys keys, as passed to Mapping.GetNested.
+func NewKeyIndex(dsSlot DatastoreSlot, numKeys int) *KeyIndex {
+	var (
+		ds            = dsSlot.Datastore()
+		slot          = dsSlot.Slot().Bytes()
+		entriesSlot   = crypto.Keccak256(slot, []byte("index.entries"))
+		positionsSlot = crypto.Keccak256(slot, []byte("index.positions"))
+	)
+	return &KeyIndex{
+		numKeys:   numKeys,
+		entries:   ds.Get(entriesSlot).DynamicArray(),
+		positions: ds.Get(positionsSlot).Mapping(),
+	}
+}
+
+func keyData(keySlot DatastoreSlot, length int) SlotArray {
+	dataSlot := crypto.Keccak256(keySlot.Slot().Bytes())
+	return keySlot.Datastore().Get(dataSlot).SlotArray([]int{(length + 31) / 32})
+}
+
+func readKey(keySlot DatastoreSlot) []byte {
+	length := int(keySlot.Uint64())
+	if length == 0 {
+		return []byte{}
+	}
+	var (
+		arr = keyData(keySlot, length)
+		key = make([]byte, length)
+	)
+	for ii := 0; ii*32 < length; ii++ {
+		word := arr.Get(ii).Bytes32()
+		copy(key[ii*32:], word[:])
+	}
+	return key
+}
+
+func writeKey(keySlot DatastoreSlot, key []byte) {
+	keySlot.SetUint64(uint64(len(key)))
+	if len(key) == 0 {
+		return
+	}
+	arr := keyData(keySlot, len(key))
+	for ii := 0; ii*32 < len(key); ii++ {
+		var word common.Hash
+		copy(word[:], key[ii*32:])
+		arr.Get(ii).SetBytes32(word)
+	}
+}
+
+func clearKey(keySlot DatastoreSlot) {
+	length := int(keySlot.Uint64())
+	if length > 0 {
+		arr := keyData(keySlot, length)
+		for ii := 0; ii*32 < length; ii++ {
+			arr.Get(ii).SetBytes32(common.Hash{})
+		}
+	}
+	keySlot.SetBytes32(common.Hash{})
+}
+
+func (i *KeyIndex) entryKeys(entry DatastoreSlot) SlotArray {
+	return entry.SlotArray([]int{i.numKeys})
+}
+
+func (i *KeyIndex) readEntry(entry DatastoreSlot) [][]byte {
+	var (
+		arr  = i.entryKeys(entry)
+		keys = make([][]byte, i.numKeys)
+	)
+	for ii := 0; ii < i.numKeys; ii++ {
+		keys[ii] = readKey(arr.Get(ii))
+	}
+	return keys
+}
+
+func (i *KeyIndex) writeEntry(entry DatastoreSlot, keys [][]byte) {
+	arr := i.entryKeys(entry)
+	for ii := 0; ii < i.numKeys; ii++ {
+		writeKey(arr.Get(ii), keys[ii])
+	}
+}
+
+func (i *KeyIndex) clearEntry(entry DatastoreSlot) {
+	arr := i.entryKeys(entry)
+	for ii := 0; ii < i.numKeys; ii++ {
+		clearKey(arr.Get(ii))
+	}
+}
+
+// position returns the slot holding the position of the entry of the given
+// keys plus one, or zero if the keys are not in the index.
+func (i *KeyIndex) position(keys [][]byte) DatastoreSlot {
+	if len(keys) != i.numKeys {
+		panic("invalid number of keys")
+	}
+	return i.positions.GetNested(keys...)
+}
+
+// Len returns the number of entries in the index.
+func (i *KeyIndex) Len() uint64 {
+	return i.entries.Length()
+}
+
+// Has returns whether the given keys are in the index.
+func (i *KeyIndex) Has(keys ...[]byte) bool {
+	return i.position(keys).Uint64() != 0
+}
+
+// Get returns the keys of the entry at the given position, or nil if out of
+// range.
+func (i *KeyIndex) Get(index uint64) [][]byte {
+	if index >= i.entries.Length() {
+		return nil
+	}
+	return i.readEntry(i.entries.Get(index))
+}
+
+// Insert adds the given keys to the index and returns whether they were not
+// there already.
+func (i *KeyIndex) Insert(keys ...[]byte) bool {
+	position := i.position(keys)
+	if position.Uint64() != 0 {
+		return false
+	}
+	i.writeEntry(i.entries.Push(), keys)
+	position.SetUint64(i.entries.Length())
+	return true
+}
+
+// Delete removes the given keys from the index and returns whether they were
+// there.
+func (i *KeyIndex) Delete(keys ...[]byte) bool {
+	position := i.position(keys)
+	index := position.Uint64()
+	if index == 0 {
+		return false
+	}
+	length := i.entries.Length()
+	last := i.entries.Get(length - 1)
+	if index != length {
+		// Move the last entry into the place of the removed one
+		lastKeys := i.readEntry(last)
+		entry := i.entries.Get(index - 1)
+		i.clearEntry(entry)
+		i.writeEntry(entry, lastKeys)
+		i.positions.GetNested(lastKeys...).SetUint64(index)
+	}
+	i.clearEntry(last)
+	i.entries.Pop()
+	position.SetBytes32(common.Hash{})
+	return true
+}
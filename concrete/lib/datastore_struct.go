@@ -55,6 +55,10 @@ func NewDatastoreStruct(store DatastoreSlot, sizes []int) *DatastoreStruct {
 	}
 }
 
+func (s *DatastoreStruct) FieldSize(index int) int {
+	return s.sizes[index]
+}
+
 func (s *DatastoreStruct) GetField(index int) []byte {
 	fieldSize := s.sizes[index]
 	absOffset := s.offsets[index]
@@ -100,3 +104,16 @@ func (s *DatastoreStruct) SetField_bytes(index int, data []byte) {
 	slotRef := s.GetField_slot(index)
 	slotRef.SetBytes(data)
 }
+
+func (s *DatastoreStruct) ClearField_bytes(index int) {
+	slotRef := s.GetField_slot(index)
+	slotRef.ClearBytes()
+}
+
+// Clear zeroes all the slots of the struct. The data of long bytes fields is
+// not cleared, use ClearField_bytes first for those.
+func (s *DatastoreStruct) Clear() {
+	for ii := 0; ii < s.arr.Length(); ii++ {
+		s.arr.Get(ii).SetBytes32(common.Hash{})
+	}
+}
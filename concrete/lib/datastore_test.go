@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/concrete/api"
 	"github.com/ethereum/go-ethereum/concrete/mock"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/require"
 )
 
@@ -50,6 +51,10 @@ func TestEnvKeyValueStore(t *testing.T) {
 			name: "Ephemeral",
 			kv:   newEnvEphemeralKeyValueStore(mock.NewMockEnvironment(address, config, meterGas, gas)),
 		},
+		{
+			name: "Isolated",
+			kv:   newEnvIsolatedKeyValueStore(mock.NewMockEnvironment(address, config, meterGas, gas)),
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -140,6 +145,45 @@ func TestDatastoreSlot(t *testing.T) {
 	r.Equal([]byte{0x01, 0x02, 0x03}, slot.Bytes())
 }
 
+func TestBytes(t *testing.T) {
+	var (
+		r          = require.New(t)
+		slot, _, _ = newSlot("bytes.test")
+		kv         = slot.(*dsSlot).ds.kv
+		dataSlot   = func(index int64) common.Hash {
+			return common.BigToHash(new(big.Int).Add(crypto.Keccak256Hash(slot.Slot().Bytes()).Big(), big.NewInt(index)))
+		}
+	)
+	for _, length := range []int{31, 32, 64} {
+		value := make([]byte, length)
+		for ii := range value {
+			value[ii] = byte(ii + 1)
+		}
+		slot.SetBytes(value)
+		r.Equal(value, slot.Bytes())
+
+		// Long values are encoded as in Solidity
+		if length >= 32 {
+			r.Equal(common.BigToHash(big.NewInt(int64(2*length+1))), slot.Bytes32())
+		}
+
+		slot.ClearBytes()
+		r.Equal([]byte{}, slot.Bytes())
+		r.Equal(common.Hash{}, slot.Bytes32())
+		for ii := int64(0); ii < 3; ii++ {
+			r.Equal(common.Hash{}, kv.Get(dataSlot(ii)))
+		}
+	}
+
+	// Shorter values clear the data left over by longer ones
+	slot.SetBytes(make([]byte, 64))
+	slot.SetBytes(make([]byte, 32))
+	r.Equal(common.Hash{}, kv.Get(dataSlot(1)))
+	slot.SetBytes([]byte{0x01})
+	r.Equal(common.Hash{}, kv.Get(dataSlot(0)))
+	r.Equal([]byte{0x01}, slot.Bytes())
+}
+
 func TestMapping(t *testing.T) {
 	var (
 		r          = require.New(t)
@@ -188,3 +232,48 @@ func TestDynamicArray(t *testing.T) {
 		return array.GetNested(1, 0) // slot1_0
 	})
 }
+
+func TestKeyIndex(t *testing.T) {
+	var (
+		r          = require.New(t)
+		slot, _, _ = newSlot("index.test")
+		index      = NewKeyIndex(slot, 2)
+		long       = common.Hash{0x01}.Bytes()
+		keys       = [][][]byte{
+			{{0x01}, {}},
+			{{0x02}, long},
+			{long, append(long, 0x03)},
+		}
+	)
+
+	r.Zero(index.Len())
+	r.Nil(index.Get(0))
+	r.False(index.Delete(keys[0]...))
+
+	for _, k := range keys {
+		r.True(index.Insert(k...))
+		r.False(index.Insert(k...))
+	}
+	r.Equal(uint64(3), index.Len())
+	for ii, k := range keys {
+		r.True(index.Has(k...))
+		r.Equal(k, index.Get(uint64(ii)))
+	}
+
+	// Deleting moves the last entry into the removed one
+	r.True(index.Delete(keys[0]...))
+	r.False(index.Has(keys[0]...))
+	r.Equal(uint64(2), index.Len())
+	r.Equal(keys[2], index.Get(0))
+	r.Equal(keys[1], index.Get(1))
+
+	r.True(index.Delete(keys[1]...))
+	r.True(index.Delete(keys[2]...))
+	r.Zero(index.Len())
+	for _, k := range keys {
+		r.False(index.Has(k...))
+	}
+
+	r.True(index.Insert(keys[1]...))
+	r.Equal(keys[1], index.Get(0))
+}
@@ -0,0 +1,246 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package lib
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/concrete/crypto"
+)
+
+// Store events follow the layout of the MUD store events, so indexers built
+// for MUD can sync tables written by precompiles:
+//
+//	event Store_SetRecord(ResourceId indexed tableId, bytes32[] keyTuple, bytes staticData, EncodedLengths encodedLengths, bytes dynamicData)
+//	event Store_SpliceStaticData(ResourceId indexed tableId, bytes32[] keyTuple, uint48 start, bytes data)
+//	event Store_SpliceDynamicData(ResourceId indexed tableId, bytes32[] keyTuple, uint8 dynamicFieldIndex, uint48 start, uint40 deleteCount, EncodedLengths encodedLengths, bytes data)
+//	event Store_DeleteRecord(ResourceId indexed tableId, bytes32[] keyTuple)
+//
+// The static data of a record is the tight packing of its static fields, and
+// the dynamic data the concatenation of its bytes and string fields. The
+// indexer needs the schema of the table to decode them.
+var (
+	StoreSetRecordTopic           = crypto.Keccak256Hash([]byte("Store_SetRecord(bytes32,bytes32[],bytes,bytes32,bytes)"))
+	StoreSpliceStaticDataTopic    = crypto.Keccak256Hash([]byte("Store_SpliceStaticData(bytes32,bytes32[],uint48,bytes)"))
+	StoreSpliceDynamicDataTopic   = crypto.Keccak256Hash([]byte("Store_SpliceDynamicData(bytes32,bytes32[],uint8,uint48,uint40,bytes32,bytes)"))
+	StoreDeleteRecordTopic        = crypto.Keccak256Hash([]byte("Store_DeleteRecord(bytes32,bytes32[])"))
+	storeTableResourceType        = []byte("tb")
+	storeResourceNamespaceSize    = 14
+	storeResourceNameSize         = 16
+	storeEncodedLengthsTotalBits  = 56
+	storeEncodedLengthsLengthBits = 40
+)
+
+// StoreTableId returns the MUD resource ID of an onchain table. The namespace
+// and name are truncated to 14 and 16 bytes.
+func StoreTableId(namespace, name string) common.Hash {
+	var id common.Hash
+	copy(id[:2], storeTableResourceType)
+	copy(id[2:2+storeResourceNamespaceSize], namespace)
+	copy(id[2+storeResourceNamespaceSize:], name)
+	return id
+}
+
+// StoreEncodedLengths packs the lengths of the dynamic fields of a record as
+// the MUD EncodedLengths type: the total length in the lowest 7 bytes, and
+// the length of each field in the following 5 bytes chunks.
+func StoreEncodedLengths(lengths ...int) common.Hash {
+	var (
+		packed = new(big.Int)
+		total  = 0
+	)
+	for ii, length := range lengths {
+		shift := uint(storeEncodedLengthsTotalBits + ii*storeEncodedLengthsLengthBits)
+		packed.Or(packed, new(big.Int).Lsh(big.NewInt(int64(length)), shift))
+		total += length
+	}
+	packed.Or(packed, big.NewInt(int64(total)))
+	return common.BigToHash(packed)
+}
+
+// StoreKeyUnsigned encodes an unsigned integer, bool, address or enum key as
+// a key tuple element.
+func StoreKeyUnsigned(key []byte) common.Hash {
+	return common.BytesToHash(key)
+}
+
+// StoreKeySigned encodes a two's complement integer key as a key tuple
+// element, extending its sign.
+func StoreKeySigned(key []byte) common.Hash {
+	var word common.Hash
+	if len(key) > 0 && key[0]&0x80 != 0 {
+		for ii := range word {
+			word[ii] = 0xff
+		}
+	}
+	copy(word[32-len(key):], key)
+	return word
+}
+
+// StoreKeyFixedBytes encodes a fixed size bytes key as a key tuple element.
+func StoreKeyFixedBytes(key []byte) common.Hash {
+	var word common.Hash
+	copy(word[:], key)
+	return word
+}
+
+// StoreEvents emits the store events of the writes to a table. Records are
+// read from the DatastoreStruct holding them, which must be written before
+// emitting the event. A nil StoreEvents emits nothing.
+type StoreEvents struct {
+	env           api.Environment
+	tableId       common.Hash
+	staticFields  []int
+	dynamicFields []int
+}
+
+// NewStoreEvents returns the store events of a table with the given static and
+// dynamic struct fields, or nil if env is nil.
+func NewStoreEvents(env api.Environment, tableId common.Hash, staticFields []int, dynamicFields []int) *StoreEvents {
+	if env == nil {
+		return nil
+	}
+	return &StoreEvents{
+		env:           env,
+		tableId:       tableId,
+		staticFields:  staticFields,
+		dynamicFields: dynamicFields,
+	}
+}
+
+func (e *StoreEvents) dynamicLengths(record *DatastoreStruct) []int {
+	lengths := make([]int, len(e.dynamicFields))
+	for ii, field := range e.dynamicFields {
+		lengths[ii] = len(record.GetField_bytes(field))
+	}
+	return lengths
+}
+
+func (e *StoreEvents) log(topic common.Hash, args ...interface{}) {
+	e.env.Log([]common.Hash{topic, e.tableId}, abiEncode(args...))
+}
+
+// SetRecord emits a Store_SetRecord event with the whole record.
+func (e *StoreEvents) SetRecord(keyTuple []common.Hash, record *DatastoreStruct) {
+	if e == nil {
+		return
+	}
+	var staticData, dynamicData []byte
+	for _, field := range e.staticFields {
+		staticData = append(staticData, record.GetField(field)...)
+	}
+	lengths := make([]int, len(e.dynamicFields))
+	for ii, field := range e.dynamicFields {
+		data := record.GetField_bytes(field)
+		lengths[ii] = len(data)
+		dynamicData = append(dynamicData, data...)
+	}
+	e.log(StoreSetRecordTopic, keyTuple, staticData, StoreEncodedLengths(lengths...), dynamicData)
+}
+
+// SpliceStaticData emits a Store_SpliceStaticData event for count consecutive
+// static fields starting at the given struct field.
+func (e *StoreEvents) SpliceStaticData(keyTuple []common.Hash, record *DatastoreStruct, field int, count int) {
+	if e == nil {
+		return
+	}
+	var (
+		start = 0
+		data  []byte
+	)
+	for _, ff := range e.staticFields {
+		if ff < field {
+			start += record.FieldSize(ff)
+		} else if ff < field+count {
+			data = append(data, record.GetField(ff)...)
+		}
+	}
+	e.log(StoreSpliceStaticDataTopic, keyTuple, uint64(start), data)
+}
+
+// DynamicLength returns the current length of a dynamic field, to be passed
+// as deleteCount to SpliceDynamicData once the field is written.
+func (e *StoreEvents) DynamicLength(record *DatastoreStruct, field int) int {
+	if e == nil {
+		return 0
+	}
+	return len(record.GetField_bytes(field))
+}
+
+// SpliceDynamicData emits a Store_SpliceDynamicData event replacing the whole
+// value of a dynamic field, whose previous length was deleteCount.
+func (e *StoreEvents) SpliceDynamicData(keyTuple []common.Hash, record *DatastoreStruct, field int, deleteCount int) {
+	if e == nil {
+		return
+	}
+	var (
+		lengths = e.dynamicLengths(record)
+		index   = 0
+		start   = 0
+	)
+	for index < len(e.dynamicFields) && e.dynamicFields[index] != field {
+		start += lengths[index]
+		index++
+	}
+	if index == len(e.dynamicFields) {
+		panic("not a dynamic field")
+	}
+	data := record.GetField_bytes(field)
+	e.log(StoreSpliceDynamicDataTopic, keyTuple, uint64(index), uint64(start), uint64(deleteCount), StoreEncodedLengths(lengths...), data)
+}
+
+// DeleteRecord emits a Store_DeleteRecord event.
+func (e *StoreEvents) DeleteRecord(keyTuple []common.Hash) {
+	if e == nil {
+		return
+	}
+	e.log(StoreDeleteRecordTopic, keyTuple)
+}
+
+// abiEncode encodes arguments of type uint64, common.Hash, []common.Hash and
+// []byte as the ABI encoding of the corresponding solidity types.
+func abiEncode(args ...interface{}) []byte {
+	var (
+		head = make([]byte, 0, 32*len(args))
+		tail []byte
+	)
+	word := func(value uint64) []byte {
+		return common.BigToHash(new(big.Int).SetUint64(value)).Bytes()
+	}
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case uint64:
+			head = append(head, word(arg)...)
+		case common.Hash:
+			head = append(head, arg.Bytes()...)
+		case []common.Hash:
+			head = append(head, word(uint64(32*len(args)+len(tail)))...)
+			tail = append(tail, word(uint64(len(arg)))...)
+			for _, item := range arg {
+				tail = append(tail, item.Bytes()...)
+			}
+		case []byte:
+			head = append(head, word(uint64(32*len(args)+len(tail)))...)
+			tail = append(tail, word(uint64(len(arg)))...)
+			tail = append(tail, common.RightPadBytes(arg, (len(arg)+31)/32*32)...)
+		default:
+			panic("unsupported abi type")
+		}
+	}
+	return append(head, tail...)
+}
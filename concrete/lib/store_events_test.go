@@ -0,0 +1,136 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !tinygo
+
+// This file will ignored when building with tinygo to prevent compatibility
+// issues.
+
+package lib
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/concrete/mock"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/stretchr/testify/require"
+)
+
+func abiArguments(t *testing.T, types ...string) abi.Arguments {
+	args := make(abi.Arguments, len(types))
+	for ii, typeStr := range types {
+		typ, err := abi.NewType(typeStr, "", nil)
+		require.NoError(t, err)
+		args[ii] = abi.Argument{Type: typ}
+	}
+	return args
+}
+
+func TestStoreEncoding(t *testing.T) {
+	r := require.New(t)
+
+	var tableId common.Hash
+	copy(tableId[:], "tbns")
+	copy(tableId[16:], "Table")
+	r.Equal(tableId, StoreTableId("ns", "Table"))
+	r.Equal(StoreTableId("ns", "0123456789abcdef"), StoreTableId("ns", "0123456789abcdefgh"))
+
+	lengths := StoreEncodedLengths(3, 300)
+	expected := new(big.Int).SetUint64(303)
+	expected.Or(expected, new(big.Int).Lsh(big.NewInt(3), 56))
+	expected.Or(expected, new(big.Int).Lsh(big.NewInt(300), 96))
+	r.Equal(common.BigToHash(expected), lengths)
+
+	r.Equal(common.HexToHash("0x0102"), StoreKeyUnsigned([]byte{1, 2}))
+	r.Equal(common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff82"), StoreKeySigned([]byte{0xff, 0x82}))
+	r.Equal(common.HexToHash("0x0102"), StoreKeySigned([]byte{1, 2}))
+	r.Equal(common.BytesToHash(common.RightPadBytes([]byte{1, 2}, 32)), StoreKeyFixedBytes([]byte{1, 2}))
+
+	var (
+		keyTuple = []common.Hash{{1}, {2}}
+		data     = []byte("data longer than thirty two bytes")
+		args     = abiArguments(t, "bytes32[]", "uint48", "bytes", "bytes32", "bytes")
+	)
+	encoded, err := args.Pack(
+		[][32]byte{keyTuple[0], keyTuple[1]},
+		big.NewInt(7),
+		data,
+		[32]byte(lengths),
+		[]byte{},
+	)
+	r.NoError(err)
+	r.Equal(encoded, abiEncode(keyTuple, uint64(7), data, lengths, []byte{}))
+}
+
+func TestStoreEvents(t *testing.T) {
+	var (
+		r       = require.New(t)
+		statedb = mock.NewMockStateDB()
+		env     = api.NewEnvironment(common.Address{}, api.EnvConfig{}, statedb, api.NewMockBlockContext(), api.NewMockCallContext(), api.NewMockCaller(), false, 0)
+		tableId = StoreTableId("", "Table")
+		ds      = NewDatastore(env)
+		record  = NewDatastoreStruct(ds.Get([]byte{1}), []int{4, 32, 2, 32})
+		events  = NewStoreEvents(env, tableId, []int{0, 2}, []int{1, 3})
+		keys    = []common.Hash{{1}}
+	)
+	lastLog := func(topic common.Hash, types ...string) []interface{} {
+		logs := statedb.(*state.StateDB).Logs()
+		log := logs[len(logs)-1]
+		r.Equal([]common.Hash{topic, tableId}, log.Topics)
+		values, err := abiArguments(t, types...).Unpack(log.Data)
+		r.NoError(err)
+		return values
+	}
+
+	record.SetField(0, []byte{1, 2, 3, 4})
+	record.SetField_bytes(1, []byte("abc"))
+	record.SetField(2, []byte{5, 6})
+	events.SetRecord(keys, record)
+	values := lastLog(StoreSetRecordTopic, "bytes32[]", "bytes", "bytes32", "bytes")
+	r.Equal([][32]byte{keys[0]}, values[0])
+	r.Equal([]byte{1, 2, 3, 4, 5, 6}, values[1])
+	r.Equal([32]byte(StoreEncodedLengths(3, 0)), values[2])
+	r.Equal([]byte("abc"), values[3])
+
+	record.SetField(2, []byte{7, 8})
+	events.SpliceStaticData(keys, record, 2, 1)
+	values = lastLog(StoreSpliceStaticDataTopic, "bytes32[]", "uint48", "bytes")
+	r.Equal(big.NewInt(4), values[1])
+	r.Equal([]byte{7, 8}, values[2])
+
+	deleteCount := events.DynamicLength(record, 3)
+	record.SetField_bytes(3, []byte("defg"))
+	events.SpliceDynamicData(keys, record, 3, deleteCount)
+	values = lastLog(StoreSpliceDynamicDataTopic, "bytes32[]", "uint8", "uint48", "uint40", "bytes32", "bytes")
+	r.Equal(uint8(1), values[1])
+	r.Equal(big.NewInt(3), values[2])
+	r.Zero(values[3].(*big.Int).Sign())
+	r.Equal([32]byte(StoreEncodedLengths(3, 4)), values[4])
+	r.Equal([]byte("defg"), values[5])
+
+	events.DeleteRecord(keys)
+	values = lastLog(StoreDeleteRecordTopic, "bytes32[]")
+	r.Equal([][32]byte{keys[0]}, values[0])
+
+	// Without an environment no events are emitted
+	r.Nil(NewStoreEvents(nil, tableId, nil, nil))
+	var nilEvents *StoreEvents
+	nilEvents.SetRecord(keys, record)
+	r.Len(statedb.(*state.StateDB).Logs(), 4)
+}
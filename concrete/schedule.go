@@ -0,0 +1,222 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package concrete
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// NamedPrecompile is implemented by precompiles that can be referenced by name
+// in the chain config precompile schedule.
+type NamedPrecompile interface {
+	Precompile
+	Name() string
+}
+
+// CodePrecompile is implemented by precompiles compiled from a WASM module,
+// which are referenced by the keccak256 hash of the module in the chain config
+// precompile schedule.
+type CodePrecompile interface {
+	Precompile
+	CodeHash() common.Hash
+}
+
+// ValidateSchedule checks that the registry activates exactly the precompiles
+// in the chain config schedule at their configured activations.
+func ValidateSchedule(registry PrecompileRegistry, config *params.ConcreteConfig) error {
+	if config == nil {
+		return nil
+	}
+	if err := config.CheckConfig(); err != nil {
+		return err
+	}
+	for _, entry := range config.Precompiles {
+		// Timestamp activations follow all block activations
+		var (
+			activation           string
+			number, time         uint64
+			prevNumber, prevTime uint64
+			first                bool
+		)
+		if entry.Block != nil {
+			activation = fmt.Sprintf("block %d", *entry.Block)
+			number, prevNumber = *entry.Block, *entry.Block-1
+			first = *entry.Block == 0
+		} else {
+			activation = fmt.Sprintf("time %d", *entry.Time)
+			number, time = math.MaxUint64, *entry.Time
+			prevNumber, prevTime = math.MaxUint64, *entry.Time-1
+			first = *entry.Time == 0
+		}
+		pc, ok := registry.Precompile(entry.Address, number, time)
+		if entry.Deactivate {
+			if ok {
+				return fmt.Errorf("precompile %v: still registered at %s", entry.Address, activation)
+			}
+			if _, ok := registry.Precompile(entry.Address, prevNumber, prevTime); first || !ok {
+				return fmt.Errorf("precompile %v: deactivated at %s but not registered before", entry.Address, activation)
+			}
+			continue
+		}
+		if !ok {
+			return fmt.Errorf("precompile %v: not registered at %s", entry.Address, activation)
+		}
+		if !matchesEntry(pc, entry) {
+			return fmt.Errorf("precompile %v: registered implementation at %s does not match %s", entry.Address, activation, describeEntry(entry))
+		}
+		if first {
+			continue
+		}
+		if prev, ok := registry.Precompile(entry.Address, prevNumber, prevTime); ok && matchesEntry(prev, entry) {
+			return fmt.Errorf("precompile %v: %s registered before %s", entry.Address, describeEntry(entry), activation)
+		}
+	}
+	if generic, ok := registry.(*GenericPrecompileRegistry); ok {
+		return generic.validateActivations(config)
+	}
+	return nil
+}
+
+// validateActivations checks that every activation and deactivation in the
+// registry is in the schedule.
+func (c *GenericPrecompileRegistry) validateActivations(config *params.ConcreteConfig) error {
+	type scheduleKey struct {
+		address    common.Address
+		time       bool
+		start      uint64
+		deactivate bool
+	}
+	scheduled := make(map[scheduleKey]bool)
+	for _, entry := range config.Precompiles {
+		if entry.Block != nil {
+			scheduled[scheduleKey{entry.Address, false, *entry.Block, entry.Deactivate}] = true
+		} else {
+			scheduled[scheduleKey{entry.Address, true, *entry.Time, entry.Deactivate}] = true
+		}
+	}
+	var last PrecompileMap
+	check := func(kind string, time bool, start uint64, current PrecompileMap, addresses []common.Address) error {
+		for _, address := range addresses {
+			prev, ok := last[address]
+			if ok && samePrecompile(prev, current[address]) {
+				continue
+			}
+			if !scheduled[scheduleKey{address, time, start, false}] {
+				return fmt.Errorf("precompile %v: registered at %s %d but not in the chain config schedule", address, kind, start)
+			}
+		}
+		for address := range last {
+			if _, ok := current[address]; ok {
+				continue
+			}
+			if !scheduled[scheduleKey{address, time, start, true}] {
+				return fmt.Errorf("precompile %v: deactivated at %s %d but not in the chain config schedule", address, kind, start)
+			}
+		}
+		last = current
+		return nil
+	}
+	for ii, start := range c.blocks.starts {
+		if err := check("block", false, start, c.blocks.precompiles[ii], c.blocks.addresses[ii]); err != nil {
+			return err
+		}
+	}
+	if err := c.validateTimeDeactivations(); err != nil {
+		return err
+	}
+	// Timestamp activations are overlaid on the last block activation
+	base, baseAddresses := c.blocks.last()
+	for ii, start := range c.times.starts {
+		current, addresses := overlay(base, baseAddresses, c.times.precompiles[ii], c.times.addresses[ii])
+		if err := check("time", true, start, current, addresses); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateTimeDeactivations checks that precompiles deactivated by timestamp
+// are not changed by block activations once activated. A timestamp
+// deactivation hides the address in every block activation, and block numbers
+// cannot be ordered against timestamps, so any such change would either be
+// hidden or be validated against the wrong block activation.
+func (c *GenericPrecompileRegistry) validateTimeDeactivations() error {
+	for ii, start := range c.times.starts {
+		for _, address := range c.times.addresses[ii] {
+			if c.times.precompiles[ii][address] != nil {
+				continue
+			}
+			var (
+				prev      Precompile
+				activated bool
+			)
+			for jj, blockStart := range c.blocks.starts {
+				pc, ok := c.blocks.precompiles[jj][address]
+				if !activated {
+					prev, activated = pc, ok
+					continue
+				}
+				if !ok || !samePrecompile(prev, pc) {
+					return fmt.Errorf("precompile %v: deactivated at time %d but changed at block %d", address, start, blockStart)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func matchesEntry(pc Precompile, entry params.ConcretePrecompileConfig) bool {
+	if entry.Name != "" {
+		named, ok := pc.(NamedPrecompile)
+		return ok && named.Name() == entry.Name
+	}
+	if entry.CodeHash != nil {
+		code, ok := pc.(CodePrecompile)
+		return ok && code.CodeHash() == *entry.CodeHash
+	}
+	return false
+}
+
+func samePrecompile(a, b Precompile) bool {
+	if na, ok := a.(NamedPrecompile); ok {
+		nb, ok := b.(NamedPrecompile)
+		return ok && na.Name() == nb.Name()
+	}
+	if ca, ok := a.(CodePrecompile); ok {
+		cb, ok := b.(CodePrecompile)
+		return ok && ca.CodeHash() == cb.CodeHash()
+	}
+	// Comparing interfaces holding uncomparable types panics
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	if !reflect.TypeOf(a).Comparable() {
+		return reflect.DeepEqual(a, b)
+	}
+	return a == b
+}
+
+func describeEntry(entry params.ConcretePrecompileConfig) string {
+	if entry.Name != "" {
+		return fmt.Sprintf("name %q", entry.Name)
+	}
+	return fmt.Sprintf("code hash %v", entry.CodeHash)
+}
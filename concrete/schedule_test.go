@@ -0,0 +1,136 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package concrete
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+type pcNamed struct {
+	pcBlank
+	name string
+}
+
+func (pc *pcNamed) Name() string {
+	return pc.name
+}
+
+type pcCode struct {
+	pcBlank
+	codeHash common.Hash
+}
+
+func (pc *pcCode) CodeHash() common.Hash {
+	return pc.codeHash
+}
+
+func TestValidateSchedule(t *testing.T) {
+	var (
+		block10  = uint64(10)
+		block20  = uint64(20)
+		codeHash = common.HexToHash("0x01")
+		named    = &pcNamed{name: "named"}
+		code     = &pcCode{codeHash: codeHash}
+	)
+	newRegistry := func() *GenericPrecompileRegistry {
+		registry := NewRegistry()
+		registry.AddPrecompiles(10, PrecompileMap{addrIncl1: named})
+		registry.AddPrecompiles(20, PrecompileMap{addrIncl1: named, addrIncl2: code})
+		return registry
+	}
+	schedule := func(entries ...params.ConcretePrecompileConfig) *params.ConcreteConfig {
+		return &params.ConcreteConfig{Precompiles: entries}
+	}
+	var (
+		namedEntry = params.ConcretePrecompileConfig{Address: addrIncl1, Name: "named", Block: &block10}
+		codeEntry  = params.ConcretePrecompileConfig{Address: addrIncl2, CodeHash: &codeHash, Block: &block20}
+	)
+
+	tests := []struct {
+		name   string
+		config *params.ConcreteConfig
+		valid  bool
+	}{
+		{"NoSchedule", nil, true},
+		{"Match", schedule(namedEntry, codeEntry), true},
+		{"Missing", schedule(namedEntry), false},
+		{"WrongBlock", schedule(namedEntry, params.ConcretePrecompileConfig{Address: addrIncl2, CodeHash: &codeHash, Block: &block10}), false},
+		{"Late", schedule(params.ConcretePrecompileConfig{Address: addrIncl1, Name: "named", Block: &block20}, codeEntry), false},
+		{"WrongName", schedule(params.ConcretePrecompileConfig{Address: addrIncl1, Name: "other", Block: &block10}, codeEntry), false},
+		{"WrongHash", schedule(namedEntry, params.ConcretePrecompileConfig{Address: addrIncl2, CodeHash: &common.Hash{}, Block: &block20}), false},
+		{"Unregistered", schedule(namedEntry, codeEntry, params.ConcretePrecompileConfig{Address: addrExcl, Name: "named", Block: &block10}), false},
+		{"Timestamp", schedule(namedEntry, codeEntry, params.ConcretePrecompileConfig{Address: addrExcl, Name: "named", Time: &block10}), false},
+		{"NoImplementation", schedule(namedEntry, params.ConcretePrecompileConfig{Address: addrIncl2, Block: &block20}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSchedule(newRegistry(), tt.config)
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+	t.Run("TimeActivation", func(t *testing.T) {
+		var (
+			time1000 = uint64(1000)
+			upgraded = &pcNamed{name: "upgraded"}
+			registry = newRegistry()
+		)
+		registry.AddPrecompilesAtTime(1000, PrecompileMap{addrIncl1: upgraded, addrIncl2: code})
+		upgradeEntry := params.ConcretePrecompileConfig{Address: addrIncl1, Name: "upgraded", Time: &time1000}
+		require.NoError(t, ValidateSchedule(registry, schedule(namedEntry, codeEntry, upgradeEntry)))
+		require.Error(t, ValidateSchedule(registry, schedule(namedEntry, codeEntry)))
+		upgradeEntry.Time = &block20
+		require.Error(t, ValidateSchedule(registry, schedule(namedEntry, codeEntry, upgradeEntry)))
+	})
+	t.Run("Deactivation", func(t *testing.T) {
+		var (
+			block30  = uint64(30)
+			registry = newRegistry()
+		)
+		registry.RemovePrecompile(30, addrIncl1)
+		deactivateEntry := params.ConcretePrecompileConfig{Address: addrIncl1, Deactivate: true, Block: &block30}
+		require.NoError(t, ValidateSchedule(registry, schedule(namedEntry, codeEntry, deactivateEntry)))
+		require.Error(t, ValidateSchedule(registry, schedule(namedEntry, codeEntry)))
+		deactivateEntry.Block = &block20
+		require.Error(t, ValidateSchedule(registry, schedule(namedEntry, codeEntry, deactivateEntry)))
+		deactivateEntry.Block, deactivateEntry.Name = &block30, "named"
+		require.Error(t, ValidateSchedule(registry, schedule(namedEntry, codeEntry, deactivateEntry)))
+	})
+	t.Run("TimeDeactivation", func(t *testing.T) {
+		var (
+			block30  = uint64(30)
+			time1000 = uint64(1000)
+			upgraded = &pcNamed{name: "upgraded"}
+			registry = newRegistry()
+		)
+		registry.RemovePrecompileAtTime(1000, addrIncl1)
+		deactivateEntry := params.ConcretePrecompileConfig{Address: addrIncl1, Deactivate: true, Time: &time1000}
+		require.NoError(t, ValidateSchedule(registry, schedule(namedEntry, codeEntry, deactivateEntry)))
+
+		// Block activations changing the deactivated address would be hidden
+		registry.SetPrecompile(30, addrIncl1, upgraded)
+		upgradeEntry := params.ConcretePrecompileConfig{Address: addrIncl1, Name: "upgraded", Block: &block30}
+		err := ValidateSchedule(registry, schedule(namedEntry, codeEntry, upgradeEntry, deactivateEntry))
+		require.ErrorContains(t, err, "changed at block 30")
+	})
+}
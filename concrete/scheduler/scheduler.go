@@ -0,0 +1,232 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package scheduler implements the queue of calls scheduled by concrete
+// precompiles for execution at the start of a future block.
+//
+// The queue lives in the storage of params.ConcreteSchedulerAddress, so it is
+// part of the state root. The calls scheduled for a block are executed as
+// deposit transactions placed right after the leading deposit transactions of
+// the block, and the queue for the block is cleared before they are executed.
+package scheduler
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	ErrPastBlock    = errors.New("scheduled block is not in the future")
+	ErrQueueFull    = errors.New("scheduled call queue is full")
+	ErrDataTooLarge = errors.New("scheduled call data too large")
+)
+
+const (
+	MaxCallsPerBlock = 16
+	MaxGasPerBlock   = 5_000_000
+	MaxDataSize      = 16 * 1024
+)
+
+// The queue for a block starts at keccak256(block) and is laid out as:
+//
+//	queue + 0: number of calls
+//	queue + 1: total gas of the calls
+//	queue + 2 + 4*i: call i as from, to, gas and data length
+//
+// The data of a call is stored in consecutive slots from keccak256(call).
+const (
+	countOffset      = 0
+	gasOffset        = 1
+	callsOffset      = 2
+	callFromOffset   = 0
+	callToOffset     = 1
+	callGasOffset    = 2
+	callDataOffset   = 3
+	callSize         = 4
+	sourceHashDomain = 0xc0c0
+)
+
+type StateDB interface {
+	GetPersistentState(addr common.Address, key common.Hash) common.Hash
+	SetPersistentState(addr common.Address, key common.Hash, value common.Hash)
+}
+
+// Call is a call scheduled by a precompile. It is executed with the scheduling
+// precompile as the sender.
+type Call struct {
+	From common.Address
+	To   common.Address
+	Gas  uint64
+	Data []byte
+}
+
+// Enqueue schedules a call for the given block. The current block number is
+// used to reject calls that are not scheduled for a future block.
+func Enqueue(db StateDB, currentBlock uint64, block uint64, call Call) error {
+	if block <= currentBlock {
+		return ErrPastBlock
+	}
+	if len(call.Data) > MaxDataSize {
+		return ErrDataTooLarge
+	}
+	var (
+		queue = queueSlot(block)
+		count = getUint64(db, offset(queue, countOffset))
+		gas   = getUint64(db, offset(queue, gasOffset))
+	)
+	if count >= MaxCallsPerBlock || call.Gas > MaxGasPerBlock-gas {
+		return ErrQueueFull
+	}
+	base := offset(queue, callsOffset+count*callSize)
+	set(db, offset(base, callFromOffset), common.BytesToHash(call.From.Bytes()))
+	set(db, offset(base, callToOffset), common.BytesToHash(call.To.Bytes()))
+	setUint64(db, offset(base, callGasOffset), call.Gas)
+	setUint64(db, offset(base, callDataOffset), uint64(len(call.Data)))
+	dataStart := dataSlot(base)
+	for ii := 0; ii*32 < len(call.Data); ii++ {
+		var word common.Hash
+		copy(word[:], call.Data[ii*32:])
+		set(db, offset(dataStart, uint64(ii)), word)
+	}
+	setUint64(db, offset(queue, countOffset), count+1)
+	setUint64(db, offset(queue, gasOffset), gas+call.Gas)
+	return nil
+}
+
+// Calls returns the calls scheduled for the given block, in the order they
+// were scheduled.
+func Calls(db StateDB, block uint64) []Call {
+	var (
+		queue = queueSlot(block)
+		count = getUint64(db, offset(queue, countOffset))
+		calls = make([]Call, 0, count)
+	)
+	for ii := uint64(0); ii < count; ii++ {
+		var (
+			base = offset(queue, callsOffset+ii*callSize)
+			size = getUint64(db, offset(base, callDataOffset))
+			data = make([]byte, 0, size)
+		)
+		dataStart := dataSlot(base)
+		for jj := uint64(0); uint64(len(data)) < size; jj++ {
+			word := get(db, offset(dataStart, jj))
+			remaining := size - uint64(len(data))
+			if remaining < 32 {
+				data = append(data, word[:remaining]...)
+			} else {
+				data = append(data, word[:]...)
+			}
+		}
+		calls = append(calls, Call{
+			From: common.BytesToAddress(get(db, offset(base, callFromOffset)).Bytes()),
+			To:   common.BytesToAddress(get(db, offset(base, callToOffset)).Bytes()),
+			Gas:  getUint64(db, offset(base, callGasOffset)),
+			Data: data,
+		})
+	}
+	return calls
+}
+
+// Clear deletes the queue for the given block.
+func Clear(db StateDB, block uint64) {
+	var (
+		queue = queueSlot(block)
+		count = getUint64(db, offset(queue, countOffset))
+	)
+	if count == 0 {
+		// Avoid creating the scheduler account when nothing was scheduled
+		return
+	}
+	for ii := uint64(0); ii < count; ii++ {
+		var (
+			base      = offset(queue, callsOffset+ii*callSize)
+			size      = getUint64(db, offset(base, callDataOffset))
+			dataStart = dataSlot(base)
+		)
+		for jj := uint64(0); jj*32 < size; jj++ {
+			set(db, offset(dataStart, jj), common.Hash{})
+		}
+		for jj := uint64(0); jj < callSize; jj++ {
+			set(db, offset(base, jj), common.Hash{})
+		}
+	}
+	set(db, offset(queue, countOffset), common.Hash{})
+	set(db, offset(queue, gasOffset), common.Hash{})
+}
+
+// SourceHash returns the deposit source hash of the call at the given index of
+// the queue for the given block.
+func SourceHash(block uint64, index int) common.Hash {
+	return crypto.Keccak256Hash(
+		common.BigToHash(big.NewInt(sourceHashDomain)).Bytes(),
+		common.BigToHash(new(big.Int).SetUint64(block)).Bytes(),
+		common.BigToHash(big.NewInt(int64(index))).Bytes(),
+	)
+}
+
+// Transactions returns the deposit transactions executing the given calls
+// scheduled for the given block.
+func Transactions(block uint64, calls []Call) types.Transactions {
+	txs := make(types.Transactions, 0, len(calls))
+	for ii, call := range calls {
+		to := call.To
+		txs = append(txs, types.NewTx(&types.DepositTx{
+			SourceHash: SourceHash(block, ii),
+			From:       call.From,
+			To:         &to,
+			Value:      new(big.Int),
+			Gas:        call.Gas,
+			Data:       call.Data,
+		}))
+	}
+	return txs
+}
+
+func queueSlot(block uint64) *big.Int {
+	return crypto.Keccak256Hash(common.BigToHash(new(big.Int).SetUint64(block)).Bytes()).Big()
+}
+
+func dataSlot(base *big.Int) *big.Int {
+	return crypto.Keccak256Hash(common.BigToHash(base).Bytes()).Big()
+}
+
+func offset(slot *big.Int, delta uint64) *big.Int {
+	return new(big.Int).Add(slot, new(big.Int).SetUint64(delta))
+}
+
+func get(db StateDB, slot *big.Int) common.Hash {
+	return db.GetPersistentState(params.ConcreteSchedulerAddress, common.BigToHash(slot))
+}
+
+func set(db StateDB, slot *big.Int, value common.Hash) {
+	db.SetPersistentState(params.ConcreteSchedulerAddress, common.BigToHash(slot), value)
+}
+
+func getUint64(db StateDB, slot *big.Int) uint64 {
+	value := get(db, slot).Big()
+	if !value.IsUint64() {
+		return 0
+	}
+	return value.Uint64()
+}
+
+func setUint64(db StateDB, slot *big.Int, value uint64) {
+	set(db, slot, common.BigToHash(new(big.Int).SetUint64(value)))
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package scheduler
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+type testStateDB map[common.Address]map[common.Hash]common.Hash
+
+func (db testStateDB) GetPersistentState(addr common.Address, key common.Hash) common.Hash {
+	return db[addr][key]
+}
+
+func (db testStateDB) SetPersistentState(addr common.Address, key common.Hash, value common.Hash) {
+	if db[addr] == nil {
+		db[addr] = make(map[common.Hash]common.Hash)
+	}
+	if value == (common.Hash{}) {
+		delete(db[addr], key)
+		return
+	}
+	db[addr][key] = value
+}
+
+func TestQueue(t *testing.T) {
+	var (
+		r     = require.New(t)
+		db    = make(testStateDB)
+		calls = []Call{
+			{From: common.Address{1}, To: common.Address{2}, Gas: 21_000},
+			{From: common.Address{1}, To: common.Address{3}, Gas: 50_000, Data: []byte{0x01, 0x02, 0x03}},
+			{From: common.Address{4}, To: common.Address{2}, Gas: 10_000, Data: bytes.Repeat([]byte{0xff}, 70)},
+		}
+	)
+	for _, call := range calls {
+		r.NoError(Enqueue(db, 1, 5, call))
+	}
+	r.NoError(Enqueue(db, 1, 6, calls[0]))
+
+	scheduled := Calls(db, 5)
+	r.Len(scheduled, len(calls))
+	for ii, call := range calls {
+		r.Equal(call.From, scheduled[ii].From)
+		r.Equal(call.To, scheduled[ii].To)
+		r.Equal(call.Gas, scheduled[ii].Gas)
+		r.Equal(len(call.Data), len(scheduled[ii].Data))
+		r.True(bytes.Equal(call.Data, scheduled[ii].Data))
+	}
+	r.Len(Calls(db, 6), 1)
+	r.Empty(Calls(db, 4))
+
+	txs := Transactions(5, scheduled)
+	r.Len(txs, len(calls))
+	r.NotEqual(txs[0].Hash(), Transactions(6, Calls(db, 6))[0].Hash())
+
+	// Clearing a queue leaves no trace of it in storage
+	Clear(db, 6)
+	Clear(db, 5)
+	r.Empty(Calls(db, 5))
+	for _, storage := range db {
+		r.Empty(storage)
+	}
+}
+
+func TestQueueLimits(t *testing.T) {
+	var (
+		r    = require.New(t)
+		db   = make(testStateDB)
+		call = Call{From: common.Address{1}, To: common.Address{2}, Gas: 1}
+	)
+	r.ErrorIs(Enqueue(db, 5, 5, call), ErrPastBlock)
+	r.ErrorIs(Enqueue(db, 5, 4, call), ErrPastBlock)
+	r.ErrorIs(Enqueue(db, 5, 6, Call{Data: make([]byte, MaxDataSize+1)}), ErrDataTooLarge)
+	r.ErrorIs(Enqueue(db, 5, 6, Call{Gas: MaxGasPerBlock + 1}), ErrQueueFull)
+	for ii := 0; ii < MaxCallsPerBlock; ii++ {
+		r.NoError(Enqueue(db, 5, 6, call))
+	}
+	r.ErrorIs(Enqueue(db, 5, 6, call), ErrQueueFull)
+	r.NoError(Enqueue(db, 5, 7, call))
+}
@@ -0,0 +1,452 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/lib"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// CheatcodeAddress is the address of the cheatcode precompile, the same as in
+// Foundry, so Foundry tests calling the supported cheatcodes run unchanged.
+var CheatcodeAddress = common.HexToAddress("0x7109709ECfa91a80626fF3989D68f67F5b1DD12D")
+
+var errUnknownCheatcode = errors.New("unknown cheatcode")
+
+type cheatcode struct {
+	static  bool
+	inputs  abi.Arguments
+	outputs abi.Arguments
+	run     func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error)
+}
+
+var cheatcodeTable = map[[4]byte]*cheatcode{}
+
+func addCheatcode(signature string, outputs []string, static bool, run func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error)) {
+	params := strings.TrimSuffix(signature[strings.Index(signature, "(")+1:], ")")
+	var inputs []string
+	if params != "" {
+		inputs = strings.Split(params, ",")
+	}
+	var selector [4]byte
+	copy(selector[:], crypto.Keccak256([]byte(signature)))
+	cheatcodeTable[selector] = &cheatcode{
+		static:  static,
+		inputs:  arguments(inputs),
+		outputs: arguments(outputs),
+		run:     run,
+	}
+}
+
+func arguments(types []string) abi.Arguments {
+	args := make(abi.Arguments, len(types))
+	for ii, typeStr := range types {
+		typ, err := abi.NewType(typeStr, "", nil)
+		if err != nil {
+			panic(err)
+		}
+		args[ii] = abi.Argument{Type: typ}
+	}
+	return args
+}
+
+func init() {
+	addCheatcode("warp(uint256)", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		time := args[0].(*big.Int).Uint64()
+		c.time = &time
+		c.evm.Context.Time = time
+		return nil, nil
+	})
+	addCheatcode("roll(uint256)", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		c.number = new(big.Int).Set(args[0].(*big.Int))
+		c.evm.Context.BlockNumber = new(big.Int).Set(c.number)
+		return nil, nil
+	})
+	addCheatcode("deal(address,uint256)", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		address := args[0].(common.Address)
+		balance, overflow := uint256.FromBig(args[1].(*big.Int))
+		if overflow {
+			return nil, errors.New("balance overflow")
+		}
+		c.evm.StateDB.SubBalance(address, c.evm.StateDB.GetBalance(address))
+		c.evm.StateDB.AddBalance(address, balance)
+		return nil, nil
+	})
+	addCheatcode("store(address,bytes32,bytes32)", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		c.evm.StateDB.SetState(args[0].(common.Address), args[1].([32]byte), args[2].([32]byte))
+		return nil, nil
+	})
+	addCheatcode("load(address,bytes32)", []string{"bytes32"}, true, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		value := c.evm.StateDB.GetState(args[0].(common.Address), args[1].([32]byte))
+		return []interface{}{[32]byte(value)}, nil
+	})
+	addCheatcode("prank(address)", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		c.prank = &prank{caller: caller, sender: args[0].(common.Address)}
+		return nil, nil
+	})
+	addCheatcode("prank(address,address)", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		origin := args[1].(common.Address)
+		c.prank = &prank{caller: caller, sender: args[0].(common.Address), origin: &origin}
+		return nil, nil
+	})
+	addCheatcode("startPrank(address)", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		c.prank = &prank{caller: caller, sender: args[0].(common.Address), persist: true}
+		return nil, nil
+	})
+	addCheatcode("startPrank(address,address)", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		origin := args[1].(common.Address)
+		c.prank = &prank{caller: caller, sender: args[0].(common.Address), origin: &origin, persist: true}
+		return nil, nil
+	})
+	addCheatcode("stopPrank()", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		c.prank = nil
+		return nil, nil
+	})
+	addCheatcode("expectRevert()", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		c.revert = &expectedRevert{caller: caller}
+		return nil, nil
+	})
+	addCheatcode("expectRevert(bytes)", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		c.revert = &expectedRevert{caller: caller, data: args[0].([]byte)}
+		return nil, nil
+	})
+	addCheatcode("expectRevert(bytes4)", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		selector := args[0].([4]byte)
+		c.revert = &expectedRevert{caller: caller, data: selector[:], selector: true}
+		return nil, nil
+	})
+	addCheatcode("expectEmit()", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		c.expectEmit(caller, [4]bool{true, true, true, true}, nil)
+		return nil, nil
+	})
+	addCheatcode("expectEmit(address)", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		emitter := args[0].(common.Address)
+		c.expectEmit(caller, [4]bool{true, true, true, true}, &emitter)
+		return nil, nil
+	})
+	addCheatcode("expectEmit(bool,bool,bool,bool)", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		c.expectEmit(caller, [4]bool{args[0].(bool), args[1].(bool), args[2].(bool), args[3].(bool)}, nil)
+		return nil, nil
+	})
+	addCheatcode("expectEmit(bool,bool,bool,bool,address)", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		emitter := args[4].(common.Address)
+		c.expectEmit(caller, [4]bool{args[0].(bool), args[1].(bool), args[2].(bool), args[3].(bool)}, &emitter)
+		return nil, nil
+	})
+	addCheatcode("label(address,string)", nil, false, func(c *cheatcodes, caller common.Address, args []interface{}) ([]interface{}, error) {
+		c.labels[args[0].(common.Address)] = args[1].(string)
+		return nil, nil
+	})
+}
+
+// revertWithReason returns the result of a call reverted with a reason string,
+// encoded as solidity's Error(string).
+func revertWithReason(reason string) ([]byte, error) {
+	data, _ := arguments([]string{"string"}).Pack(reason)
+	return append(crypto.Keccak256([]byte("Error(string)"))[:4], data...), vm.ErrExecutionReverted
+}
+
+type prank struct {
+	caller     common.Address // Address whose next call is pranked
+	sender     common.Address
+	origin     *common.Address
+	persist    bool
+	depth      int // Depth of the pranked call, zero until it starts
+	prevOrigin common.Address
+}
+
+type expectedRevert struct {
+	caller   common.Address
+	data     []byte // Expected revert data, nil to accept any
+	selector bool   // Whether data is only a selector the revert data starts with
+	depth    int
+}
+
+func (r *expectedRevert) check(ret []byte, err error) ([]byte, error) {
+	if err == nil {
+		return revertWithReason("call did not revert as expected")
+	}
+	if r.data == nil || bytes.Equal(ret, r.data) || (r.selector && bytes.HasPrefix(ret, r.data)) {
+		return nil, nil
+	}
+	if reason, err := abi.UnpackRevert(ret); err == nil && reason == string(r.data) {
+		return nil, nil
+	}
+	return revertWithReason(fmt.Sprintf("unexpected revert data 0x%x", ret))
+}
+
+type expectedEmit struct {
+	caller   common.Address
+	checks   [4]bool // Whether to check topics 1 to 3 and the data
+	emitter  *common.Address
+	logIndex int // Index of the expected log, emitted after the cheatcode call
+	start    int // Index of the first log emitted by the checked call
+	depth    int
+}
+
+func (e *expectedEmit) matches(expected, log *types.Log) bool {
+	if e.emitter != nil && log.Address != *e.emitter {
+		return false
+	}
+	if len(log.Topics) != len(expected.Topics) {
+		return false
+	}
+	for ii := range log.Topics {
+		if ii > 0 && !e.checks[ii-1] {
+			continue
+		}
+		if log.Topics[ii] != expected.Topics[ii] {
+			return false
+		}
+	}
+	return !e.checks[3] || bytes.Equal(log.Data, expected.Data)
+}
+
+func (e *expectedEmit) check(logs []*types.Log) bool {
+	if e.logIndex >= e.start {
+		return false
+	}
+	for _, log := range logs[e.start:] {
+		if e.matches(logs[e.logIndex], log) {
+			return true
+		}
+	}
+	return false
+}
+
+// cheatcodes implements Foundry cheatcodes for test runs. It is registered as
+// a precompile at CheatcodeAddress and set as the call hook of the EVM, which
+// gives it access to the EVM running the test and lets it alter the calls made
+// by the test contract.
+type cheatcodes struct {
+	lib.BlankPrecompile
+	evm    *vm.EVM
+	depth  int
+	time   *uint64
+	number *big.Int
+	prank  *prank
+	revert *expectedRevert
+	emit   *expectedEmit
+	labels map[common.Address]string
+
+	returnData [][]byte // Data returned by every transaction, in order
+}
+
+var (
+	_ concrete.Precompile = (*cheatcodes)(nil)
+	_ vm.CallHook         = (*cheatcodes)(nil)
+)
+
+func newCheatcodes() *cheatcodes {
+	return &cheatcodes{labels: make(map[common.Address]string)}
+}
+
+// copy returns cheatcodes with the same state, used to run several tests from
+// the state left by setUp.
+func (c *cheatcodes) copy() *cheatcodes {
+	cpy := newCheatcodes()
+	if c.time != nil {
+		time := *c.time
+		cpy.time = &time
+	}
+	if c.number != nil {
+		cpy.number = new(big.Int).Set(c.number)
+	}
+	if c.prank != nil {
+		prank := *c.prank
+		cpy.prank = &prank
+	}
+	if c.revert != nil {
+		revert := *c.revert
+		cpy.revert = &revert
+	}
+	if c.emit != nil {
+		emit := *c.emit
+		cpy.emit = &emit
+	}
+	for address, label := range c.labels {
+		cpy.labels[address] = label
+	}
+	return cpy
+}
+
+func (c *cheatcodes) logs() []*types.Log {
+	if statedb, ok := c.evm.StateDB.(interface{ Logs() []*types.Log }); ok {
+		return statedb.Logs()
+	}
+	return nil
+}
+
+func (c *cheatcodes) expectEmit(caller common.Address, checks [4]bool, emitter *common.Address) {
+	c.emit = &expectedEmit{caller: caller, checks: checks, emitter: emitter, logIndex: len(c.logs())}
+}
+
+// label returns the address followed by its label, if any.
+func (c *cheatcodes) label(address common.Address) string {
+	if label, ok := c.labels[address]; ok {
+		return fmt.Sprintf("%s (%s)", address.Hex(), label)
+	}
+	return address.Hex()
+}
+
+func (c *cheatcodes) IsStatic(input []byte) bool {
+	if len(input) < 4 {
+		return true
+	}
+	if cheat, ok := cheatcodeTable[[4]byte(input[:4])]; ok {
+		return cheat.static
+	}
+	return true
+}
+
+func (c *cheatcodes) Run(env concrete.Environment, input []byte) ([]byte, error) {
+	if len(input) < 4 {
+		return nil, errUnknownCheatcode
+	}
+	cheat, ok := cheatcodeTable[[4]byte(input[:4])]
+	if !ok {
+		return nil, errUnknownCheatcode
+	}
+	args, err := cheat.inputs.Unpack(input[4:])
+	if err != nil {
+		return nil, err
+	}
+	outputs, err := cheat.run(c, env.GetCaller(), args)
+	if err != nil {
+		return nil, err
+	}
+	return cheat.outputs.Pack(outputs...)
+}
+
+func (c *cheatcodes) BeforeCall(evm *vm.EVM, kind vm.OpCode, caller vm.ContractRef, addr common.Address) vm.ContractRef {
+	// Block context overrides persist across transactions
+	c.evm = evm
+	if c.time != nil {
+		evm.Context.Time = *c.time
+	}
+	if c.number != nil {
+		evm.Context.BlockNumber = new(big.Int).Set(c.number)
+	}
+	c.depth++
+	if addr == CheatcodeAddress {
+		return caller
+	}
+
+	from := caller.Address()
+	if r := c.revert; r != nil && r.depth == 0 && r.caller == from {
+		r.depth = c.depth
+	}
+	if e := c.emit; e != nil && e.depth == 0 && e.caller == from {
+		e.depth = c.depth
+		e.start = len(c.logs())
+	}
+	// Pranks do not apply to calls executed in the context of the caller
+	if kind == vm.DELEGATECALL || kind == vm.CALLCODE {
+		return caller
+	}
+	if p := c.prank; p != nil && p.depth == 0 && p.caller == from {
+		p.depth = c.depth
+		if p.origin != nil {
+			p.prevOrigin = evm.Origin
+			evm.Origin = *p.origin
+		}
+		return vm.AccountRef(p.sender)
+	}
+	return caller
+}
+
+func (c *cheatcodes) AfterCall(evm *vm.EVM, kind vm.OpCode, caller common.Address, addr common.Address, ret []byte, err error) ([]byte, error) {
+	ret, err = c.afterCall(evm, ret, err)
+	if c.depth == 0 {
+		c.returnData = append(c.returnData, ret)
+	}
+	return ret, err
+}
+
+func (c *cheatcodes) afterCall(evm *vm.EVM, ret []byte, err error) ([]byte, error) {
+	defer func() { c.depth-- }()
+	if p := c.prank; p != nil && p.depth == c.depth {
+		if p.origin != nil {
+			evm.Origin = p.prevOrigin
+		}
+		if p.persist {
+			p.depth = 0
+		} else {
+			c.prank = nil
+		}
+	}
+	if e := c.emit; e != nil && e.depth == c.depth {
+		c.emit = nil
+		if err == nil && !e.check(c.logs()) {
+			return revertWithReason("log != expected log")
+		}
+	}
+	if r := c.revert; r != nil && r.depth == c.depth {
+		c.revert = nil
+		return r.check(ret, err)
+	}
+	return ret, err
+}
+
+// cheatcodeRegistry adds the cheatcode precompile to the precompiles of a
+// registry.
+type cheatcodeRegistry struct {
+	concrete.PrecompileRegistry
+	cheats *cheatcodes
+}
+
+var _ concrete.StatefulPrecompileRegistry = (*cheatcodeRegistry)(nil)
+
+func (r *cheatcodeRegistry) withCheatcodes(precompiles concrete.PrecompileMap) concrete.PrecompileMap {
+	pcs := concrete.PrecompileMap{}
+	for address, pc := range precompiles {
+		pcs[address] = pc
+	}
+	pcs[CheatcodeAddress] = r.cheats
+	return pcs
+}
+
+func (r *cheatcodeRegistry) Precompile(address common.Address, blockNumber uint64, time uint64) (concrete.Precompile, bool) {
+	if address == CheatcodeAddress {
+		return r.cheats, true
+	}
+	return r.PrecompileRegistry.Precompile(address, blockNumber, time)
+}
+
+func (r *cheatcodeRegistry) Precompiles(blockNumber uint64, time uint64) concrete.PrecompileMap {
+	return r.withCheatcodes(r.PrecompileRegistry.Precompiles(blockNumber, time))
+}
+
+func (r *cheatcodeRegistry) PrecompilesWithState(blockNumber uint64, time uint64, state concrete.StateReader) concrete.PrecompileMap {
+	return r.withCheatcodes(concrete.GetPrecompiles(r.PrecompileRegistry, blockNumber, time, state))
+}
+
+func (r *cheatcodeRegistry) ActivePrecompiles(blockNumber uint64, time uint64) []common.Address {
+	addresses := r.PrecompileRegistry.ActivePrecompiles(blockNumber, time)
+	return append(addresses[:len(addresses):len(addresses)], CheatcodeAddress)
+}
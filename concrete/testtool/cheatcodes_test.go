@@ -0,0 +1,191 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// Contracts returning a 32 byte word pushed by a single opcode
+	callerCode    = common.FromHex("0x3360005260206000f3")
+	originCode    = common.FromHex("0x3260005260206000f3")
+	timestampCode = common.FromHex("0x4260005260206000f3")
+	numberCode    = common.FromHex("0x4360005260206000f3")
+	// Contract reverting with the call data
+	revertCode = common.FromHex("0x366000600037366000fd")
+	// Contract emitting a log with the first word of the call data as topic
+	emitterCode = common.FromHex("0x60003560006000a100")
+)
+
+func cheatInput(t *testing.T, signature string, types []string, args ...interface{}) []byte {
+	input, err := arguments(types).Pack(args...)
+	require.NoError(t, err)
+	return append(crypto.Keccak256([]byte(signature))[:4], input...)
+}
+
+func TestCheatcodes(t *testing.T) {
+	var (
+		r        = require.New(t)
+		test     = common.HexToAddress("0x1000")
+		alice    = common.HexToAddress("0xa11ce")
+		cheats   = newCheatcodes()
+		code     = map[string]common.Address{}
+		db       = state.NewDatabase(rawdb.NewMemoryDatabase())
+		blockCtx = vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			BlockNumber: big.NewInt(1),
+			Time:        1,
+			Difficulty:  big.NewInt(0),
+			BaseFee:     big.NewInt(0),
+		}
+	)
+	statedb, err := state.New(types.EmptyRootHash, db, nil)
+	r.NoError(err)
+	for ii, c := range []struct {
+		name string
+		code []byte
+	}{
+		{"caller", callerCode},
+		{"origin", originCode},
+		{"timestamp", timestampCode},
+		{"number", numberCode},
+		{"revert", revertCode},
+		{"emitter", emitterCode},
+	} {
+		address := common.BigToAddress(big.NewInt(int64(0x2000 + ii)))
+		statedb.SetCode(address, c.code)
+		code[c.name] = address
+	}
+
+	// Every transaction runs in a new EVM
+	newEVM := func() *vm.EVM {
+		return vm.NewEVMWithConcrete(blockCtx, vm.TxContext{Origin: test}, statedb, params.TestChainConfig, vm.Config{CallHook: cheats}, concrete.PrecompileMap{CheatcodeAddress: cheats})
+	}
+	evm := newEVM()
+	call := func(to common.Address, input []byte) ([]byte, error) {
+		ret, _, err := evm.Call(vm.AccountRef(test), to, input, 1e6, new(uint256.Int))
+		return ret, err
+	}
+	cheat := func(signature string, types []string, args ...interface{}) []byte {
+		ret, err := call(CheatcodeAddress, cheatInput(t, signature, types, args...))
+		r.NoError(err)
+		return ret
+	}
+	word := func(to common.Address) common.Hash {
+		ret, err := call(to, nil)
+		r.NoError(err)
+		return common.BytesToHash(ret)
+	}
+
+	// warp and roll persist across transactions
+	cheat("warp(uint256)", []string{"uint256"}, big.NewInt(1000))
+	cheat("roll(uint256)", []string{"uint256"}, big.NewInt(50))
+	r.Equal(common.BigToHash(big.NewInt(1000)), word(code["timestamp"]))
+	evm = newEVM()
+	r.Equal(common.BigToHash(big.NewInt(1000)), word(code["timestamp"]))
+	r.Equal(common.BigToHash(big.NewInt(50)), word(code["number"]))
+
+	// deal, store and load
+	cheat("deal(address,uint256)", []string{"address", "uint256"}, alice, big.NewInt(5))
+	r.Equal(uint256.NewInt(5), statedb.GetBalance(alice))
+	cheat("store(address,bytes32,bytes32)", []string{"address", "bytes32", "bytes32"}, alice, common.Hash{1}, common.Hash{2})
+	r.Equal(common.Hash{2}, statedb.GetState(alice, common.Hash{1}))
+	ret := cheat("load(address,bytes32)", []string{"address", "bytes32"}, alice, common.Hash{1})
+	r.Equal(common.Hash{2}.Bytes(), ret)
+
+	// prank only applies to the next call, startPrank until stopPrank
+	cheat("prank(address,address)", []string{"address", "address"}, alice, alice)
+	r.Equal(common.BytesToHash(alice.Bytes()), word(code["caller"]))
+	r.Equal(common.BytesToHash(test.Bytes()), word(code["caller"]))
+	r.Equal(common.BytesToHash(test.Bytes()), word(code["origin"]))
+	cheat("startPrank(address)", []string{"address"}, alice)
+	r.Equal(common.BytesToHash(alice.Bytes()), word(code["caller"]))
+	r.Equal(common.BytesToHash(alice.Bytes()), word(code["caller"]))
+	cheat("stopPrank()", nil)
+	r.Equal(common.BytesToHash(test.Bytes()), word(code["caller"]))
+
+	// Creations are pranked, delegate calls are not
+	cheat("prank(address)", []string{"address"}, alice)
+	_, created, _, err := evm.Create(vm.AccountRef(test), callerCode, 1e6, new(uint256.Int))
+	r.NoError(err)
+	r.Equal(crypto.CreateAddress(alice, 0), created)
+	cheat("prank(address)", []string{"address"}, alice)
+	contract := vm.NewContract(vm.AccountRef(test), vm.AccountRef(test), new(uint256.Int), 1e6)
+	ret, _, err = evm.DelegateCall(contract, code["caller"], nil, 1e6)
+	r.NoError(err)
+	r.Equal(common.BytesToHash(test.Bytes()), common.BytesToHash(ret))
+	r.Equal(common.BytesToHash(alice.Bytes()), word(code["caller"]))
+
+	// expectRevert turns the expected revert into a success and anything else
+	// into a revert
+	cheat("expectRevert()", nil)
+	_, err = call(code["revert"], []byte{1})
+	r.NoError(err)
+	cheat("expectRevert()", nil)
+	_, err = call(code["caller"], nil)
+	r.ErrorIs(err, vm.ErrExecutionReverted)
+	reason, _ := revertWithReason("reason")
+	cheat("expectRevert(bytes)", []string{"bytes"}, []byte("reason"))
+	_, err = call(code["revert"], reason)
+	r.NoError(err)
+	cheat("expectRevert()", nil)
+	_, _, _, err = evm.Create(vm.AccountRef(test), revertCode, 1e6, new(uint256.Int))
+	r.NoError(err)
+	cheat("expectRevert(bytes4)", []string{"bytes4"}, [4]byte{1, 2, 3, 4})
+	_, err = call(code["revert"], []byte{1, 2, 3, 4, 5})
+	r.NoError(err)
+	cheat("expectRevert(bytes)", []string{"bytes"}, []byte("reason"))
+	_, err = call(code["revert"], []byte{1, 2, 3, 4, 5})
+	r.ErrorIs(err, vm.ErrExecutionReverted)
+
+	// expectEmit checks the next call emits the log emitted after it
+	emit := func(topic common.Hash) {
+		statedb.AddLog(&types.Log{Address: test, Topics: []common.Hash{topic}})
+	}
+	cheat("expectEmit()", nil)
+	emit(common.Hash{1})
+	_, err = call(code["emitter"], common.Hash{1}.Bytes())
+	r.NoError(err)
+	cheat("expectEmit(address)", []string{"address"}, code["emitter"])
+	emit(common.Hash{1})
+	_, err = call(code["emitter"], common.Hash{2}.Bytes())
+	r.ErrorIs(err, vm.ErrExecutionReverted)
+	cheat("expectEmit(address)", []string{"address"}, test)
+	emit(common.Hash{1})
+	_, err = call(code["emitter"], common.Hash{1}.Bytes())
+	r.ErrorIs(err, vm.ErrExecutionReverted)
+
+	cheat("label(address,string)", []string{"address", "string"}, alice, "alice")
+	r.Equal(alice.Hex()+" (alice)", cheats.label(alice))
+
+	_, err = call(CheatcodeAddress, []byte{1, 2, 3, 4})
+	r.ErrorIs(err, vm.ErrExecutionReverted)
+}
@@ -0,0 +1,389 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	maxFuzzLength  = 32   // Maximum length of random bytes, strings and slices
+	maxShrinkSteps = 1024 // Maximum number of runs spent shrinking a failure
+)
+
+type FuzzConfig struct {
+	Runs           int   // Runs of each fuzz test
+	Seed           int64 // Seed of the random inputs, zero for a random seed
+	InvariantRuns  int   // Call sequences run for each invariant test
+	InvariantDepth int   // Calls in each sequence
+}
+
+var DefaultFuzzConfig = FuzzConfig{
+	Runs:           256,
+	InvariantRuns:  64,
+	InvariantDepth: 16,
+}
+
+// withDefaults returns the config with the unset fields taken from
+// DefaultFuzzConfig and the seed set.
+func (c FuzzConfig) withDefaults() FuzzConfig {
+	if c.Runs == 0 {
+		c.Runs = DefaultFuzzConfig.Runs
+	}
+	if c.Seed == 0 {
+		c.Seed = DefaultFuzzConfig.Seed
+	}
+	if c.Seed == 0 {
+		c.Seed = time.Now().UnixNano()
+	}
+	if c.InvariantRuns == 0 {
+		c.InvariantRuns = DefaultFuzzConfig.InvariantRuns
+	}
+	if c.InvariantDepth == 0 {
+		c.InvariantDepth = DefaultFuzzConfig.InvariantDepth
+	}
+	return c
+}
+
+func intRange(typ abi.Type) (*big.Int, *big.Int) {
+	if typ.T == abi.IntTy {
+		max := new(big.Int).Lsh(common.Big1, uint(typ.Size-1))
+		return new(big.Int).Neg(max), max.Sub(max, common.Big1)
+	}
+	max := new(big.Int).Lsh(common.Big1, uint(typ.Size))
+	return new(big.Int), max.Sub(max, common.Big1)
+}
+
+// randomInt returns an integer of the given type. Edge values are picked more
+// often, and the bit length of the other values is uniform so small values are
+// as likely as large ones.
+func randomInt(rng *rand.Rand, typ abi.Type) *big.Int {
+	min, max := intRange(typ)
+	if rng.Intn(4) == 0 {
+		edges := []*big.Int{new(big.Int), big.NewInt(1), min, max, new(big.Int).Sub(max, common.Big1)}
+		return edges[rng.Intn(len(edges))]
+	}
+	bits := uint(1 + rng.Intn(max.BitLen()))
+	x := new(big.Int).Rand(rng, new(big.Int).Lsh(common.Big1, bits))
+	if typ.T == abi.IntTy && rng.Intn(2) == 0 {
+		x.Neg(x)
+	}
+	if x.Cmp(min) < 0 {
+		return min
+	}
+	if x.Cmp(max) > 0 {
+		return max
+	}
+	return x
+}
+
+func randomBytes(rng *rand.Rand, length int) []byte {
+	data := make([]byte, length)
+	rng.Read(data)
+	return data
+}
+
+func randomString(rng *rand.Rand, length int) string {
+	const chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 "
+	data := make([]byte, length)
+	for ii := range data {
+		data[ii] = chars[rng.Intn(len(chars))]
+	}
+	return string(data)
+}
+
+func getInt(value reflect.Value) *big.Int {
+	switch value.Kind() {
+	case reflect.Ptr:
+		return new(big.Int).Set(value.Interface().(*big.Int))
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(value.Int())
+	default:
+		return new(big.Int).SetUint64(value.Uint())
+	}
+}
+
+func setInt(value reflect.Value, x *big.Int) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		value.Set(reflect.ValueOf(new(big.Int).Set(x)))
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value.SetInt(x.Int64())
+	default:
+		value.SetUint(x.Uint64())
+	}
+}
+
+// randomValue returns a random value of the go type the abi package packs as
+// the given type.
+func randomValue(rng *rand.Rand, typ abi.Type) reflect.Value {
+	value := reflect.New(typ.GetType()).Elem()
+	switch typ.T {
+	case abi.IntTy, abi.UintTy:
+		setInt(value, randomInt(rng, typ))
+	case abi.BoolTy:
+		value.SetBool(rng.Intn(2) == 1)
+	case abi.AddressTy, abi.FixedBytesTy, abi.FunctionTy:
+		if rng.Intn(8) != 0 {
+			reflect.Copy(value, reflect.ValueOf(randomBytes(rng, value.Len())))
+		}
+	case abi.BytesTy:
+		value.SetBytes(randomBytes(rng, rng.Intn(maxFuzzLength+1)))
+	case abi.StringTy:
+		value.SetString(randomString(rng, rng.Intn(maxFuzzLength+1)))
+	case abi.SliceTy:
+		length := rng.Intn(maxFuzzLength + 1)
+		value.Set(reflect.MakeSlice(value.Type(), length, length))
+		for ii := 0; ii < length; ii++ {
+			value.Index(ii).Set(randomValue(rng, *typ.Elem))
+		}
+	case abi.ArrayTy:
+		for ii := 0; ii < typ.Size; ii++ {
+			value.Index(ii).Set(randomValue(rng, *typ.Elem))
+		}
+	case abi.TupleTy:
+		for ii, elem := range typ.TupleElems {
+			value.Field(ii).Set(randomValue(rng, *elem))
+		}
+	}
+	return value
+}
+
+func randomArgs(rng *rand.Rand, args abi.Arguments) []reflect.Value {
+	values := make([]reflect.Value, len(args))
+	for ii, arg := range args {
+		values[ii] = randomValue(rng, arg.Type)
+	}
+	return values
+}
+
+func packArgs(method abi.Method, values []reflect.Value) ([]byte, error) {
+	args := make([]interface{}, len(values))
+	for ii, value := range values {
+		args[ii] = value.Interface()
+	}
+	input, err := method.Inputs.Pack(args...)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, method.ID...), input...), nil
+}
+
+func formatArgs(values []reflect.Value) string {
+	args := make([]string, len(values))
+	for ii, value := range values {
+		switch v := value.Interface().(type) {
+		case []byte:
+			args[ii] = fmt.Sprintf("0x%x", v)
+		case string:
+			args[ii] = fmt.Sprintf("%q", v)
+		case common.Address:
+			args[ii] = v.Hex()
+		default:
+			if value.Kind() == reflect.Array && value.Type().Elem().Kind() == reflect.Uint8 {
+				data := make([]byte, value.Len())
+				reflect.Copy(reflect.ValueOf(data), value)
+				args[ii] = fmt.Sprintf("0x%x", data)
+			} else {
+				args[ii] = fmt.Sprint(v)
+			}
+		}
+	}
+	return strings.Join(args, ", ")
+}
+
+// shorterSlices returns shorter versions of a slice, shortest first.
+func shorterSlices(value reflect.Value) []reflect.Value {
+	length := value.Len()
+	if length == 0 {
+		return nil
+	}
+	candidates := []reflect.Value{value.Slice(0, 0)}
+	if length/2 > 0 {
+		candidates = append(candidates, value.Slice(0, length/2))
+	}
+	if length-1 > length/2 {
+		candidates = append(candidates, value.Slice(0, length-1))
+	}
+	return candidates
+}
+
+// shrinkElems returns copies of an array or slice with one of its elements
+// shrunk.
+func shrinkElems(typ abi.Type, value reflect.Value) []reflect.Value {
+	var candidates []reflect.Value
+	for ii := 0; ii < value.Len(); ii++ {
+		for _, elem := range shrinkValue(*typ.Elem, value.Index(ii)) {
+			candidate := reflect.New(value.Type()).Elem()
+			if value.Kind() == reflect.Slice {
+				candidate.Set(reflect.MakeSlice(value.Type(), value.Len(), value.Len()))
+			}
+			reflect.Copy(candidate, value)
+			candidate.Index(ii).Set(elem)
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+// shrinkValue returns simpler values of the given type than value, simplest
+// first. Integers shrink towards zero, bytes, strings and slices towards empty.
+func shrinkValue(typ abi.Type, value reflect.Value) []reflect.Value {
+	var candidates []reflect.Value
+	switch typ.T {
+	case abi.IntTy, abi.UintTy:
+		x := getInt(value)
+		if x.Sign() == 0 {
+			return nil
+		}
+		candidate := reflect.New(value.Type()).Elem()
+		setInt(candidate, new(big.Int))
+		candidates = append(candidates, candidate)
+		for d := new(big.Int).Quo(x, common.Big2); d.Sign() != 0; d.Quo(d, common.Big2) {
+			candidate := reflect.New(value.Type()).Elem()
+			setInt(candidate, new(big.Int).Sub(x, d))
+			candidates = append(candidates, candidate)
+		}
+	case abi.BoolTy:
+		if value.Bool() {
+			candidates = append(candidates, reflect.ValueOf(false))
+		}
+	case abi.AddressTy, abi.FixedBytesTy, abi.FunctionTy:
+		if !value.IsZero() {
+			candidates = append(candidates, reflect.New(value.Type()).Elem())
+		}
+	case abi.BytesTy:
+		candidates = shorterSlices(value)
+	case abi.StringTy:
+		str := value.String()
+		for _, shorter := range shorterSlices(reflect.ValueOf([]byte(str))) {
+			candidates = append(candidates, reflect.ValueOf(string(shorter.Bytes())))
+		}
+	case abi.SliceTy:
+		candidates = append(shorterSlices(value), shrinkElems(typ, value)...)
+	case abi.ArrayTy:
+		candidates = shrinkElems(typ, value)
+	case abi.TupleTy:
+		for ii, elem := range typ.TupleElems {
+			for _, field := range shrinkValue(*elem, value.Field(ii)) {
+				candidate := reflect.New(value.Type()).Elem()
+				candidate.Set(value)
+				candidate.Field(ii).Set(field)
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+	return candidates
+}
+
+// shrinkArgs returns the simplest arguments it finds for which fails still
+// returns true, by repeatedly replacing a single argument by a simpler value.
+func shrinkArgs(args abi.Arguments, values []reflect.Value, fails func([]reflect.Value) bool) []reflect.Value {
+	steps := 0
+	for {
+		shrunk := false
+		for ii := 0; ii < len(values) && !shrunk; ii++ {
+			for _, candidate := range shrinkValue(args[ii].Type, values[ii]) {
+				if steps >= maxShrinkSteps {
+					return values
+				}
+				steps++
+				trial := append([]reflect.Value{}, values...)
+				trial[ii] = candidate
+				if fails(trial) {
+					values = trial
+					shrunk = true
+					break
+				}
+			}
+		}
+		if !shrunk {
+			return values
+		}
+	}
+}
+
+type fuzzResult struct {
+	runs           int
+	meanGas        uint64
+	medianGas      uint64
+	counterexample []reflect.Value // Shrunk failing arguments, nil if passed
+	outcome        *testOutcome    // Outcome of the failing call
+	err            error
+}
+
+// runFuzzTest calls a test method with random arguments until it fails or the
+// configured number of runs is reached. Failing arguments are shrunk.
+func runFuzzTest(runner testRunner, method abi.Method, shouldFail bool, config FuzzConfig) fuzzResult {
+	var (
+		rng    = rand.New(rand.NewSource(config.Seed))
+		gas    []uint64
+		result fuzzResult
+	)
+	run := func(values []reflect.Value) (*testOutcome, error) {
+		input, err := packArgs(method, values)
+		if err != nil {
+			return nil, err
+		}
+		return runTestInput(runner, input, shouldFail)
+	}
+
+	for result.runs < config.Runs {
+		values := randomArgs(rng, method.Inputs)
+		result.runs++
+		outcome, err := run(values)
+		if err == nil {
+			gas = append(gas, outcome.receipt.GasUsed)
+			continue
+		}
+		if outcome == nil {
+			// The test could not be run at all, e.g. setUp failed
+			result.err = err
+			return result
+		}
+		result.counterexample = shrinkArgs(method.Inputs, values, func(trial []reflect.Value) bool {
+			_, err := run(trial)
+			return err != nil
+		})
+		result.outcome, result.err = run(result.counterexample)
+		if result.err == nil {
+			// Flaky failure, report the original arguments
+			result.counterexample = values
+			result.outcome, result.err = outcome, err
+		}
+		return result
+	}
+
+	if len(gas) > 0 {
+		var total uint64
+		for _, g := range gas {
+			total += g
+		}
+		sort.Slice(gas, func(i, j int) bool { return gas[i] < gas[j] })
+		result.meanGas = total / uint64(len(gas))
+		result.medianGas = gas[len(gas)/2]
+	}
+	return result
+}
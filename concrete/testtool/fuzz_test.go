@@ -0,0 +1,161 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"math/big"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// Test contract whose methods revert if the first argument is over 1000
+	fuzzCode = common.FromHex("0x6103e860043511600b57005b600080fd")
+	fuzzABI  = `[
+		{"type":"function","name":"testFuzz","inputs":[{"name":"x","type":"uint256"}],"outputs":[],"stateMutability":"nonpayable"},
+		{"type":"function","name":"testFailFuzz","inputs":[{"name":"x","type":"uint256"}],"outputs":[],"stateMutability":"nonpayable"}
+	]`
+	// Contract storing the argument of set(uint256), and returning it when
+	// called without call data
+	targetCode = common.FromHex("0x3615600c57600435600055005b60005460005260206000f3")
+	targetABI  = `[{"type":"function","name":"set","inputs":[{"name":"x","type":"uint256"}],"outputs":[],"stateMutability":"nonpayable"}]`
+	// Test contract deploying the target contract in setUp, with an invariant
+	// reverting if the value stored by the target is over 1000
+	invariantCode = common.FromHex("0x60003560e01c630a9254e414602d5760206000600060006000545afa50" +
+		"6103e860005111602857005b600080fd5b602480603f60003960006000f060005500" +
+		"601880600c6000396000f300" + "3615600c57600435600055005b60005460005260206000f3")
+	invariantABI = `[
+		{"type":"function","name":"setUp","inputs":[],"outputs":[],"stateMutability":"nonpayable"},
+		{"type":"function","name":"invariant_small","inputs":[],"outputs":[],"stateMutability":"view"}
+	]`
+)
+
+func parseABI(t *testing.T, abiJSON string) abi.ABI {
+	ABI, err := abi.JSON(strings.NewReader(abiJSON))
+	require.NoError(t, err)
+	return ABI
+}
+
+func TestRandomValues(t *testing.T) {
+	var (
+		r   = require.New(t)
+		rng = rand.New(rand.NewSource(1))
+	)
+	tupleType, err := abi.NewType("tuple", "", []abi.ArgumentMarshaling{{Name: "a", Type: "uint24"}, {Name: "b", Type: "bytes"}})
+	r.NoError(err)
+	args := append(arguments([]string{"uint8", "int256", "int64", "bool", "address", "bytes4", "bytes", "string", "uint64[]", "bytes32[2]"}), abi.Argument{Type: tupleType})
+
+	for ii := 0; ii < 100; ii++ {
+		values := randomArgs(rng, args)
+		input := make([]interface{}, len(values))
+		for jj, value := range values {
+			input[jj] = value.Interface()
+		}
+		packed, err := args.Pack(input...)
+		r.NoError(err)
+		_, err = args.Unpack(packed)
+		r.NoError(err)
+	}
+
+	// Signed integers stay in range
+	int8Type := arguments([]string{"int8"})[0].Type
+	for ii := 0; ii < 100; ii++ {
+		x := randomInt(rng, int8Type)
+		r.True(x.IsInt64() && x.Int64() >= -128 && x.Int64() <= 127, x)
+	}
+}
+
+func TestShrinkArgs(t *testing.T) {
+	var (
+		r    = require.New(t)
+		args = arguments([]string{"uint256", "int64", "bytes", "bool"})
+	)
+	values := []reflect.Value{
+		reflect.ValueOf(new(big.Int).Lsh(common.Big1, 200)),
+		reflect.ValueOf(int64(-123456)),
+		reflect.ValueOf([]byte("some bytes")),
+		reflect.ValueOf(true),
+	}
+	shrunk := shrinkArgs(args, values, func(values []reflect.Value) bool {
+		return values[0].Interface().(*big.Int).Cmp(big.NewInt(1000)) > 0 &&
+			values[1].Int() < -50 &&
+			len(values[2].Bytes()) >= 3
+	})
+	r.Equal(big.NewInt(1001), shrunk[0].Interface())
+	r.Equal(int64(-51), shrunk[1].Interface())
+	r.Len(shrunk[2].Bytes(), 3)
+	r.Equal(false, shrunk[3].Interface())
+}
+
+func TestFuzzTest(t *testing.T) {
+	var (
+		r        = require.New(t)
+		registry = concrete.NewRegistry()
+		ABI      = parseABI(t, fuzzABI)
+		config   = FuzzConfig{Runs: 64, Seed: 1}
+		runner   = &chainRunner{registry, fuzzCode}
+	)
+
+	result := runFuzzTest(runner, ABI.Methods["testFuzz"], false, config)
+	r.Error(result.err)
+	r.Equal(big.NewInt(1001), result.counterexample[0].Interface())
+
+	result = runFuzzTest(runner, ABI.Methods["testFailFuzz"], true, config)
+	r.Error(result.err)
+	r.Equal(big.NewInt(0), result.counterexample[0].Interface())
+
+	// A test that always passes runs the configured number of times
+	result = runFuzzTest(&chainRunner{registry, common.FromHex("0x00")}, ABI.Methods["testFuzz"], false, config)
+	r.NoError(result.err)
+	r.Nil(result.counterexample)
+	r.Equal(config.Runs, result.runs)
+	r.NotZero(result.meanGas)
+}
+
+func TestInvariantTest(t *testing.T) {
+	var (
+		r         = require.New(t)
+		registry  = concrete.NewRegistry()
+		ABI       = parseABI(t, invariantABI)
+		artifacts = []testArtifact{{"Target", targetCode, parseABI(t, targetABI)}}
+		config    = FuzzConfig{InvariantRuns: 8, InvariantDepth: 8, Seed: 1}
+	)
+
+	targets, err := invariantTargets(registry, invariantCode, artifacts)
+	r.NoError(err)
+	r.Len(targets, 1)
+	r.Equal("Target", targets[0].name)
+
+	result := runInvariantTest(registry, invariantCode, ABI.Methods["invariant_small"], targets, config)
+	r.Error(result.err)
+	r.Len(result.sequence, 1)
+	r.Equal("set", result.sequence[0].method.Name)
+	r.Equal(1, result.sequence[0].args[0].Interface().(*big.Int).Cmp(big.NewInt(1000)))
+	r.NotNil(result.outcome)
+
+	// Without known targets the invariant cannot be tested
+	targets, err = invariantTargets(registry, invariantCode, nil)
+	r.NoError(err)
+	result = runInvariantTest(registry, invariantCode, ABI.Methods["invariant_small"], targets, config)
+	r.Error(result.err)
+}
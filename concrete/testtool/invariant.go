@@ -0,0 +1,207 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testArtifact is a compiled contract, used to find the ABI of the contracts
+// deployed by the test contract.
+type testArtifact struct {
+	name     string
+	bytecode []byte
+	ABI      abi.ABI
+}
+
+type invariantTarget struct {
+	address common.Address
+	name    string
+	methods []abi.Method // Methods that can change the state
+}
+
+type invariantCall struct {
+	target *invariantTarget
+	method abi.Method
+	args   []reflect.Value
+	input  []byte
+}
+
+func (c invariantCall) String() string {
+	return fmt.Sprintf("%s(%s).%s(%s)", c.target.name, c.target.address.Hex(), c.method.Name, formatArgs(c.args))
+}
+
+// invariantTargets returns the contracts deployed by the test contract in
+// setUp, as in Foundry. Contracts that do not match any artifact or have no
+// state changing methods are skipped.
+func invariantTargets(concreteRegistry concrete.PrecompileRegistry, bytecode []byte, artifacts []testArtifact) ([]*invariantTarget, error) {
+	run, err := runTestChain(concreteRegistry, bytecode, nil)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := run.state()
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []*invariantTarget
+	for nonce := uint64(0); nonce < statedb.GetNonce(testContractAddress); nonce++ {
+		address := crypto.CreateAddress(testContractAddress, nonce)
+		code := statedb.GetCode(address)
+		if len(code) == 0 {
+			continue
+		}
+		for _, artifact := range artifacts {
+			if !bytes.Equal(code, artifact.bytecode) {
+				continue
+			}
+			target := &invariantTarget{address: address, name: artifact.name}
+			for _, method := range artifact.ABI.Methods {
+				if !method.IsConstant() {
+					target.methods = append(target.methods, method)
+				}
+			}
+			if len(target.methods) > 0 {
+				targets = append(targets, target)
+			}
+			break
+		}
+	}
+	return targets, nil
+}
+
+func randomInvariantCall(rng *rand.Rand, targets []*invariantTarget) (invariantCall, error) {
+	target := targets[rng.Intn(len(targets))]
+	method := target.methods[rng.Intn(len(target.methods))]
+	args := randomArgs(rng, method.Inputs)
+	input, err := packArgs(method, args)
+	return invariantCall{target, method, args, input}, err
+}
+
+type invariantResult struct {
+	runs     int
+	calls    int
+	reverts  int
+	sequence []invariantCall // Shrunk sequence breaking the invariant, nil if passed
+	outcome  *testOutcome    // Outcome of the failing invariant check
+	err      error
+}
+
+// runInvariantSequence runs a sequence of calls, checking the invariant after
+// setUp and after every call, each in a block of its own. It returns the number
+// of reverted calls and the outcome of the first failed check, if any.
+func runInvariantSequence(concreteRegistry concrete.PrecompileRegistry, bytecode []byte, invariant abi.Method, sequence []invariantCall) (int, *testOutcome, error) {
+	check := testCall{testContractAddress, invariant.ID}
+	batches := [][]testCall{{check}}
+	for _, call := range sequence {
+		batches = append(batches, []testCall{{call.target.address, call.input}, check})
+	}
+	run, err := runTestChain(concreteRegistry, bytecode, batches)
+	if err != nil {
+		return 0, nil, err
+	}
+	var (
+		reverts = 0
+		txIndex = 0 // Index of the check among all transactions, after setUp
+	)
+	for _, receipts := range run.receipts {
+		txIndex += len(receipts)
+		checkReceipt := receipts[len(receipts)-1]
+		if len(receipts) > 1 && receipts[0].Status != types.ReceiptStatusSuccessful {
+			reverts++
+		}
+		if checkReceipt.Status != types.ReceiptStatusSuccessful {
+			return reverts, &testOutcome{checkReceipt, run.cheats.returnData[txIndex], run.cheats}, nil
+		}
+	}
+	return reverts, nil, nil
+}
+
+// shrinkSequence removes the calls that are not needed to break the
+// invariant.
+func shrinkSequence(sequence []invariantCall, fails func([]invariantCall) bool) []invariantCall {
+	steps := 0
+	for ii := 0; ii < len(sequence) && steps < maxShrinkSteps; {
+		steps++
+		trial := append(append([]invariantCall{}, sequence[:ii]...), sequence[ii+1:]...)
+		if fails(trial) {
+			sequence = trial
+		} else {
+			ii++
+		}
+	}
+	return sequence
+}
+
+// runInvariantTest runs random sequences of calls to the target contracts,
+// checking the invariant holds after each call. The first sequence breaking
+// the invariant is shrunk.
+func runInvariantTest(concreteRegistry concrete.PrecompileRegistry, bytecode []byte, invariant abi.Method, targets []*invariantTarget, config FuzzConfig) invariantResult {
+	var (
+		rng    = rand.New(rand.NewSource(config.Seed))
+		result invariantResult
+	)
+	if len(targets) == 0 {
+		result.err = errors.New("no target contracts")
+		return result
+	}
+
+	for result.runs < config.InvariantRuns {
+		result.runs++
+		sequence := make([]invariantCall, config.InvariantDepth)
+		for ii := range sequence {
+			call, err := randomInvariantCall(rng, targets)
+			if err != nil {
+				result.err = err
+				return result
+			}
+			sequence[ii] = call
+		}
+
+		reverts, outcome, err := runInvariantSequence(concreteRegistry, bytecode, invariant, sequence)
+		if err != nil {
+			result.err = err
+			return result
+		}
+		result.reverts += reverts
+		if outcome == nil {
+			result.calls += len(sequence)
+			continue
+		}
+
+		// The block number of the failed check gives the calls made before it
+		sequence = sequence[:outcome.receipt.BlockNumber.Uint64()-1]
+		result.calls += len(sequence)
+		result.sequence = shrinkSequence(sequence, func(trial []invariantCall) bool {
+			_, outcome, err := runInvariantSequence(concreteRegistry, bytecode, invariant, trial)
+			return err == nil && outcome != nil
+		})
+		_, result.outcome, _ = runInvariantSequence(concreteRegistry, bytecode, invariant, result.sequence)
+		result.err = errors.New("invariant broken")
+		return result
+	}
+	return result
+}
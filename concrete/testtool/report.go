@@ -0,0 +1,198 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+const (
+	KindTest      = "test"
+	KindFuzz      = "fuzz"
+	KindInvariant = "invariant"
+)
+
+// TestResult is the result of a single test method.
+type TestResult struct {
+	Path           string        `json:"path"`     // Path of the source file of the test contract
+	Contract       string        `json:"contract"` // Name of the test contract
+	Test           string        `json:"test"`     // Signature of the test method
+	Kind           string        `json:"kind"`
+	Passed         bool          `json:"passed"`
+	Reason         string        `json:"reason,omitempty"` // Reason of the failure
+	Gas            uint64        `json:"gas,omitempty"`
+	Runs           int           `json:"runs,omitempty"`
+	MeanGas        uint64        `json:"meanGas,omitempty"`
+	MedianGas      uint64        `json:"medianGas,omitempty"`
+	Calls          int           `json:"calls,omitempty"`
+	Reverts        int           `json:"reverts,omitempty"`
+	Seed           int64         `json:"seed,omitempty"`
+	Counterexample []string      `json:"counterexample,omitempty"` // Failing arguments or call sequence
+	Duration       time.Duration `json:"durationNs"`
+}
+
+// details returns the gas or run statistics of the test, as printed by Forge.
+func (r *TestResult) details() string {
+	switch {
+	case r.Kind == KindTest:
+		return fmt.Sprintf("gas: %d", r.Gas)
+	case r.Counterexample != nil:
+		return fmt.Sprintf("runs: %d, seed: %d", r.Runs, r.Seed)
+	case r.Kind == KindFuzz:
+		return fmt.Sprintf("runs: %d, μ: %d, ~: %d", r.Runs, r.MeanGas, r.MedianGas)
+	default:
+		return fmt.Sprintf("runs: %d, calls: %d, reverts: %d", r.Runs, r.Calls, r.Reverts)
+	}
+}
+
+func (r *TestResult) String() string {
+	if r.Passed {
+		return fmt.Sprintf("[PASS] %s (%s)", r.Test, r.details())
+	}
+	return fmt.Sprintf("[FAIL] %s (%s): %s", r.Test, r.details(), r.Reason)
+}
+
+func countResults(results []TestResult) (int, int) {
+	passed := 0
+	for _, result := range results {
+		if result.Passed {
+			passed++
+		}
+	}
+	return passed, len(results) - passed
+}
+
+// decodeRevert returns a readable form of revert data, decoding Error(string),
+// Panic(uint256) and the custom errors of the given ABIs.
+func decodeRevert(data []byte, ABIs ...abi.ABI) string {
+	if len(data) == 0 {
+		return "no revert data"
+	}
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return reason
+	}
+	if len(data) >= 4 {
+		for _, ABI := range ABIs {
+			for _, abiErr := range ABI.Errors {
+				if !bytes.Equal(abiErr.ID[:4], data[:4]) {
+					continue
+				}
+				args, err := abiErr.Inputs.Unpack(data[4:])
+				if err != nil {
+					continue
+				}
+				values := make([]reflect.Value, len(args))
+				for ii, arg := range args {
+					values[ii] = reflect.ValueOf(arg)
+				}
+				return fmt.Sprintf("%s(%s)", abiErr.Name, formatArgs(values))
+			}
+		}
+	}
+	return fmt.Sprintf("0x%x", data)
+}
+
+// WriteJSONReport writes the results as a JSON array.
+func WriteJSONReport(w io.Writer, results []TestResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      float64         `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Time       float64          `xml:"time,attr"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+// WriteJUnitReport writes the results as JUnit XML, with a test suite for
+// every test contract.
+func WriteJUnitReport(w io.Writer, results []TestResult) error {
+	var (
+		report junitTestSuites
+		suites = make(map[string]int)
+	)
+	for _, result := range results {
+		name := result.Path + ":" + result.Contract
+		idx, ok := suites[name]
+		if !ok {
+			idx = len(report.TestSuites)
+			suites[name] = idx
+			report.TestSuites = append(report.TestSuites, junitTestSuite{Name: name})
+		}
+		suite := &report.TestSuites[idx]
+		testCase := junitTestCase{
+			Name:      result.Test,
+			ClassName: name,
+			Time:      result.Duration.Seconds(),
+		}
+		if !result.Passed {
+			text := result.details()
+			for _, line := range result.Counterexample {
+				text += "\n" + line
+			}
+			testCase.Failure = &junitFailure{Message: result.Reason, Text: text}
+			suite.Failures++
+			report.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+		suite.Tests++
+		suite.Time += testCase.Time
+		report.Tests++
+		report.Time += testCase.Time
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
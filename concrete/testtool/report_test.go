@@ -0,0 +1,97 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+var testResults = []TestResult{
+	{Path: "test/A.t.sol", Contract: "ATest", Test: "testA()", Kind: KindTest, Passed: true, Gas: 1000},
+	{Path: "test/A.t.sol", Contract: "ATest", Test: "testB()", Kind: KindTest, Reason: "reverted: oops", Gas: 500},
+	{Path: "test/B.t.sol", Contract: "BTest", Test: "testFuzz(uint256)", Kind: KindFuzz, Passed: true, Runs: 256, MeanGas: 2000, MedianGas: 1990},
+	{Path: "test/B.t.sol", Contract: "BTest", Test: "invariant_a()", Kind: KindInvariant, Passed: true, Runs: 64, Calls: 1024, Reverts: 3},
+}
+
+func TestDecodeRevert(t *testing.T) {
+	r := require.New(t)
+	ABI := parseABI(t, `[{"type":"error","name":"TooBig","inputs":[{"name":"x","type":"uint256"},{"name":"who","type":"address"}]}]`)
+
+	reason, _ := revertWithReason("oops")
+	r.Equal("oops", decodeRevert(reason, ABI))
+	custom, err := ABI.Errors["TooBig"].Inputs.Pack(big.NewInt(7), common.HexToAddress("0x01"))
+	r.NoError(err)
+	custom = append(crypto.Keccak256([]byte("TooBig(uint256,address)"))[:4], custom...)
+	r.Equal("TooBig(7, 0x0000000000000000000000000000000000000001)", decodeRevert(custom, ABI))
+	r.Equal("0x01020304", decodeRevert([]byte{1, 2, 3, 4}, ABI))
+	r.Equal("no revert data", decodeRevert(nil))
+}
+
+func TestReports(t *testing.T) {
+	r := require.New(t)
+
+	var buf bytes.Buffer
+	r.NoError(WriteJSONReport(&buf, testResults))
+	var decoded []TestResult
+	r.NoError(json.Unmarshal(buf.Bytes(), &decoded))
+	r.Equal(testResults, decoded)
+
+	buf.Reset()
+	r.NoError(WriteJUnitReport(&buf, testResults))
+	var junit junitTestSuites
+	r.NoError(xml.Unmarshal(buf.Bytes(), &junit))
+	r.Equal(4, junit.Tests)
+	r.Equal(1, junit.Failures)
+	r.Len(junit.TestSuites, 2)
+	r.Equal("test/A.t.sol:ATest", junit.TestSuites[0].Name)
+	r.Equal("reverted: oops", junit.TestSuites[0].TestCases[1].Failure.Message)
+}
+
+func TestGasSnapshot(t *testing.T) {
+	r := require.New(t)
+
+	var buf bytes.Buffer
+	r.NoError(WriteGasSnapshot(&buf, testResults))
+	r.Equal(`ATest:testA() (gas: 1000)
+BTest:invariant_a() (runs: 64, calls: 1024, reverts: 3)
+BTest:testFuzz(uint256) (runs: 256, μ: 2000, ~: 1990)
+`, buf.String())
+	snapshot, err := ReadGasSnapshot(&buf)
+	r.NoError(err)
+	r.Equal(map[string]uint64{"ATest:testA()": 1000, "BTest:testFuzz(uint256)": 2000}, snapshot)
+	_, err = ReadGasSnapshot(strings.NewReader("ATest:testA() 1000"))
+	r.Error(err)
+
+	results := append([]TestResult{}, testResults...)
+	results[0].Gas = 1100
+	buf.Reset()
+	r.NoError(DiffGasSnapshot(&buf, snapshot, results))
+	r.Equal("ATest:testA() (gas: +100 (+10.000%))\nOverall gas change: +100 (+3.333%)\n", buf.String())
+
+	r.Equal(1, CheckGasSnapshot(snapshot, results))
+	r.False(results[0].Passed)
+	r.Equal("gas 1100 != snapshot gas 1000", results[0].Reason)
+	r.True(results[2].Passed)
+}
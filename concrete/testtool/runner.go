@@ -0,0 +1,152 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testOutcome is the outcome of a call to the test contract.
+type testOutcome struct {
+	receipt    *types.Receipt
+	returnData []byte // Data returned by the call, used to decode reverts
+	cheats     *cheatcodes
+}
+
+// testRunner calls the test contract after setUp.
+type testRunner interface {
+	run(input []byte) (*testOutcome, error)
+}
+
+// chainRunner generates a new chain for every call, running setUp and the
+// call in its first block.
+type chainRunner struct {
+	registry concrete.PrecompileRegistry
+	bytecode []byte
+}
+
+func (r *chainRunner) run(input []byte) (*testOutcome, error) {
+	run, err := runTestChain(r.registry, r.bytecode, [][]testCall{{{testContractAddress, input}}})
+	if err != nil {
+		return nil, err
+	}
+	returnData := run.cheats.returnData
+	return &testOutcome{
+		receipt:    run.receipts[0][0],
+		returnData: returnData[len(returnData)-1],
+		cheats:     run.cheats,
+	}, nil
+}
+
+// testChain gives access to the headers of a test chain.
+type testChain struct {
+	db ethdb.Database
+}
+
+var _ core.ChainContext = (*testChain)(nil)
+
+func (c *testChain) Engine() consensus.Engine {
+	return ethash.NewFaker()
+}
+
+func (c *testChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return rawdb.ReadHeader(c.db, hash, number)
+}
+
+// isolatedRunner runs setUp once and every call on top of the state it left,
+// reverting the changes of the call after it. Calls run in the block of setUp,
+// as with chainRunner, but the state is not finalised between setUp and the
+// call.
+type isolatedRunner struct {
+	registry concrete.PrecompileRegistry
+	statedb  *state.StateDB
+	header   *types.Header
+	chain    *testChain
+	cheats   *cheatcodes // Cheatcode state left by setUp
+}
+
+func newIsolatedRunner(concreteRegistry concrete.PrecompileRegistry, bytecode []byte) (*isolatedRunner, error) {
+	run, err := runTestChain(concreteRegistry, bytecode, nil)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := run.state()
+	if err != nil {
+		return nil, err
+	}
+	return &isolatedRunner{
+		registry: concreteRegistry,
+		statedb:  statedb,
+		header:   run.blocks[0].Header(),
+		chain:    &testChain{run.db},
+		cheats:   run.cheats,
+	}, nil
+}
+
+func (r *isolatedRunner) run(input []byte) (*testOutcome, error) {
+	snapshot := r.statedb.Snapshot()
+	defer r.statedb.RevertToSnapshot(snapshot)
+
+	var (
+		cheats   = r.cheats.copy()
+		registry = &cheatcodeRegistry{r.registry, cheats}
+		number   = r.header.Number.Uint64()
+		txHash   = crypto.Keccak256Hash(input)
+		msg      = &core.Message{
+			From:      testSenderAddress,
+			To:        &testContractAddress,
+			Nonce:     r.statedb.GetNonce(testSenderAddress),
+			Value:     new(big.Int),
+			GasLimit:  testGasLimit,
+			GasPrice:  r.header.BaseFee,
+			GasFeeCap: r.header.BaseFee,
+			GasTipCap: r.header.BaseFee,
+			Data:      input,
+		}
+		blockCtx = core.NewEVMBlockContext(r.header, r.chain, &r.header.Coinbase, params.TestChainConfig, r.statedb)
+		pcs      = concrete.GetPrecompiles(registry, number, r.header.Time, r.statedb)
+		evm      = vm.NewEVMWithConcrete(blockCtx, core.NewEVMTxContext(msg), r.statedb, params.TestChainConfig, vm.Config{CallHook: cheats}, pcs)
+	)
+	r.statedb.SetTxContext(txHash, 0)
+	result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(r.header.GasLimit))
+	if err != nil {
+		return nil, err
+	}
+	receipt := &types.Receipt{
+		Status:      types.ReceiptStatusSuccessful,
+		TxHash:      txHash,
+		GasUsed:     result.UsedGas,
+		Logs:        r.statedb.GetLogs(txHash, number, r.header.Hash()),
+		BlockNumber: r.header.Number,
+	}
+	if result.Failed() {
+		receipt.Status = types.ReceiptStatusFailed
+	}
+	return &testOutcome{receipt: receipt, returnData: result.ReturnData, cheats: cheats}, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// Test contract incrementing a counter on every call, reverting if it goes
+	// over 2, i.e. if calls after setUp are not isolated
+	counterCode = common.FromHex("0x60005460010180600055600311601457600080fd5b00")
+	// Test contract reverting with the call data unless it is 4 bytes long
+	revertUnlessSetUpCode = common.FromHex("0x36600414601157366000600037366000fd5b00")
+)
+
+func TestIsolatedRunner(t *testing.T) {
+	var (
+		r        = require.New(t)
+		registry = concrete.NewRegistry()
+	)
+	chain := &chainRunner{registry, counterCode}
+	expected, err := chain.run([]byte{1})
+	r.NoError(err)
+	r.Equal(types.ReceiptStatusSuccessful, expected.receipt.Status)
+
+	isolated, err := newIsolatedRunner(registry, counterCode)
+	r.NoError(err)
+	for ii := 0; ii < 3; ii++ {
+		outcome, err := isolated.run([]byte{1})
+		r.NoError(err)
+		r.Equal(types.ReceiptStatusSuccessful, outcome.receipt.Status)
+		r.Equal(expected.receipt.GasUsed, outcome.receipt.GasUsed)
+	}
+
+	// Revert data is kept in both modes
+	isolated, err = newIsolatedRunner(registry, revertUnlessSetUpCode)
+	r.NoError(err)
+	for _, runner := range []testRunner{&chainRunner{registry, revertUnlessSetUpCode}, isolated} {
+		outcome, err := runTestInput(runner, []byte{1, 2, 3}, false)
+		r.EqualError(err, "reverted")
+		r.Equal([]byte{1, 2, 3}, outcome.returnData)
+		_, err = runTestInput(runner, []byte{1, 2, 3}, true)
+		r.NoError(err)
+		_, err = runTestInput(runner, []byte{1, 2, 3, 4}, true)
+		r.EqualError(err, "expected revert")
+	}
+
+	// setUp failures are reported when creating the runner
+	_, err = newIsolatedRunner(registry, revertCode)
+	r.Error(err)
+}
+
+func TestRunTestContractIsolated(t *testing.T) {
+	r := require.New(t)
+	opts := testOptions{fuzz: FuzzConfig{Runs: 16, Seed: 1}, isolate: true}
+	results := runTestContract(concrete.NewRegistry(), fuzzCode, parseABI(t, fuzzABI), opts)
+	r.Len(results, 2)
+	r.Equal("testFailFuzz(uint256)", results[0].Test)
+	r.Equal("expected revert", results[0].Reason)
+	r.Equal([]string{"testFailFuzz(0)"}, results[0].Counterexample)
+	r.Equal("testFuzz(uint256)", results[1].Test)
+	r.Equal("reverted: no revert data", results[1].Reason)
+	r.Equal([]string{"testFuzz(1001)"}, results[1].Counterexample)
+}
@@ -0,0 +1,174 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SnapshotMode is how the gas snapshot file is used, following forge snapshot.
+type SnapshotMode int
+
+const (
+	SnapshotWrite SnapshotMode = iota // Write the gas used by the tests to the file
+	SnapshotCheck                     // Fail tests whose gas differs from the file
+	SnapshotDiff                      // Print the gas differences with the file
+)
+
+var snapshotLineRegexp = regexp.MustCompile(`^(\S+) \((?:gas: (\d+)|runs: \d+, μ: (\d+), ~: \d+|runs: \d+, calls: \d+, reverts: \d+)\)$`)
+
+func (r *TestResult) snapshotKey() string {
+	return r.Contract + ":" + r.Test
+}
+
+// snapshotGas returns the gas compared across snapshots, the mean gas for fuzz
+// tests. Invariant tests have none.
+func (r *TestResult) snapshotGas() (uint64, bool) {
+	switch r.Kind {
+	case KindTest:
+		return r.Gas, true
+	case KindFuzz:
+		return r.MeanGas, true
+	default:
+		return 0, false
+	}
+}
+
+// WriteGasSnapshot writes the gas used by the passed tests in the format of a
+// Forge .gas-snapshot file.
+func WriteGasSnapshot(w io.Writer, results []TestResult) error {
+	var lines []string
+	for _, result := range results {
+		if result.Passed {
+			lines = append(lines, fmt.Sprintf("%s (%s)", result.snapshotKey(), result.details()))
+		}
+	}
+	sort.Strings(lines)
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadGasSnapshot reads a gas snapshot file, returning the gas of every test
+// by Contract:test key.
+func ReadGasSnapshot(r io.Reader) (map[string]uint64, error) {
+	var (
+		snapshot = make(map[string]uint64)
+		scanner  = bufio.NewScanner(r)
+		lineNum  = 0
+	)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		match := snapshotLineRegexp.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("invalid gas snapshot line %d: %s", lineNum, line)
+		}
+		gas := match[2] + match[3]
+		if gas == "" {
+			continue
+		}
+		value, err := strconv.ParseUint(gas, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gas snapshot line %d: %w", lineNum, err)
+		}
+		snapshot[match[1]] = value
+	}
+	return snapshot, scanner.Err()
+}
+
+// CheckGasSnapshot fails the passed tests whose gas differs from the snapshot
+// or that are missing from it. It returns the number of failed tests.
+func CheckGasSnapshot(snapshot map[string]uint64, results []TestResult) int {
+	failed := 0
+	for ii := range results {
+		result := &results[ii]
+		gas, ok := result.snapshotGas()
+		if !result.Passed || !ok {
+			continue
+		}
+		expected, ok := snapshot[result.snapshotKey()]
+		switch {
+		case !ok:
+			result.Reason = "not in gas snapshot"
+		case expected != gas:
+			result.Reason = fmt.Sprintf("gas %d != snapshot gas %d", gas, expected)
+		default:
+			continue
+		}
+		result.Passed = false
+		failed++
+	}
+	return failed
+}
+
+// DiffGasSnapshot writes the gas differences of the passed tests with the
+// snapshot, sorted by increasing difference, followed by the overall change.
+func DiffGasSnapshot(w io.Writer, snapshot map[string]uint64, results []TestResult) error {
+	type gasDiff struct {
+		key        string
+		prev, curr uint64
+	}
+	var (
+		diffs      []gasDiff
+		prev, curr uint64
+	)
+	for _, result := range results {
+		gas, ok := result.snapshotGas()
+		if !result.Passed || !ok {
+			continue
+		}
+		expected, ok := snapshot[result.snapshotKey()]
+		if !ok {
+			continue
+		}
+		prev += expected
+		curr += gas
+		if expected != gas {
+			diffs = append(diffs, gasDiff{result.snapshotKey(), expected, gas})
+		}
+	}
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return int64(diffs[i].curr-diffs[i].prev) < int64(diffs[j].curr-diffs[j].prev)
+	})
+	for _, diff := range diffs {
+		if _, err := fmt.Fprintf(w, "%s (gas: %s)\n", diff.key, formatGasChange(diff.prev, diff.curr)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "Overall gas change: %s\n", formatGasChange(prev, curr))
+	return err
+}
+
+func formatGasChange(prev, curr uint64) string {
+	change := int64(curr - prev)
+	if prev == 0 {
+		return fmt.Sprintf("%+d", change)
+	}
+	return fmt.Sprintf("%+d (%+.3f%%)", change, float64(change)*100/float64(prev))
+}
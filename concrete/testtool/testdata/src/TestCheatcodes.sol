@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: LGPL-3.0-only
+pragma solidity ^0.8.0;
+
+/*
+This contract tests the cheatcodes supported by the test tool, called through
+the same interface as in Foundry
+*/
+
+interface Vm {
+    function warp(uint256) external;
+    function roll(uint256) external;
+    function deal(address, uint256) external;
+    function store(address, bytes32, bytes32) external;
+    function load(address, bytes32) external view returns (bytes32);
+    function prank(address) external;
+    function startPrank(address) external;
+    function stopPrank() external;
+    function expectRevert() external;
+    function expectRevert(bytes calldata) external;
+    function expectEmit() external;
+    function label(address, string calldata) external;
+}
+
+contract Target {
+    event Called(address indexed caller, uint256 value);
+
+    uint256 public value;
+
+    function caller() external view returns (address) {
+        return msg.sender;
+    }
+
+    function call(uint256 _value) external {
+        value = _value;
+        emit Called(msg.sender, _value);
+    }
+
+    function fail() external pure {
+        revert("failed");
+    }
+}
+
+contract TestCheatcodes {
+    event Called(address indexed caller, uint256 value);
+
+    Vm constant vm = Vm(address(uint160(uint256(keccak256("hevm cheat code")))));
+    address constant alice = address(0xa11ce);
+    Target target;
+
+    function setUp() external {
+        target = new Target();
+        vm.label(address(target), "target");
+        vm.warp(1000);
+        vm.roll(50);
+    }
+
+    function testWarpRoll() external view {
+        require(block.timestamp == 1000, "timestamp not warped");
+        require(block.number == 50, "number not rolled");
+    }
+
+    function testDeal() external {
+        vm.deal(alice, 1 ether);
+        require(alice.balance == 1 ether, "balance not dealt");
+    }
+
+    function testStoreLoad() external {
+        vm.store(address(target), bytes32(0), bytes32(uint256(7)));
+        require(target.value() == 7, "storage not set");
+        require(vm.load(address(target), bytes32(0)) == bytes32(uint256(7)), "storage not loaded");
+    }
+
+    function testPrank() external {
+        vm.prank(alice);
+        require(target.caller() == alice, "caller not pranked");
+        require(target.caller() == address(this), "prank not cleared");
+        vm.startPrank(alice);
+        require(target.caller() == alice, "caller not pranked");
+        require(target.caller() == alice, "prank not persisted");
+        vm.stopPrank();
+        require(target.caller() == address(this), "prank not stopped");
+    }
+
+    function testExpectRevert() external {
+        vm.expectRevert(bytes("failed"));
+        target.fail();
+        vm.expectRevert();
+        target.fail();
+    }
+
+    function testFailExpectRevert() external {
+        vm.expectRevert();
+        target.call(1);
+    }
+
+    function testExpectEmit() external {
+        vm.expectEmit();
+        emit Called(address(this), 1);
+        target.call(1);
+    }
+
+    function testFailExpectEmit() external {
+        vm.expectEmit();
+        emit Called(address(this), 2);
+        target.call(1);
+    }
+}
@@ -18,10 +18,14 @@ package testtool
 import (
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"math/big"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -30,8 +34,11 @@ import (
 	"github.com/ethereum/go-ethereum/concrete"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"golang.org/x/exp/slog"
@@ -41,84 +48,251 @@ var (
 	PrintLogs = true
 )
 
-func runTestMethod(concreteRegistry concrete.PrecompileRegistry, bytecode []byte, method abi.Method, shouldFail bool) (uint64, error) {
+var (
+	testKey, _          = crypto.HexToECDSA("d17bd946feb884d463d58fb702b94dd0457ca349338da1d732a57856cf777ccd") // 0xCcca11AbAC28D9b6FceD3a9CA73C434f6b33B215
+	testSenderAddress   = crypto.PubkeyToAddress(testKey.PublicKey)
+	testContractAddress = common.HexToAddress("cc73570000000000000000000000000000000000")
+	testGasLimit        = uint64(1e7)
+	setUpId             = crypto.Keccak256([]byte("setUp()"))[:4]
+)
+
+type testCall struct {
+	to   common.Address
+	data []byte
+}
+
+type testRun struct {
+	receipts []types.Receipts // Receipts of the calls of each block, excluding setUp
+	cheats   *cheatcodes
+	db       ethdb.Database
+	blocks   []*types.Block
+}
+
+// state returns the state at the end of the run.
+func (r *testRun) state() (*state.StateDB, error) {
+	return state.New(r.blocks[len(r.blocks)-1].Root(), state.NewDatabase(r.db), nil)
+}
+
+// runTestChain deploys the test contract in the genesis of a new chain and
+// calls setUp followed by the calls of the first batch in its first block, and
+// the calls of every other batch in a block of their own.
+func runTestChain(concreteRegistry concrete.PrecompileRegistry, bytecode []byte, batches [][]testCall) (*testRun, error) {
 	var (
-		key, _          = crypto.HexToECDSA("d17bd946feb884d463d58fb702b94dd0457ca349338da1d732a57856cf777ccd") // 0xCcca11AbAC28D9b6FceD3a9CA73C434f6b33B215
-		senderAddress   = crypto.PubkeyToAddress(key.PublicKey)
-		contractAddress = common.HexToAddress("cc73570000000000000000000000000000000000")
-		gspec           = &core.Genesis{
+		gspec = &core.Genesis{
 			GasLimit: 2e7,
 			Config:   params.TestChainConfig,
 			Alloc: core.GenesisAlloc{
-				senderAddress:   {Balance: big.NewInt(1e18)},
-				contractAddress: {Balance: common.Big0, Code: bytecode},
+				testSenderAddress:   {Balance: big.NewInt(1e18)},
+				testContractAddress: {Balance: common.Big0, Code: bytecode},
 			},
 		}
 		signer   = types.LatestSigner(gspec.Config)
-		gasLimit = uint64(1e7)
-		setupId  = crypto.Keccak256([]byte("setUp()"))[:4]
+		cheats   = newCheatcodes()
+		registry = &cheatcodeRegistry{concreteRegistry, cheats}
 	)
+	if len(batches) == 0 {
+		batches = [][]testCall{nil}
+	}
+	batches[0] = append([]testCall{{testContractAddress, setUpId}}, batches[0]...)
 
-	_, _, receipts := core.GenerateChainWithGenesisWithConcrete(gspec, ethash.NewFaker(), 1, concreteRegistry, func(ii int, block *core.BlockGen) {
-		for _, id := range [][]byte{setupId, method.ID} {
-			tx := types.NewTransaction(block.TxNonce(senderAddress), contractAddress, common.Big0, gasLimit, block.BaseFee(), id)
-			signed, err := types.SignTx(tx, signer, key)
+	db, blocks, receipts := core.GenerateChainWithGenesisWithConcrete(gspec, ethash.NewFaker(), len(batches), registry, func(ii int, block *core.BlockGen) {
+		for _, call := range batches[ii] {
+			tx := types.NewTransaction(block.TxNonce(testSenderAddress), call.to, common.Big0, testGasLimit, block.BaseFee(), call.data)
+			signed, err := types.SignTx(tx, signer, testKey)
 			if err != nil {
 				panic(err)
 			}
-			block.AddTx(signed)
+			block.AddTxWithVMConfig(signed, vm.Config{CallHook: cheats})
 		}
 	})
 
-	if len(receipts[0]) != 2 {
-		return 0, fmt.Errorf("expected 2 receipts, got %d", len(receipts))
+	for ii, batch := range batches {
+		if len(receipts[ii]) != len(batch) {
+			return nil, fmt.Errorf("expected %d receipts, got %d", len(batch), len(receipts[ii]))
+		}
 	}
-	setupReceipt := receipts[0][0]
-	testReceipt := receipts[0][1]
-	if setupReceipt.Status != types.ReceiptStatusSuccessful {
-		return 0, fmt.Errorf("setup failed")
+	if receipts[0][0].Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("setup failed")
 	}
-	if (testReceipt.Status == types.ReceiptStatusSuccessful) == shouldFail {
-		return 0, fmt.Errorf("test failed")
+	receipts[0] = receipts[0][1:]
+	return &testRun{
+		receipts: receipts,
+		cheats:   cheats,
+		db:       db,
+		blocks:   blocks,
+	}, nil
+}
+
+// runTestInput calls the test contract with the given input, failing if the
+// call reverts and shouldFail is false or the other way around.
+func runTestInput(runner testRunner, input []byte, shouldFail bool) (*testOutcome, error) {
+	outcome, err := runner.run(input)
+	if err != nil {
+		return nil, err
+	}
+	reverted := outcome.receipt.Status != types.ReceiptStatusSuccessful
+	if reverted && !shouldFail {
+		return outcome, errors.New("reverted")
 	}
+	if !reverted && shouldFail {
+		return outcome, errors.New("expected revert")
+	}
+	return outcome, nil
+}
 
-	if PrintLogs && len(testReceipt.Logs) > 0 {
-		fmt.Println("")
-		for ii, log := range testReceipt.Logs {
-			fmt.Printf("Logs[%d]\n", ii)
-			fmt.Println("Address :", log.Address)
-			if len(log.Topics) > 0 {
-				fmt.Println("Topics  :", log.Topics[0])
-				for _, topic := range log.Topics[1:] {
-					fmt.Println("         ", topic)
-				}
+func printLogs(receipt *types.Receipt, cheats *cheatcodes) {
+	if !PrintLogs || receipt == nil || len(receipt.Logs) == 0 {
+		return
+	}
+	fmt.Println("")
+	for ii, log := range receipt.Logs {
+		fmt.Printf("Logs[%d]\n", ii)
+		fmt.Println("Address :", cheats.label(log.Address))
+		if len(log.Topics) > 0 {
+			fmt.Println("Topics  :", log.Topics[0])
+			for _, topic := range log.Topics[1:] {
+				fmt.Println("         ", topic)
 			}
-			fmt.Println("Data    : 0x" + hex.EncodeToString(log.Data))
 		}
-		fmt.Println("")
+		fmt.Println("Data    : 0x" + hex.EncodeToString(log.Data))
 	}
+	fmt.Println("")
+}
 
-	return testReceipt.GasUsed, nil
+// testOptions are the options of a test run shared by all test contracts.
+type testOptions struct {
+	fuzz      FuzzConfig
+	isolate   bool           // Run setUp once and revert the state after each test
+	artifacts []testArtifact // Contracts invariant tests can target
 }
 
-func runTestContract(concreteRegistry concrete.PrecompileRegistry, bytecode []byte, ABI abi.ABI) (int, int) {
-	passed := 0
-	failed := 0
-	for _, method := range ABI.Methods {
+// failureReason returns the error of a failed test, with the reason of the
+// revert if the test reverted.
+func failureReason(err error, outcome *testOutcome, ABIs []abi.ABI) string {
+	if outcome == nil || outcome.receipt.Status == types.ReceiptStatusSuccessful {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s: %s", err, decodeRevert(outcome.returnData, ABIs...))
+}
+
+func runTestContract(concreteRegistry concrete.PrecompileRegistry, bytecode []byte, ABI abi.ABI, opts testOptions) []TestResult {
+	var (
+		results    []TestResult
+		invariants []abi.Method
+		runner     testRunner = &chainRunner{concreteRegistry, bytecode}
+		runnerErr  error
+		ABIs       = []abi.ABI{ABI}
+	)
+	for _, artifact := range opts.artifacts {
+		ABIs = append(ABIs, artifact.ABI)
+	}
+	if opts.isolate {
+		runner, runnerErr = newIsolatedRunner(concreteRegistry, bytecode)
+	}
+	report := func(result TestResult, outcome *testOutcome, start time.Time) {
+		result.Duration = time.Since(start)
+		if outcome != nil && (!result.Passed || result.Kind == KindTest) {
+			printLogs(outcome.receipt, outcome.cheats)
+		}
+		if result.Kind == KindFuzz && result.Counterexample != nil {
+			fmt.Printf("Counterexample: %s\n", result.Counterexample[0])
+		} else if result.Kind == KindInvariant && result.Counterexample != nil {
+			fmt.Println("Sequence:")
+			for _, call := range result.Counterexample {
+				fmt.Println("        ", call)
+			}
+		}
+		fmt.Println(result.String())
+		results = append(results, result)
+	}
+
+	for _, method := range sortedMethods(ABI) {
+		if strings.HasPrefix(method.Name, "invariant") && len(method.Inputs) == 0 {
+			invariants = append(invariants, method)
+			continue
+		}
 		if !strings.HasPrefix(method.Name, "test") {
 			continue
 		}
-		shouldFail := strings.HasPrefix(method.Name, "testFail")
-		gas, err := runTestMethod(concreteRegistry, bytecode, method, shouldFail)
-		if err == nil {
-			passed++
-			fmt.Printf("[PASS] %s() (gas: %d)\n", method.Name, gas)
-		} else {
-			failed++
-			fmt.Printf("[FAIL] %s() (gas: %d): %s\n", method.Name, gas, err)
+		var (
+			start      = time.Now()
+			shouldFail = strings.HasPrefix(method.Name, "testFail")
+			result     = TestResult{Test: method.Sig, Kind: KindTest, Passed: true}
+		)
+		if len(method.Inputs) > 0 {
+			result.Kind = KindFuzz
 		}
+		if runnerErr != nil {
+			result.Passed, result.Reason = false, runnerErr.Error()
+			report(result, nil, start)
+			continue
+		}
+
+		if result.Kind == KindTest {
+			outcome, err := runTestInput(runner, method.ID, shouldFail)
+			if outcome != nil {
+				result.Gas = outcome.receipt.GasUsed
+			}
+			if err != nil {
+				result.Passed, result.Reason = false, failureReason(err, outcome, ABIs)
+			}
+			report(result, outcome, start)
+			continue
+		}
+
+		fuzz := runFuzzTest(runner, method, shouldFail, opts.fuzz)
+		result.Runs, result.MeanGas, result.MedianGas = fuzz.runs, fuzz.meanGas, fuzz.medianGas
+		if fuzz.counterexample != nil {
+			result.Seed = opts.fuzz.Seed
+			result.Counterexample = []string{fmt.Sprintf("%s(%s)", method.Name, formatArgs(fuzz.counterexample))}
+		}
+		if fuzz.err != nil {
+			result.Passed, result.Reason = false, failureReason(fuzz.err, fuzz.outcome, ABIs)
+		}
+		report(result, fuzz.outcome, start)
 	}
-	return passed, failed
+
+	var (
+		targets    []*invariantTarget
+		targetsErr error
+	)
+	if len(invariants) > 0 {
+		targets, targetsErr = invariantTargets(concreteRegistry, bytecode, opts.artifacts)
+	}
+	for _, invariant := range invariants {
+		var (
+			start  = time.Now()
+			result = TestResult{Test: invariant.Sig, Kind: KindInvariant, Passed: true}
+		)
+		if targetsErr != nil {
+			result.Passed, result.Reason = false, targetsErr.Error()
+			report(result, nil, start)
+			continue
+		}
+		inv := runInvariantTest(concreteRegistry, bytecode, invariant, targets, opts.fuzz)
+		result.Runs, result.Calls, result.Reverts = inv.runs, inv.calls, inv.reverts
+		if inv.outcome != nil {
+			result.Seed = opts.fuzz.Seed
+			result.Counterexample = make([]string, len(inv.sequence))
+			for ii, call := range inv.sequence {
+				result.Counterexample[ii] = call.String()
+			}
+		}
+		if inv.err != nil {
+			result.Passed, result.Reason = false, failureReason(inv.err, inv.outcome, ABIs)
+		}
+		report(result, inv.outcome, start)
+	}
+	return results
+}
+
+func sortedMethods(ABI abi.ABI) []abi.Method {
+	methods := make([]abi.Method, 0, len(ABI.Methods))
+	for _, method := range ABI.Methods {
+		methods = append(methods, method)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+	return methods
 }
 
 func extractTestData(contractJsonBytes []byte) ([]byte, abi.ABI, string, error) {
@@ -195,8 +369,27 @@ func getTestPaths(testDir, outDir string) ([]string, error) {
 	return paths, nil
 }
 
-func runTestPaths(concreteRegistry concrete.PrecompileRegistry, contractJsonPaths []string) (int, int) {
-	var totalPassed, totalFailed int
+// loadArtifacts returns the contracts compiled to the output dir, skipping the
+// files that are not contract artifacts.
+func loadArtifacts(outDir string) []testArtifact {
+	var artifacts []testArtifact
+	filepath.WalkDir(outDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		bytecode, ABI, _, err := extractTestDataFromPath(path)
+		if err != nil || len(bytecode) == 0 {
+			return nil
+		}
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		artifacts = append(artifacts, testArtifact{name, bytecode, ABI})
+		return nil
+	})
+	return artifacts
+}
+
+func runTestPaths(concreteRegistry concrete.PrecompileRegistry, contractJsonPaths []string, opts testOptions) []TestResult {
+	var results []TestResult
 	startTime := time.Now()
 
 	for _, path := range contractJsonPaths {
@@ -208,12 +401,14 @@ func runTestPaths(concreteRegistry concrete.PrecompileRegistry, contractJsonPath
 		contractName := filepath.Base(path)
 		contractName = strings.TrimSuffix(contractName, filepath.Ext(contractName))
 		fmt.Printf("\nRunning tests for %s:%s\n", testPath, contractName)
-		passed, failed := runTestContract(concreteRegistry, bytecode, ABI)
-		totalPassed += passed
-		totalFailed += failed
+		for _, result := range runTestContract(concreteRegistry, bytecode, ABI, opts) {
+			result.Path, result.Contract = testPath, contractName
+			results = append(results, result)
+		}
 	}
 
 	timeMs := float64(time.Since(startTime).Microseconds()) / 1000
+	totalPassed, totalFailed := countResults(results)
 
 	var result string
 	if totalFailed == 0 {
@@ -224,7 +419,7 @@ func runTestPaths(concreteRegistry concrete.PrecompileRegistry, contractJsonPath
 
 	fmt.Printf("\nTest result: %s. %d passed; %d failed; finished in %.2fms\n", result, totalPassed, totalFailed, timeMs)
 
-	return totalPassed, totalFailed
+	return results
 }
 
 func setGethVerbosity(_ slog.Level) func() {
@@ -242,12 +437,72 @@ type TestConfig struct {
 	Contract string
 	TestDir  string
 	OutDir   string
+	Fuzz     FuzzConfig
+	Isolate  bool // Run setUp once and revert the state after each test
+
+	JSONReport   string // Path of the JSON report, none if empty
+	JUnitReport  string // Path of the JUnit XML report, none if empty
+	GasSnapshot  string // Path of the gas snapshot file, none if empty
+	SnapshotMode SnapshotMode
 }
 
+// RunTestContract runs the tests of a contract with the default fuzz config.
+// Invariant tests fail as the ABIs of their target contracts are unknown, use
+// Test to run them.
 func RunTestContract(concreteRegistry concrete.PrecompileRegistry, bytecode []byte, ABI abi.ABI) (int, int) {
 	resetGethLogger := setGethVerbosity(log.LevelWarn)
 	defer resetGethLogger()
-	return runTestContract(concreteRegistry, bytecode, ABI)
+	results := runTestContract(concreteRegistry, bytecode, ABI, testOptions{fuzz: DefaultFuzzConfig.withDefaults()})
+	return countResults(results)
+}
+
+// writeFile creates a file and writes to it with write.
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// processGasSnapshot writes, checks or diffs the gas snapshot file, failing the
+// tests whose gas changed in check mode.
+func processGasSnapshot(config TestConfig, results []TestResult) error {
+	if config.SnapshotMode == SnapshotWrite {
+		return writeFile(config.GasSnapshot, func(w io.Writer) error {
+			return WriteGasSnapshot(w, results)
+		})
+	}
+	file, err := os.Open(config.GasSnapshot)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	snapshot, err := ReadGasSnapshot(file)
+	if err != nil {
+		return err
+	}
+	switch config.SnapshotMode {
+	case SnapshotCheck:
+		if failed := CheckGasSnapshot(snapshot, results); failed > 0 {
+			fmt.Printf("\nGas snapshot check failed for %d tests:\n", failed)
+			for _, result := range results {
+				if !result.Passed && strings.Contains(result.Reason, "snapshot") {
+					fmt.Printf("%s: %s\n", result.snapshotKey(), result.Reason)
+				}
+			}
+		}
+		return nil
+	case SnapshotDiff:
+		fmt.Println("")
+		return DiffGasSnapshot(os.Stdout, snapshot, results)
+	default:
+		return fmt.Errorf("unknown gas snapshot mode %d", config.SnapshotMode)
+	}
 }
 
 func Test(concreteRegistry concrete.PrecompileRegistry, config TestConfig) (int, int) {
@@ -277,5 +532,38 @@ func Test(concreteRegistry concrete.PrecompileRegistry, config TestConfig) (int,
 	}
 
 	// Run tests
-	return runTestPaths(concreteRegistry, testPaths)
+	opts := testOptions{
+		fuzz:      config.Fuzz.withDefaults(),
+		isolate:   config.Isolate,
+		artifacts: loadArtifacts(config.OutDir),
+	}
+	results := runTestPaths(concreteRegistry, testPaths, opts)
+
+	// Write reports
+	if config.GasSnapshot != "" {
+		if err := processGasSnapshot(config, results); err != nil {
+			fmt.Printf("Error processing gas snapshot: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	reports := []struct {
+		path  string
+		write func(io.Writer, []TestResult) error
+	}{
+		{config.JSONReport, WriteJSONReport},
+		{config.JUnitReport, WriteJUnitReport},
+	}
+	for _, report := range reports {
+		if report.path == "" {
+			continue
+		}
+		err := writeFile(report.path, func(w io.Writer) error {
+			return report.write(w, results)
+		})
+		if err != nil {
+			fmt.Printf("Error writing report %s: %s\n", report.path, err)
+			os.Exit(1)
+		}
+	}
+	return countResults(results)
 }
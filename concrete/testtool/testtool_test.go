@@ -16,17 +16,64 @@
 package testtool
 
 import (
-	_ "embed"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/concrete"
 )
 
-//go:embed testdata/out/Test.sol/Test.json
-var testContractJsonBytes []byte
+var (
+	forgeBuildOnce sync.Once
+	forgeBuildErr  error
+	forgeOutput    []byte
+)
+
+// testContractJSON returns the artifact of a test contract in testdata/src,
+// building the contracts with forge first if it is installed. Tests using it
+// are skipped if the contracts are not built.
+func testContractJSON(t *testing.T, name string) []byte {
+	forgeBuildOnce.Do(func() {
+		forge, err := exec.LookPath("forge")
+		if err != nil {
+			return
+		}
+		cmd := exec.Command(forge, "build")
+		cmd.Dir = "testdata"
+		forgeOutput, forgeBuildErr = cmd.CombinedOutput()
+	})
+	if forgeBuildErr != nil {
+		t.Fatalf("forge build failed: %v\n%s", forgeBuildErr, forgeOutput)
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", "out", name+".sol", name+".json"))
+	if os.IsNotExist(err) {
+		t.Skipf("test contract %s not built, forge is required", name)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
 
 func TestRunTestContract(t *testing.T) {
-	bytecode, ABI, _, err := extractTestData(testContractJsonBytes)
+	bytecode, ABI, _, err := extractTestData(testContractJSON(t, "Test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	precompileRegistry := concrete.NewRegistry()
+	passed, failed := RunTestContract(precompileRegistry, bytecode, ABI)
+	if failed > 0 {
+		t.Errorf("failed tests: %v", failed)
+	}
+	if passed == 0 {
+		t.Error("no tests passed")
+	}
+}
+
+func TestRunTestContractWithCheatcodes(t *testing.T) {
+	bytecode, ABI, _, err := extractTestData(testContractJSON(t, "TestCheatcodes"))
 	if err != nil {
 		t.Fatal(err)
 	}
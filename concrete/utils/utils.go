@@ -32,6 +32,8 @@ func BytesToUint64(data []byte) uint64 {
 	return binary.BigEndian.Uint64(data)
 }
 
+var ErrInvalidEncoding = errors.New("invalid encoding")
+
 const (
 	nil_error    = byte(0x00)
 	notNil_error = byte(0x01)
@@ -54,6 +56,39 @@ func DecodeError(data []byte) error {
 	return errors.New(string(data[1:]))
 }
 
+// EncodeValues packs a list of byte slices into a single byte slice by
+// prefixing each value with its length as a big-endian uint32.
+func EncodeValues(values [][]byte) []byte {
+	size := 0
+	for _, value := range values {
+		size += 4 + len(value)
+	}
+	data := make([]byte, 0, size)
+	for _, value := range values {
+		data = binary.BigEndian.AppendUint32(data, uint32(len(value)))
+		data = append(data, value...)
+	}
+	return data
+}
+
+// DecodeValues unpacks a byte slice produced by EncodeValues.
+func DecodeValues(data []byte) ([][]byte, error) {
+	values := make([][]byte, 0)
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, ErrInvalidEncoding
+		}
+		size := binary.BigEndian.Uint32(data)
+		data = data[4:]
+		if uint64(size) > uint64(len(data)) {
+			return nil, ErrInvalidEncoding
+		}
+		values = append(values, data[:size])
+		data = data[size:]
+	}
+	return values, nil
+}
+
 func GetData(data []byte, start uint64, size uint64) []byte {
 	length := uint64(len(data))
 	if start > length {
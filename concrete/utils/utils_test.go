@@ -108,3 +108,20 @@ func TestDataUtils(t *testing.T) {
 		require.Equal(t, part2, input[size:])
 	})
 }
+
+func TestValuesCodec(t *testing.T) {
+	values := [][]byte{{}, {0x01}, []byte("concrete"), make([]byte, 300)}
+	enc := EncodeValues(values)
+	dec, err := DecodeValues(enc)
+	require.NoError(t, err)
+	require.Equal(t, values, dec)
+
+	dec, err = DecodeValues(nil)
+	require.NoError(t, err)
+	require.Len(t, dec, 0)
+
+	_, err = DecodeValues(enc[:len(enc)-1])
+	require.Equal(t, ErrInvalidEncoding, err)
+	_, err = DecodeValues([]byte{0x00, 0x00})
+	require.Equal(t, ErrInvalidEncoding, err)
+}
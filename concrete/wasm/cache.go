@@ -0,0 +1,50 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasm
+
+import (
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+)
+
+var (
+	compilationCacheLock sync.Mutex
+	compilationCaches    = make(map[string]wazero.CompilationCache)
+)
+
+// compilationCache returns the wazero compilation cache shared by all the
+// precompiles compiled with the same cache directory. Compiled modules are
+// stored in a subdirectory for the wazero version, keyed by module hash. An
+// empty directory returns a cache kept in memory only.
+func compilationCache(dir string) (wazero.CompilationCache, error) {
+	compilationCacheLock.Lock()
+	defer compilationCacheLock.Unlock()
+	if cache, ok := compilationCaches[dir]; ok {
+		return cache, nil
+	}
+	var (
+		cache wazero.CompilationCache
+		err   error
+	)
+	if dir == "" {
+		cache = wazero.NewCompilationCache()
+	} else if cache, err = wazero.NewCompilationCacheWithDir(dir); err != nil {
+		return nil, err
+	}
+	compilationCaches[dir] = cache
+	return cache, nil
+}
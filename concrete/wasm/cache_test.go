@@ -0,0 +1,60 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasm
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompilationCache(t *testing.T) {
+	var (
+		r   = require.New(t)
+		dir = t.TempDir()
+	)
+	cache, err := compilationCache(dir)
+	r.NoError(err)
+	same, err := compilationCache(dir)
+	r.NoError(err)
+	r.Same(cache, same)
+	memory, err := compilationCache("")
+	r.NoError(err)
+	r.NotSame(cache, memory)
+	entries, err := os.ReadDir(dir)
+	r.NoError(err)
+	r.Len(entries, 1, "cache is kept in a subdirectory for the wazero version")
+}
+
+func TestCompileErrors(t *testing.T) {
+	r := require.New(t)
+
+	pc := NewWazeroPrecompile([]byte{0x00, 0x61, 0x73, 0x6d}).(concrete.CompilingPrecompile)
+	err := pc.Compile(t.TempDir())
+	r.Error(err)
+	// Errors are kept for later calls
+	r.Equal(err, pc.Compile(""))
+	r.False(pc.IsStatic(nil))
+	_, runErr := pc.Run(nil, nil)
+	r.Equal(err, runErr)
+
+	// A valid module without the precompile exports
+	blank := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	pc = NewWazeroPrecompile(blank).(concrete.CompilingPrecompile)
+	r.EqualError(pc.Compile(""), "isStatic not exported")
+}
@@ -17,20 +17,17 @@ package wasm
 
 import (
 	"context"
-	_ "embed"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/concrete/api"
 	"github.com/ethereum/go-ethereum/concrete/wasm/host"
 	"github.com/ethereum/go-ethereum/concrete/wasm/memory"
+	"github.com/ethereum/go-ethereum/concrete/wasm/wasi"
 	"github.com/stretchr/testify/require"
 	"github.com/tetratelabs/wazero"
 	"github.com/wasmerio/wasmer-go/wasmer"
 )
 
-//go:embed testdata/blank.wasm
-var blankCode []byte
-
 type mockMemory []byte
 
 func newMockMemory() memory.Memory {
@@ -110,18 +107,22 @@ func TestMockMemoryPutGetValues(t *testing.T) {
 	testMemoryPutGetValues(t, mem)
 }
 
-func newWazeroMemory() (memory.Memory, memory.Allocator) {
+func newWazeroMemory(blankCode []byte) (memory.Memory, memory.Allocator) {
 	envCall := host.NewWazeroEnvironmentCaller(func() api.Environment { return nil })
 	config := wazero.NewRuntimeConfigInterpreter()
-	mod, _, err := newWazeroModule(envCall, blankCode, config)
+	ctx := context.Background()
+	compiled, r, err := newWazeroModule(ctx, envCall, wasi.NewShim(nil), blankCode, config)
+	if err != nil {
+		panic(err)
+	}
+	mod, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
 	if err != nil {
 		panic(err)
 	}
-	ctx := context.Background()
 	return host.NewWazeroMemory(ctx, mod)
 }
 
-func newWasmerMemory() (memory.Memory, memory.Allocator) {
+func newWasmerMemory(blankCode []byte) (memory.Memory, memory.Allocator) {
 	envCall := host.NewWasmerEnvironmentCaller(func() api.Environment { return nil })
 	var config *wasmer.Config
 	if wasmer.IsCompilerAvailable(wasmer.SINGLEPASS) {
@@ -130,7 +131,7 @@ func newWasmerMemory() (memory.Memory, memory.Allocator) {
 	} else {
 		config = wasmer.NewConfig().UseCraneliftCompiler()
 	}
-	_, instance, err := newWasmerModule(envCall, blankCode, config)
+	_, instance, err := newWasmerModule(envCall, wasi.NewShim(nil), blankCode, config)
 	if err != nil {
 		panic(err)
 	}
@@ -138,9 +139,10 @@ func newWasmerMemory() (memory.Memory, memory.Allocator) {
 }
 
 func TestWasmMemory(t *testing.T) {
+	blankCode := loadTestdata(t, "blank.wasm")
 	rts := []struct {
 		name string
-		new  func() (memory.Memory, memory.Allocator)
+		new  func([]byte) (memory.Memory, memory.Allocator)
 	}{
 		{
 			"wazero",
@@ -152,7 +154,7 @@ func TestWasmMemory(t *testing.T) {
 	}
 	for _, rt := range rts {
 		t.Run(rt.name, func(t *testing.T) {
-			mem, alloc := rt.new()
+			mem, alloc := rt.new(blankCode)
 			t.Run("readwrite", func(t *testing.T) {
 				testMemoryReadWrite(t, mem)
 				testMemoryPutGetValues(t, mem)
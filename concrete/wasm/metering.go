@@ -0,0 +1,643 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Fuel_WasmGlobalName is the global holding the fuel left to a metered module.
+const Fuel_WasmGlobalName = "concrete_Fuel"
+
+var errMalformedModule = errors.New("malformed wasm module")
+
+// Metering works by instrumenting the module before compiling it. A mutable
+// i64 global holding the fuel left is appended to the module and exported, and
+// a metering block is inserted at the start of every function and at the top
+// of every loop body. Every metering block consumes as much fuel as there are
+// instructions from it to the next metering block in the function body, and
+// traps with the fuel set to zero if there is not enough fuel left.
+//
+// Code between two metering blocks can only run again by looping or by being
+// called, both of which go through a metering block, so the instructions
+// executed are bounded by the fuel consumed regardless of branches.
+//
+// Bulk memory and table instructions, and growing memory or tables, do work
+// proportional to an operand. Two charging functions are appended to the module
+// and called right before each of them, consuming fuel for every byte or table
+// element from the length operand, or for every byte of the pages grown.
+
+const (
+	wasmSectionCustom   = 0
+	wasmSectionType     = 1
+	wasmSectionImport   = 2
+	wasmSectionFunction = 3
+	wasmSectionGlobal   = 6
+	wasmSectionExport   = 7
+	wasmSectionCode     = 10
+
+	wasmExternFunc   = 0x00
+	wasmExternGlobal = 0x03
+
+	wasmOpLoop       = 0x03
+	wasmOpCall       = 0x10
+	wasmOpGlobalGet  = 0x23
+	wasmOpGlobalSet  = 0x24
+	wasmOpMemoryGrow = 0x40
+	wasmOpMisc       = 0xfc
+
+	// Fuel charged for every byte of a 64KiB page grown
+	wasmPageFuelShift = 16
+)
+
+// wasmSectionOrder is the position of every known non-custom section in a
+// module, used to insert new sections in order.
+var wasmSectionOrder = map[byte]int{
+	1: 1, 2: 2, 3: 3, 4: 4, 5: 5, 13: 6, 6: 7, 7: 8, 8: 9, 9: 10, 12: 11, 10: 12, 11: 13,
+}
+
+type wasmSection struct {
+	id      byte
+	content []byte
+}
+
+// instrumentFuel returns the module with fuel metering added. The fuel global
+// starts with initialFuel, which bounds the start function of the module.
+func instrumentFuel(code []byte, initialFuel uint64) ([]byte, error) {
+	if len(code) < 8 || !bytes.Equal(code[:4], []byte{0x00, 0x61, 0x73, 0x6d}) {
+		return nil, errMalformedModule
+	}
+	var sections []wasmSection
+	r := &wasmReader{data: code, pos: 8}
+	for !r.done() {
+		id, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		content, err := r.vecBytes()
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, wasmSection{id, content})
+	}
+
+	// The fuel global is appended after the imported and defined globals, and
+	// the charging functions and their type after the existing ones
+	var globalIdx, funcIdx, typeIdx uint32
+	for _, section := range sections {
+		var (
+			count uint32
+			err   error
+		)
+		switch section.id {
+		case wasmSectionImport:
+			var funcs uint32
+			funcs, count, err = countImports(section.content)
+			funcIdx += funcs
+		case wasmSectionGlobal:
+			count, err = (&wasmReader{data: section.content}).u32()
+		case wasmSectionFunction:
+			var funcs uint32
+			funcs, err = (&wasmReader{data: section.content}).u32()
+			funcIdx += funcs
+		case wasmSectionType:
+			typeIdx, err = (&wasmReader{data: section.content}).u32()
+		}
+		if err != nil {
+			return nil, err
+		}
+		globalIdx += count
+	}
+	meter := fuelMeter{fuelIdx: globalIdx, chargeIdx: funcIdx, chargePagesIdx: funcIdx + 1}
+
+	if initialFuel > math.MaxInt64 {
+		initialFuel = math.MaxInt64
+	}
+	fuelGlobal := []byte{0x7e, 0x01, 0x42} // mut i64 = initialFuel
+	fuelGlobal = append(appendS64(fuelGlobal, int64(initialFuel)), 0x0b)
+	fuelExport := append(appendName(nil, Fuel_WasmGlobalName), wasmExternGlobal)
+	fuelExport = appendU32(fuelExport, globalIdx)
+
+	sections, err := appendToSection(sections, wasmSectionGlobal, fuelGlobal)
+	if err != nil {
+		return nil, err
+	}
+	for _, section := range sections {
+		if section.id == wasmSectionExport {
+			if err := checkExports(section.content, globalIdx); err != nil {
+				return nil, err
+			}
+		}
+	}
+	sections, err = appendToSection(sections, wasmSectionExport, fuelExport)
+	if err != nil {
+		return nil, err
+	}
+	for ii, section := range sections {
+		if section.id != wasmSectionCode {
+			continue
+		}
+		content, err := meter.instrumentCode(section.content)
+		if err != nil {
+			return nil, err
+		}
+		sections[ii].content = content
+	}
+	// (i32) -> i32
+	if sections, err = appendToSection(sections, wasmSectionType, []byte{0x60, 0x01, 0x7f, 0x01, 0x7f}); err != nil {
+		return nil, err
+	}
+	for _, shift := range []byte{0, wasmPageFuelShift} {
+		if sections, err = appendToSection(sections, wasmSectionFunction, appendU32(nil, typeIdx)); err != nil {
+			return nil, err
+		}
+		body := meter.chargeBody(shift)
+		if sections, err = appendToSection(sections, wasmSectionCode, append(appendU32(nil, uint32(len(body))), body...)); err != nil {
+			return nil, err
+		}
+	}
+
+	out := append([]byte{}, code[:8]...)
+	for _, section := range sections {
+		out = append(out, section.id)
+		out = appendU32(out, uint32(len(section.content)))
+		out = append(out, section.content...)
+	}
+	return out, nil
+}
+
+// countImports returns the number of imported functions and globals.
+func countImports(content []byte) (uint32, uint32, error) {
+	r := &wasmReader{data: content}
+	count, err := r.u32()
+	if err != nil {
+		return 0, 0, err
+	}
+	var funcs, globals uint32
+	for ii := uint32(0); ii < count; ii++ {
+		if _, err := r.vecBytes(); err != nil {
+			return 0, 0, err
+		}
+		if _, err := r.vecBytes(); err != nil {
+			return 0, 0, err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return 0, 0, err
+		}
+		switch kind {
+		case wasmExternFunc:
+			funcs++
+			_, err = r.u32()
+		case 0x01: // table
+			if _, err = r.byte(); err == nil {
+				err = r.skipLimits()
+			}
+		case 0x02: // memory
+			err = r.skipLimits()
+		case wasmExternGlobal:
+			globals++
+			err = r.skip(2)
+		case 0x04: // tag
+			if _, err = r.byte(); err == nil {
+				_, err = r.u32()
+			}
+		default:
+			return 0, 0, fmt.Errorf("unknown import kind 0x%x", kind)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return funcs, globals, nil
+}
+
+// checkExports rejects modules exporting the fuel global themselves, which
+// would only be valid after instrumenting them.
+func checkExports(content []byte, fuelIdx uint32) error {
+	r := &wasmReader{data: content}
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for ii := uint32(0); ii < count; ii++ {
+		name, err := r.vecBytes()
+		if err != nil {
+			return err
+		}
+		if string(name) == Fuel_WasmGlobalName {
+			return fmt.Errorf("export %s is reserved", Fuel_WasmGlobalName)
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return err
+		}
+		idx, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if kind == wasmExternGlobal && idx >= fuelIdx {
+			return fmt.Errorf("export of unknown global %d", idx)
+		}
+	}
+	return nil
+}
+
+// appendToSection appends an item to a vector section, creating the section if
+// the module does not have it.
+func appendToSection(sections []wasmSection, id byte, item []byte) ([]wasmSection, error) {
+	for ii, section := range sections {
+		if section.id != id {
+			continue
+		}
+		r := &wasmReader{data: section.content}
+		count, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		content := appendU32(nil, count+1)
+		content = append(content, section.content[r.pos:]...)
+		sections[ii].content = append(content, item...)
+		return sections, nil
+	}
+	section := wasmSection{id, append(appendU32(nil, 1), item...)}
+	for ii, other := range sections {
+		if other.id != wasmSectionCustom && wasmSectionOrder[other.id] > wasmSectionOrder[id] {
+			return append(sections[:ii], append([]wasmSection{section}, sections[ii:]...)...), nil
+		}
+	}
+	return append(sections, section), nil
+}
+
+// fuelMeter holds the indices of the fuel global and the charging functions in
+// the instrumented module.
+type fuelMeter struct {
+	fuelIdx        uint32
+	chargeIdx      uint32
+	chargePagesIdx uint32
+}
+
+func (m fuelMeter) instrumentCode(content []byte) ([]byte, error) {
+	r := &wasmReader{data: content}
+	count, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	out := appendU32(nil, count)
+	for ii := uint32(0); ii < count; ii++ {
+		body, err := r.vecBytes()
+		if err != nil {
+			return nil, err
+		}
+		body, err = m.instrumentBody(body)
+		if err != nil {
+			return nil, fmt.Errorf("function %d: %w", ii, err)
+		}
+		out = appendU32(out, uint32(len(body)))
+		out = append(out, body...)
+	}
+	return out, nil
+}
+
+func (m fuelMeter) instrumentBody(body []byte) ([]byte, error) {
+	r := &wasmReader{data: body}
+	locals, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	for ii := uint32(0); ii < locals; ii++ {
+		if _, err := r.u32(); err != nil {
+			return nil, err
+		}
+		if _, err := r.byte(); err != nil {
+			return nil, err
+		}
+	}
+	out := append([]byte{}, body[:r.pos]...)
+
+	// Split the body at metering points, counting the instructions in every
+	// segment
+	var (
+		segment []byte
+		cost    uint64
+	)
+	for !r.done() {
+		start := r.pos
+		op, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		cost++
+		if op == wasmOpGlobalGet || op == wasmOpGlobalSet {
+			idx, err := r.u32()
+			if err != nil {
+				return nil, err
+			}
+			if idx >= m.fuelIdx {
+				return nil, fmt.Errorf("unknown global %d", idx)
+			}
+			segment = append(segment, body[start:r.pos]...)
+			continue
+		}
+		if err := r.skipImmediates(op); err != nil {
+			return nil, err
+		}
+		if chargeIdx, ok := m.dynamicCharge(body[start:r.pos]); ok {
+			segment = appendU32(append(segment, wasmOpCall), chargeIdx)
+			cost++
+		}
+		segment = append(segment, body[start:r.pos]...)
+		if op == wasmOpLoop {
+			out = append(appendFuelMeter(out, m.fuelIdx, cost), segment...)
+			segment, cost = segment[:0], 0
+		}
+	}
+	out = appendFuelMeter(out, m.fuelIdx, cost)
+	return append(out, segment...), nil
+}
+
+// dynamicCharge returns the charging function to call before an instruction
+// doing work proportional to its topmost i32 operand, if any.
+func (m fuelMeter) dynamicCharge(instr []byte) (uint32, bool) {
+	switch instr[0] {
+	case wasmOpMemoryGrow:
+		return m.chargePagesIdx, true
+	case wasmOpMisc:
+		sub, err := (&wasmReader{data: instr, pos: 1}).u32()
+		if err != nil {
+			return 0, false
+		}
+		switch sub {
+		case 8, 10, 11, 12, 14, 15, 17: // memory.init, memory.copy, memory.fill, table.init, table.copy, table.grow, table.fill
+			return m.chargeIdx, true
+		}
+	}
+	return 0, false
+}
+
+// chargeBody returns the body of a function consuming its i32 argument shifted
+// left by shift as fuel, and returning the argument:
+//
+//	(local $cost i64)
+//	local.get 0
+//	i64.extend_i32_u
+//	i64.const shift
+//	i64.shl
+//	local.set $cost
+//	global.get $fuel
+//	local.get $cost
+//	i64.lt_u
+//	if
+//	  i64.const 0
+//	  global.set $fuel
+//	  unreachable
+//	end
+//	global.get $fuel
+//	local.get $cost
+//	i64.sub
+//	global.set $fuel
+//	local.get 0
+func (m fuelMeter) chargeBody(shift byte) []byte {
+	out := []byte{0x01, 0x01, 0x7e, 0x20, 0x00, 0xad, 0x42, shift, 0x86, 0x21, 0x01, wasmOpGlobalGet}
+	out = appendU32(out, m.fuelIdx)
+	out = append(out, 0x20, 0x01, 0x54, 0x04, 0x40, 0x42, 0x00, wasmOpGlobalSet)
+	out = appendU32(out, m.fuelIdx)
+	out = append(out, 0x00, 0x0b, wasmOpGlobalGet)
+	out = appendU32(out, m.fuelIdx)
+	out = append(out, 0x20, 0x01, 0x7d, wasmOpGlobalSet)
+	out = appendU32(out, m.fuelIdx)
+	return append(out, 0x20, 0x00, 0x0b)
+}
+
+// appendFuelMeter appends the code consuming cost fuel:
+//
+//	global.get $fuel
+//	i64.const cost
+//	i64.lt_u
+//	if
+//	  i64.const 0
+//	  global.set $fuel
+//	  unreachable
+//	end
+//	global.get $fuel
+//	i64.const cost
+//	i64.sub
+//	global.set $fuel
+func appendFuelMeter(out []byte, fuelIdx uint32, cost uint64) []byte {
+	out = appendU32(append(out, wasmOpGlobalGet), fuelIdx)
+	out = appendS64(append(out, 0x42), int64(cost))
+	out = append(out, 0x54, 0x04, 0x40, 0x42, 0x00, wasmOpGlobalSet)
+	out = appendU32(out, fuelIdx)
+	out = append(out, 0x00, 0x0b, wasmOpGlobalGet)
+	out = appendU32(out, fuelIdx)
+	out = appendS64(append(out, 0x42), int64(cost))
+	out = append(out, 0x7d, wasmOpGlobalSet)
+	return appendU32(out, fuelIdx)
+}
+
+type wasmReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *wasmReader) done() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *wasmReader) byte() (byte, error) {
+	if r.done() {
+		return 0, errMalformedModule
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *wasmReader) skip(n int) error {
+	if n > len(r.data)-r.pos {
+		return errMalformedModule
+	}
+	r.pos += n
+	return nil
+}
+
+func (r *wasmReader) leb(maxBytes int) (uint64, error) {
+	var value uint64
+	for ii := 0; ii < maxBytes; ii++ {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint64(b&0x7f) << (7 * ii)
+		if b < 0x80 {
+			return value, nil
+		}
+	}
+	return 0, errMalformedModule
+}
+
+func (r *wasmReader) u32() (uint32, error) {
+	value, err := r.leb(5)
+	return uint32(value), err
+}
+
+func (r *wasmReader) vecBytes() ([]byte, error) {
+	n, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	start := r.pos
+	if err := r.skip(int(n)); err != nil {
+		return nil, err
+	}
+	return r.data[start:r.pos], nil
+}
+
+func (r *wasmReader) skipLimits() error {
+	flags, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if _, err := r.leb(10); err != nil {
+		return err
+	}
+	if flags&0x01 != 0 {
+		_, err = r.leb(10)
+	}
+	return err
+}
+
+func (r *wasmReader) skipBlockType() error {
+	if r.done() {
+		return errMalformedModule
+	}
+	switch r.data[r.pos] {
+	case 0x40, 0x7f, 0x7e, 0x7d, 0x7c, 0x7b, 0x70, 0x6f:
+		r.pos++
+		return nil
+	}
+	_, err := r.leb(5)
+	return err
+}
+
+// skipImmediates skips the immediate arguments of an instruction. Only the
+// instructions of the features enabled in wazero by default are supported,
+// without SIMD.
+func (r *wasmReader) skipImmediates(op byte) error {
+	var err error
+	switch {
+	case op == 0x02 || op == 0x03 || op == 0x04: // block, loop, if
+		err = r.skipBlockType()
+	case op == 0x0c || op == 0x0d || op == 0x10 || op == 0xd2: // br, br_if, call, ref.func
+		_, err = r.u32()
+	case op == 0x0e: // br_table
+		var n uint32
+		if n, err = r.u32(); err == nil {
+			for ii := uint32(0); ii <= n && err == nil; ii++ {
+				_, err = r.u32()
+			}
+		}
+	case op == 0x11: // call_indirect
+		if _, err = r.u32(); err == nil {
+			_, err = r.u32()
+		}
+	case op == 0x1c: // select t*
+		var n uint32
+		if n, err = r.u32(); err == nil {
+			err = r.skip(int(n))
+		}
+	case op >= 0x20 && op <= 0x26: // locals, globals, table.get, table.set
+		_, err = r.u32()
+	case op >= 0x28 && op <= 0x3e: // memory loads and stores
+		if _, err = r.u32(); err == nil {
+			_, err = r.u32()
+		}
+	case op == 0x3f || op == 0x40: // memory.size, memory.grow
+		_, err = r.byte()
+	case op == 0x41: // i32.const
+		_, err = r.leb(5)
+	case op == 0x42: // i64.const
+		_, err = r.leb(10)
+	case op == 0x43: // f32.const
+		err = r.skip(4)
+	case op == 0x44: // f64.const
+		err = r.skip(8)
+	case op == 0xd0: // ref.null
+		_, err = r.byte()
+	case op <= 0x01 || op == 0x05 || op == 0x0b || op == 0x0f || op == 0x1a || op == 0x1b ||
+		(op >= 0x45 && op <= 0xc4) || op == 0xd1:
+		// No immediates
+	case op == wasmOpMisc:
+		err = r.skipMiscImmediates()
+	default:
+		err = fmt.Errorf("unsupported instruction 0x%x", op)
+	}
+	return err
+}
+
+func (r *wasmReader) skipMiscImmediates() error {
+	sub, err := r.u32()
+	if err != nil {
+		return err
+	}
+	switch {
+	case sub <= 7: // saturating truncations
+		return nil
+	case sub == 8: // memory.init
+		if _, err = r.u32(); err == nil {
+			_, err = r.byte()
+		}
+	case sub == 10: // memory.copy
+		err = r.skip(2)
+	case sub == 11: // memory.fill
+		_, err = r.byte()
+	case sub == 12 || sub == 14: // table.init, table.copy
+		if _, err = r.u32(); err == nil {
+			_, err = r.u32()
+		}
+	case sub == 9 || sub == 13 || (sub >= 15 && sub <= 17): // data.drop, elem.drop, table.grow, table.size, table.fill
+		_, err = r.u32()
+	default:
+		err = fmt.Errorf("unsupported instruction 0xfc 0x%x", sub)
+	}
+	return err
+}
+
+func appendU32(out []byte, value uint32) []byte {
+	for value >= 0x80 {
+		out = append(out, byte(value&0x7f|0x80))
+		value >>= 7
+	}
+	return append(out, byte(value))
+}
+
+func appendS64(out []byte, value int64) []byte {
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if (value == 0 && b&0x40 == 0) || (value == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+func appendName(out []byte, name string) []byte {
+	return append(appendU32(out, uint32(len(name))), name...)
+}
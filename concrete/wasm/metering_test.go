@@ -0,0 +1,228 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/concrete/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tetratelabs/wazero"
+	wz_api "github.com/tetratelabs/wazero/api"
+)
+
+// Minimal encoding of WASM modules with up to 127 of anything
+
+func testSection(id byte, items ...[]byte) []byte {
+	content := []byte{byte(len(items))}
+	for _, item := range items {
+		content = append(content, item...)
+	}
+	return append(appendU32([]byte{id}, uint32(len(content))), content...)
+}
+
+func testFuncType(params []byte, results []byte) []byte {
+	return append(append(append([]byte{0x60, byte(len(params))}, params...), byte(len(results))), results...)
+}
+
+func testExport(name string, kind byte, idx byte) []byte {
+	return append(appendName(nil, name), kind, idx)
+}
+
+func testBody(instrs ...byte) []byte {
+	return append([]byte{byte(len(instrs) + 2), 0x00}, append(instrs, 0x0b)...)
+}
+
+func testModule(sections ...[]byte) []byte {
+	code := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	for _, section := range sections {
+		code = append(code, section...)
+	}
+	return code
+}
+
+// loopModule exports:
+//   - count(n) looping n times, using 2 + 11 * (n + 1) fuel
+//   - spin() looping forever
+var loopModule = testModule(
+	testSection(1, testFuncType([]byte{0x7e}, nil), testFuncType(nil, nil)),
+	testSection(3, []byte{0}, []byte{1}),
+	testSection(6, []byte{0x7f, 0x00, 0x41, 0x00, 0x0b}),
+	testSection(7, testExport("count", 0x00, 0), testExport("spin", 0x00, 1)),
+	testSection(10,
+		[]byte{0x16, 0x00,
+			0x02, 0x40, // block
+			0x03, 0x40, // loop
+			0x20, 0x00, 0x50, 0x0d, 0x01, // br_if 1 (n == 0)
+			0x20, 0x00, 0x42, 0x01, 0x7d, 0x21, 0x00, // n = n - 1
+			0x0c, 0x00, // br 0
+			0x0b, 0x0b, 0x0b},
+		testBody(0x03, 0x40, 0x0c, 0x00, 0x0b),
+	),
+)
+
+// spinPrecompile is a precompile whose Run loops forever.
+var spinPrecompile = testModule(
+	testSection(1,
+		testFuncType([]byte{0x7e}, []byte{0x7e}),
+		testFuncType(nil, []byte{0x7e}),
+		testFuncType([]byte{0x7e}, nil),
+	),
+	testSection(3, []byte{0}, []byte{1}, []byte{1}, []byte{0}, []byte{0}, []byte{2}),
+	testSection(5, []byte{0x00, 0x01}),
+	testSection(7,
+		testExport(IsStatic_WasmFuncName, 0x00, 0),
+		testExport(Finalise_WasmFuncName, 0x00, 1),
+		testExport(Commit_WasmFuncName, 0x00, 2),
+		testExport(Run_WasmFuncName, 0x00, 3),
+		testExport("concrete_Malloc", 0x00, 4),
+		testExport("concrete_Free", 0x00, 5),
+	),
+	testSection(10,
+		testBody(0x42, 0x01),
+		testBody(0x42, 0x00),
+		testBody(0x42, 0x00),
+		testBody(0x03, 0x40, 0x0c, 0x00, 0x0b, 0x42, 0x00),
+		testBody(0x42, 0x00),
+		testBody(),
+	),
+)
+
+func TestInstrumentFuel(t *testing.T) {
+	var (
+		r   = require.New(t)
+		ctx = context.Background()
+	)
+	code, err := instrumentFuel(loopModule, 7)
+	r.NoError(err)
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter())
+	defer runtime.Close(ctx)
+	mod, err := runtime.Instantiate(ctx, code)
+	r.NoError(err)
+	fuel := mod.ExportedGlobal(Fuel_WasmGlobalName).(wz_api.MutableGlobal)
+	r.Equal(uint64(7), fuel.Get())
+
+	// Fuel is consumed per instruction, including in loops without calls
+	fuel.Set(1000)
+	_, err = mod.ExportedFunction("count").Call(ctx, 10)
+	r.NoError(err)
+	r.Equal(uint64(1000-2-11*11), fuel.Get())
+
+	fuel.Set(100)
+	_, err = mod.ExportedFunction("count").Call(ctx, 10)
+	r.Error(err)
+	r.Zero(fuel.Get())
+
+	fuel.Set(1000)
+	_, err = mod.ExportedFunction("spin").Call(ctx)
+	r.Error(err)
+	r.Zero(fuel.Get())
+
+	// Modules cannot access the fuel global themselves
+	setGlobal := testModule(
+		testSection(1, testFuncType(nil, nil)),
+		testSection(3, []byte{0}),
+		testSection(10, testBody(0x42, 0x00, 0x24, 0x00)),
+	)
+	_, err = instrumentFuel(setGlobal, 0)
+	r.EqualError(err, "function 0: unknown global 0")
+	exportFuel := testModule(testSection(7, testExport(Fuel_WasmGlobalName, 0x03, 0)))
+	_, err = instrumentFuel(exportFuel, 0)
+	r.EqualError(err, "export concrete_Fuel is reserved")
+}
+
+func TestUntrustedWasmInfiniteLoop(t *testing.T) {
+	config := api.EnvConfig{Trusted: false}
+
+	t.Run("OutOfGas", func(t *testing.T) {
+		r := require.New(t)
+		pc := NewUntrustedWazeroPrecompile(spinPrecompile, DefaultSandboxConfig)
+		env := mock.NewMockEnvironment(common.Address{}, config, true, 1e6)
+		_, _, err := concrete.RunPrecompile(pc, env, nil, false)
+		r.Equal(api.ErrOutOfGas, err)
+	})
+
+	t.Run("FuelExhausted", func(t *testing.T) {
+		r := require.New(t)
+		sandbox := DefaultSandboxConfig
+		sandbox.MaxFuel = 1000
+		pc := NewUntrustedWazeroPrecompile(spinPrecompile, sandbox)
+		env := mock.NewMockEnvironment(common.Address{}, config, true, 1e6)
+		_, remainingGas, err := concrete.RunPrecompile(pc, env, nil, false)
+		r.Equal(api.ErrExecutionReverted, err)
+		r.Zero(remainingGas)
+
+		// The instance can be used again
+		env = mock.NewMockEnvironment(common.Address{}, config, true, 1e6)
+		_, _, err = concrete.RunPrecompile(pc, env, nil, false)
+		r.Equal(api.ErrExecutionReverted, err)
+	})
+}
+
+// bulkModule has one page of memory, growable to two, and exports:
+//   - fill(n) filling n bytes of memory
+//   - grow(n) growing memory by n pages
+var bulkModule = testModule(
+	testSection(1, testFuncType([]byte{0x7f}, nil), testFuncType([]byte{0x7f}, []byte{0x7f})),
+	testSection(3, []byte{0}, []byte{1}),
+	testSection(5, []byte{0x01, 0x01, 0x02}),
+	testSection(7, testExport("fill", 0x00, 0), testExport("grow", 0x00, 1)),
+	testSection(10,
+		testBody(0x41, 0x00, 0x41, 0x00, 0x20, 0x00, 0xfc, 0x0b, 0x00),
+		testBody(0x20, 0x00, 0x40, 0x00),
+	),
+)
+
+func TestInstrumentFuelBulkMemory(t *testing.T) {
+	var (
+		r   = require.New(t)
+		ctx = context.Background()
+	)
+	code, err := instrumentFuel(bulkModule, 0)
+	r.NoError(err)
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter())
+	defer runtime.Close(ctx)
+	mod, err := runtime.Instantiate(ctx, code)
+	r.NoError(err)
+	fuel := mod.ExportedGlobal(Fuel_WasmGlobalName).(wz_api.MutableGlobal)
+
+	// Bulk operations are charged per byte on top of the instructions
+	fuel.Set(1000)
+	_, err = mod.ExportedFunction("fill").Call(ctx, 100)
+	r.NoError(err)
+	r.Equal(uint64(1000-6-100), fuel.Get())
+
+	fuel.Set(1000)
+	_, err = mod.ExportedFunction("fill").Call(ctx, 1<<16)
+	r.Error(err)
+	r.Zero(fuel.Get())
+
+	// Growing memory is charged per byte of the pages grown
+	fuel.Set(1000)
+	_, err = mod.ExportedFunction("grow").Call(ctx, 1)
+	r.Error(err)
+	r.Zero(fuel.Get())
+
+	fuel.Set(1 << 17)
+	ret, err := mod.ExportedFunction("grow").Call(ctx, 1)
+	r.NoError(err)
+	r.Equal(uint64(1), ret[0])
+	r.Equal(uint64(1<<17-4-1<<16), fuel.Get())
+}
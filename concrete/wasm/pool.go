@@ -0,0 +1,82 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasm
+
+import "sync"
+
+// PoolConfig sets how module instances are reused across precompile calls.
+// Every call runs in its own instance, so calls to the same precompile can run
+// concurrently.
+type PoolConfig struct {
+	// Maximum number of idle instances kept per precompile. Instances created
+	// over it are closed after their call.
+	MaxIdle int
+	// Instances whose memory grew over this size in bytes are closed after
+	// their call instead of being kept idle. Zero keeps them regardless.
+	MaxIdleMemory uint64
+}
+
+// DefaultPoolConfig is used by all precompiles created after it is set.
+var DefaultPoolConfig = PoolConfig{
+	MaxIdle:       8,
+	MaxIdleMemory: 64 * 1024 * 1024,
+}
+
+type poolInstance interface {
+	memorySize() uint64
+	close()
+}
+
+type instancePool[T poolInstance] struct {
+	config      PoolConfig
+	newInstance func() (T, error)
+	lock        sync.Mutex
+	idle        []T
+}
+
+func newInstancePool[T poolInstance](config PoolConfig, newInstance func() (T, error)) *instancePool[T] {
+	return &instancePool[T]{config: config, newInstance: newInstance}
+}
+
+// get returns an idle instance, or a new one if there are none.
+func (p *instancePool[T]) get() (T, error) {
+	p.lock.Lock()
+	if n := len(p.idle); n > 0 {
+		instance := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.lock.Unlock()
+		return instance, nil
+	}
+	p.lock.Unlock()
+	return p.newInstance()
+}
+
+// put returns an instance to the pool after a call.
+func (p *instancePool[T]) put(instance T) {
+	if p.config.MaxIdleMemory > 0 && instance.memorySize() > p.config.MaxIdleMemory {
+		instance.close()
+		return
+	}
+	p.lock.Lock()
+	kept := len(p.idle) < p.config.MaxIdle
+	if kept {
+		p.idle = append(p.idle, instance)
+	}
+	p.lock.Unlock()
+	if !kept {
+		instance.close()
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasm
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockInstance struct {
+	size   uint64
+	closed bool
+}
+
+func (i *mockInstance) memorySize() uint64 { return i.size }
+func (i *mockInstance) close()             { i.closed = true }
+
+func TestInstancePool(t *testing.T) {
+	var (
+		r       = require.New(t)
+		created int
+		fail    bool
+	)
+	pool := newInstancePool(PoolConfig{MaxIdle: 2, MaxIdleMemory: 100}, func() (*mockInstance, error) {
+		if fail {
+			return nil, errors.New("instantiate failed")
+		}
+		created++
+		return &mockInstance{}, nil
+	})
+
+	// Instances are reused once returned
+	a, err := pool.get()
+	r.NoError(err)
+	pool.put(a)
+	reused, err := pool.get()
+	r.NoError(err)
+	r.Same(a, reused)
+	r.Equal(1, created)
+
+	// Concurrent calls get distinct instances, and only MaxIdle are kept
+	b, err := pool.get()
+	r.NoError(err)
+	c, err := pool.get()
+	r.NoError(err)
+	r.NotSame(a, b)
+	r.NotSame(b, c)
+	r.Equal(3, created)
+	pool.put(a)
+	pool.put(b)
+	pool.put(c)
+	r.False(a.closed)
+	r.False(b.closed)
+	r.True(c.closed)
+
+	// Instances whose memory grew too much are not kept
+	d, err := pool.get()
+	r.NoError(err)
+	d.size = 101
+	pool.put(d)
+	r.True(d.closed)
+
+	// Instantiation errors are returned
+	fail = true
+	_, err = pool.get()
+	r.NoError(err)
+	_, err = pool.get()
+	r.EqualError(err, "instantiate failed")
+}
+
+func TestInstancePoolConcurrent(t *testing.T) {
+	var (
+		r    = require.New(t)
+		lock sync.Mutex
+		used = make(map[*mockInstance]bool)
+		wg   sync.WaitGroup
+	)
+	pool := newInstancePool(PoolConfig{MaxIdle: 4}, func() (*mockInstance, error) {
+		return &mockInstance{}, nil
+	})
+	for ii := 0; ii < 16; ii++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for jj := 0; jj < 100; jj++ {
+				instance, err := pool.get()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				lock.Lock()
+				inUse := used[instance]
+				used[instance] = true
+				lock.Unlock()
+				if inUse {
+					t.Error("instance used by two calls")
+				}
+				lock.Lock()
+				used[instance] = false
+				lock.Unlock()
+				pool.put(instance)
+			}
+		}()
+	}
+	wg.Wait()
+	r.LessOrEqual(len(pool.idle), 4)
+}
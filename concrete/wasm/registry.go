@@ -0,0 +1,274 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasm
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// The on-chain registry reads WASM precompiles from the storage of a system
+// contract with the following Solidity layout:
+//
+//	struct Deployment {
+//	    uint256 activationBlock;
+//	    uint256 recordedBlock;
+//	    bytes32 codeHash;
+//	}
+//
+//	address[] precompiles;                        // slot 0
+//	mapping(address => Deployment[]) deployments; // slot 1
+//	mapping(bytes32 => bytes) code;               // slot 2
+//
+// A deployment applies from activationBlock onwards, until superseded by a
+// later deployment for the same address. A zero code hash deactivates the
+// address. Deployments are only valid if they were recorded in a block before
+// the activation block, i.e. recordedBlock < activationBlock, and must be
+// appended in activation order. This makes the result for any given block the
+// same regardless of which later state it is read from.
+
+var (
+	precompilesSlot = common.BigToHash(big.NewInt(0))
+	deploymentsSlot = common.BigToHash(big.NewInt(1))
+	codeSlot        = common.BigToHash(big.NewInt(2))
+)
+
+// MaxOnChainCodeSize is the largest module the registry will read from state.
+const MaxOnChainCodeSize = 16 * 1024 * 1024
+
+const (
+	deploymentActivationOffset = 0
+	deploymentRecordedOffset   = 1
+	deploymentCodeHashOffset   = 2
+	deploymentSize             = 3
+)
+
+type OnChainPrecompileRegistry struct {
+	address       common.Address
+	base          concrete.PrecompileRegistry
+	newPrecompile func(code []byte) concrete.Precompile
+	lock          sync.Mutex
+	cache         map[common.Hash]concrete.Precompile
+	cacheDir      string
+}
+
+var (
+	_ concrete.StatefulPrecompileRegistry  = (*OnChainPrecompileRegistry)(nil)
+	_ concrete.CompilingPrecompileRegistry = (*OnChainPrecompileRegistry)(nil)
+)
+
+// NewOnChainRegistry returns a registry that serves the precompiles in base
+// plus the WASM precompiles deployed in the registry contract at address.
+func NewOnChainRegistry(address common.Address, base concrete.PrecompileRegistry) *OnChainPrecompileRegistry {
+	return NewOnChainRegistryWithConstructor(address, base, NewWazeroPrecompile)
+}
+
+func NewOnChainRegistryWithConstructor(address common.Address, base concrete.PrecompileRegistry, newPrecompile func(code []byte) concrete.Precompile) *OnChainPrecompileRegistry {
+	if base == nil {
+		base = concrete.NewRegistry()
+	}
+	return &OnChainPrecompileRegistry{
+		address:       address,
+		base:          base,
+		newPrecompile: newPrecompile,
+		cache:         make(map[common.Hash]concrete.Precompile),
+	}
+}
+
+func (r *OnChainPrecompileRegistry) Address() common.Address {
+	return r.address
+}
+
+// Compile compiles the precompiles in the base registry, and sets the cache
+// directory on-chain precompiles are compiled with when first read.
+func (r *OnChainPrecompileRegistry) Compile(cacheDir string) error {
+	r.lock.Lock()
+	r.cacheDir = cacheDir
+	r.lock.Unlock()
+	return concrete.CompilePrecompiles(r.base, cacheDir)
+}
+
+// Precompile returns the precompiles in the base registry, as on-chain
+// precompiles can only be resolved with access to the state.
+func (r *OnChainPrecompileRegistry) Precompile(address common.Address, blockNumber uint64, time uint64) (concrete.Precompile, bool) {
+	return r.base.Precompile(address, blockNumber, time)
+}
+
+func (r *OnChainPrecompileRegistry) Precompiles(blockNumber uint64, time uint64) concrete.PrecompileMap {
+	return r.base.Precompiles(blockNumber, time)
+}
+
+func (r *OnChainPrecompileRegistry) ActivePrecompiles(blockNumber uint64, time uint64) []common.Address {
+	return r.base.ActivePrecompiles(blockNumber, time)
+}
+
+// PrecompilesWithState returns the base precompiles merged with the on-chain
+// precompiles active at the given block. On-chain precompiles take precedence.
+func (r *OnChainPrecompileRegistry) PrecompilesWithState(blockNumber uint64, time uint64, state concrete.StateReader) concrete.PrecompileMap {
+	basePcs := r.base.Precompiles(blockNumber, time)
+	codeHashes := r.activeCodeHashes(blockNumber, state)
+	if len(codeHashes) == 0 {
+		return basePcs
+	}
+	pcs := make(concrete.PrecompileMap, len(basePcs)+len(codeHashes))
+	for address, pc := range basePcs {
+		pcs[address] = pc
+	}
+	for address, codeHash := range codeHashes {
+		if codeHash == (common.Hash{}) {
+			delete(pcs, address)
+			continue
+		}
+		pc := r.precompile(codeHash, state)
+		if pc == nil {
+			continue
+		}
+		pcs[address] = pc
+	}
+	return pcs
+}
+
+// activeCodeHashes returns the code hash of the deployment active at the given
+// block for every address in the registry contract with one.
+func (r *OnChainPrecompileRegistry) activeCodeHashes(blockNumber uint64, state concrete.StateReader) map[common.Address]common.Hash {
+	codeHashes := make(map[common.Address]common.Hash)
+	nPrecompiles := state.GetState(r.address, precompilesSlot).Big().Uint64()
+	precompilesStart := crypto.Keccak256Hash(precompilesSlot.Bytes()).Big()
+	for ii := uint64(0); ii < nPrecompiles; ii++ {
+		slot := common.BigToHash(new(big.Int).Add(precompilesStart, new(big.Int).SetUint64(ii)))
+		address := common.BytesToAddress(state.GetState(r.address, slot).Bytes())
+		if codeHash, ok := r.activeCodeHash(address, blockNumber, state); ok {
+			codeHashes[address] = codeHash
+		}
+	}
+	return codeHashes
+}
+
+func (r *OnChainPrecompileRegistry) activeCodeHash(address common.Address, blockNumber uint64, state concrete.StateReader) (common.Hash, bool) {
+	var (
+		arraySlot    = crypto.Keccak256Hash(common.LeftPadBytes(address.Bytes(), 32), deploymentsSlot.Bytes())
+		nDeployments = state.GetState(r.address, arraySlot).Big().Uint64()
+		arrayStart   = crypto.Keccak256Hash(arraySlot.Bytes()).Big()
+		codeHash     common.Hash
+		found        bool
+	)
+	for ii := uint64(0); ii < nDeployments; ii++ {
+		base := new(big.Int).Add(arrayStart, new(big.Int).SetUint64(ii*deploymentSize))
+		var (
+			activation = r.getUint64(state, base, deploymentActivationOffset)
+			recorded   = r.getUint64(state, base, deploymentRecordedOffset)
+		)
+		if activation > blockNumber {
+			// Deployments are sorted by activation block
+			break
+		}
+		if recorded >= activation {
+			continue
+		}
+		codeHash = r.getHash(state, base, deploymentCodeHashOffset)
+		found = true
+	}
+	return codeHash, found
+}
+
+func (r *OnChainPrecompileRegistry) getHash(state concrete.StateReader, base *big.Int, offset int64) common.Hash {
+	slot := common.BigToHash(new(big.Int).Add(base, big.NewInt(offset)))
+	return state.GetState(r.address, slot)
+}
+
+func (r *OnChainPrecompileRegistry) getUint64(state concrete.StateReader, base *big.Int, offset int64) uint64 {
+	value := r.getHash(state, base, offset).Big()
+	if !value.IsUint64() {
+		return ^uint64(0)
+	}
+	return value.Uint64()
+}
+
+// ValidatingPrecompile is implemented by precompiles that can check their code
+// is valid. The result must be the same on every node.
+type ValidatingPrecompile interface {
+	Validate() error
+}
+
+// precompile returns the precompile for the given code hash, reading and
+// instantiating the module on first use. It returns nil if the code is missing,
+// does not match its hash or fails validation, in which case no precompile is
+// active at the address. Failing to compile valid code is a local error, so it
+// is fatal instead of leaving this node with a different set of precompiles.
+func (r *OnChainPrecompileRegistry) precompile(codeHash common.Hash, state concrete.StateReader) concrete.Precompile {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if pc, ok := r.cache[codeHash]; ok {
+		return pc
+	}
+	code := r.readCode(codeHash, state)
+	if crypto.Keccak256Hash(code) != codeHash {
+		// The code might not have been written yet, so this is not cached
+		log.Warn("Concrete precompile code does not match hash", "registry", r.address, "codeHash", codeHash)
+		return nil
+	}
+	pc := r.newPrecompile(code)
+	if vp, ok := pc.(ValidatingPrecompile); ok {
+		if err := vp.Validate(); err != nil {
+			log.Warn("Invalid concrete precompile code", "registry", r.address, "codeHash", codeHash, "err", err)
+			r.cache[codeHash] = nil
+			return nil
+		}
+	}
+	if cp, ok := pc.(concrete.CompilingPrecompile); ok {
+		if err := cp.Compile(r.cacheDir); err != nil {
+			log.Crit("Failed to compile concrete precompile", "registry", r.address, "codeHash", codeHash, "err", err)
+		}
+	}
+	r.cache[codeHash] = pc
+	return pc
+}
+
+// readCode reads a Solidity bytes value from the code mapping.
+func (r *OnChainPrecompileRegistry) readCode(codeHash common.Hash, state concrete.StateReader) []byte {
+	slot := crypto.Keccak256Hash(codeHash.Bytes(), codeSlot.Bytes())
+	header := state.GetState(r.address, slot)
+	if header[31]&1 == 0 {
+		// Short bytes are stored in the header slot along with their length
+		length := int(header[31] / 2)
+		return common.CopyBytes(header[:length])
+	}
+	lengthBig := new(big.Int).Rsh(header.Big(), 1)
+	if !lengthBig.IsUint64() || lengthBig.Uint64() > MaxOnChainCodeSize {
+		return nil
+	}
+	var (
+		length    = lengthBig.Uint64()
+		code      = make([]byte, 0, length)
+		dataStart = crypto.Keccak256Hash(slot.Bytes()).Big()
+	)
+	for ii := uint64(0); uint64(len(code)) < length; ii++ {
+		dataSlot := common.BigToHash(new(big.Int).Add(dataStart, new(big.Int).SetUint64(ii)))
+		word := state.GetState(r.address, dataSlot)
+		remaining := length - uint64(len(code))
+		if remaining < 32 {
+			code = append(code, word[:remaining]...)
+		} else {
+			code = append(code, word[:]...)
+		}
+	}
+	return code
+}
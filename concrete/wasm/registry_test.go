@@ -0,0 +1,189 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasm
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/lib"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+type codePrecompile struct {
+	lib.BlankPrecompile
+	code []byte
+}
+
+type invalidPrecompile struct {
+	lib.BlankPrecompile
+}
+
+func (p *invalidPrecompile) Validate() error {
+	return errors.New("invalid")
+}
+
+type registryWriter struct {
+	statedb *state.StateDB
+	address common.Address
+}
+
+func (w *registryWriter) slotAt(base common.Hash, offset uint64) common.Hash {
+	return common.BigToHash(new(big.Int).Add(base.Big(), new(big.Int).SetUint64(offset)))
+}
+
+func (w *registryWriter) addPrecompile(address common.Address) {
+	n := w.statedb.GetState(w.address, precompilesSlot).Big().Uint64()
+	start := crypto.Keccak256Hash(precompilesSlot.Bytes())
+	w.statedb.SetState(w.address, w.slotAt(start, n), common.BytesToHash(address.Bytes()))
+	w.statedb.SetState(w.address, precompilesSlot, common.BigToHash(new(big.Int).SetUint64(n+1)))
+}
+
+func (w *registryWriter) addDeployment(address common.Address, activation, recorded uint64, code []byte) {
+	arraySlot := crypto.Keccak256Hash(common.LeftPadBytes(address.Bytes(), 32), deploymentsSlot.Bytes())
+	n := w.statedb.GetState(w.address, arraySlot).Big().Uint64()
+	start := crypto.Keccak256Hash(arraySlot.Bytes())
+	codeHash := common.Hash{}
+	if code != nil {
+		codeHash = crypto.Keccak256Hash(code)
+		w.setCode(codeHash, code)
+	}
+	w.statedb.SetState(w.address, w.slotAt(start, n*deploymentSize+deploymentActivationOffset), common.BigToHash(new(big.Int).SetUint64(activation)))
+	w.statedb.SetState(w.address, w.slotAt(start, n*deploymentSize+deploymentRecordedOffset), common.BigToHash(new(big.Int).SetUint64(recorded)))
+	w.statedb.SetState(w.address, w.slotAt(start, n*deploymentSize+deploymentCodeHashOffset), codeHash)
+	w.statedb.SetState(w.address, arraySlot, common.BigToHash(new(big.Int).SetUint64(n+1)))
+}
+
+func (w *registryWriter) setCode(codeHash common.Hash, code []byte) {
+	slot := crypto.Keccak256Hash(codeHash.Bytes(), codeSlot.Bytes())
+	if len(code) < 32 {
+		var header common.Hash
+		copy(header[:], code)
+		header[31] = byte(len(code) * 2)
+		w.statedb.SetState(w.address, slot, header)
+		return
+	}
+	w.statedb.SetState(w.address, slot, common.BigToHash(new(big.Int).SetUint64(uint64(len(code)*2+1))))
+	start := crypto.Keccak256Hash(slot.Bytes())
+	for ii := 0; ii*32 < len(code); ii++ {
+		var word common.Hash
+		copy(word[:], code[ii*32:])
+		w.statedb.SetState(w.address, w.slotAt(start, uint64(ii)), word)
+	}
+}
+
+func TestOnChainRegistry(t *testing.T) {
+	var (
+		r            = require.New(t)
+		registryAddr = common.HexToAddress("0xc0ffee")
+		addr1        = common.HexToAddress("0x80")
+		addr2        = common.HexToAddress("0x81")
+		baseAddr     = common.HexToAddress("0x82")
+		basePc       = &lib.BlankPrecompile{}
+		code1        = []byte("short code")
+		code2        = bytes.Repeat([]byte("long code "), 10)
+		instances    = 0
+	)
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	r.NoError(err)
+
+	base := concrete.NewRegistry()
+	base.AddPrecompile(0, baseAddr, basePc)
+	registry := NewOnChainRegistryWithConstructor(registryAddr, base, func(code []byte) concrete.Precompile {
+		instances++
+		return &codePrecompile{code: code}
+	})
+
+	w := &registryWriter{statedb: statedb, address: registryAddr}
+	w.addPrecompile(addr1)
+	w.addPrecompile(addr2)
+	w.addPrecompile(baseAddr)
+	w.addDeployment(addr1, 10, 5, code1)
+	w.addDeployment(addr1, 20, 15, code2)
+	w.addDeployment(addr1, 30, 25, nil)
+	// Recorded in its own activation block, so it is ignored
+	w.addDeployment(addr2, 10, 10, code1)
+	w.addDeployment(baseAddr, 40, 35, nil)
+
+	codeOf := func(pcs concrete.PrecompileMap, address common.Address) []byte {
+		pc, ok := pcs[address]
+		if !ok {
+			return nil
+		}
+		return pc.(*codePrecompile).code
+	}
+
+	pcs := concrete.GetPrecompiles(registry, 9, 0, statedb)
+	r.Len(pcs, 1)
+	r.Equal(basePc, pcs[baseAddr])
+
+	pcs = concrete.GetPrecompiles(registry, 10, 0, statedb)
+	r.Len(pcs, 2)
+	r.Equal(code1, codeOf(pcs, addr1))
+
+	pcs = concrete.GetPrecompiles(registry, 25, 0, statedb)
+	r.Len(pcs, 2)
+	r.Equal(code2, codeOf(pcs, addr1))
+
+	pcs = concrete.GetPrecompiles(registry, 30, 0, statedb)
+	r.Len(pcs, 1)
+	r.Equal(basePc, pcs[baseAddr])
+
+	pcs = concrete.GetPrecompiles(registry, 40, 0, statedb)
+	r.Len(pcs, 0)
+
+	// Modules are instantiated once per code hash
+	concrete.GetPrecompiles(registry, 10, 0, statedb)
+	concrete.GetPrecompiles(registry, 20, 0, statedb)
+	r.Equal(2, instances)
+
+	// Without state only the base precompiles are available
+	r.Equal(base.Precompiles(10, 0), registry.Precompiles(10, 0))
+}
+
+func TestOnChainRegistryInvalidCode(t *testing.T) {
+	var (
+		r            = require.New(t)
+		registryAddr = common.HexToAddress("0xc0ffee")
+		addr         = common.HexToAddress("0x80")
+		instances    = 0
+	)
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	r.NoError(err)
+	w := &registryWriter{statedb: statedb, address: registryAddr}
+	w.addPrecompile(addr)
+	w.addDeployment(addr, 10, 5, []byte("invalid code"))
+
+	// Invalid code is no precompile, and is only validated once
+	registry := NewOnChainRegistryWithConstructor(registryAddr, nil, func(code []byte) concrete.Precompile {
+		instances++
+		return &invalidPrecompile{}
+	})
+	r.Len(concrete.GetPrecompiles(registry, 10, 0, statedb), 0)
+	r.Len(concrete.GetPrecompiles(registry, 11, 0, statedb), 0)
+	r.Equal(1, instances)
+
+	// Code that is not a WASM module fails validation
+	registry = NewOnChainRegistry(registryAddr, nil)
+	r.Len(concrete.GetPrecompiles(registry, 10, 0, statedb), 0)
+}
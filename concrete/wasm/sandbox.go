@@ -0,0 +1,58 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasm
+
+import (
+	"errors"
+
+	"github.com/tetratelabs/wazero"
+)
+
+var (
+	ErrTrap          = errors.New("wasm trap")
+	ErrFuelExhausted = errors.New("fuel exhausted")
+)
+
+// SandboxConfig sets the limits applied to untrusted precompiles.
+//
+// Execution is only bounded by deterministic metering, so that every node gets
+// the same result for the same call. The module is instrumented when compiled
+// to consume one unit of fuel per instruction, and every unit of fuel costs
+// InstructionGas of the EVM gas passed into the precompile.
+type SandboxConfig struct {
+	// Gas charged for every instruction executed in the guest.
+	InstructionGas uint64
+	// Maximum number of instructions in a single invocation, regardless of
+	// the gas available. This bounds calls made without gas metering, e.g.
+	// IsStatic, Finalise and Commit.
+	MaxFuel uint64
+	// Maximum number of 64KiB memory pages available to the guest. Zero
+	// leaves the runtime default.
+	MemoryLimitPages uint32
+}
+
+var DefaultSandboxConfig = SandboxConfig{
+	InstructionGas:   1,
+	MaxFuel:          1 << 30,
+	MemoryLimitPages: 256, // 16MiB
+}
+
+func (c SandboxConfig) runtimeConfig(config wazero.RuntimeConfig) wazero.RuntimeConfig {
+	if c.MemoryLimitPages > 0 {
+		config = config.WithMemoryLimitPages(c.MemoryLimitPages)
+	}
+	return config
+}
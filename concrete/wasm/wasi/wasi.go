@@ -0,0 +1,382 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package wasi implements a deterministic subset of wasi_snapshot_preview1 for
+// WASM precompiles. Every node must get the same results when running a
+// precompile, so the shim never exposes the host clock, randomness, files or
+// sockets:
+//
+//   - Clocks return the block timestamp.
+//   - Random bytes are derived from the block's prevRandao.
+//   - Writes to stdout and stderr are routed to the Debug environment method.
+//   - Arguments and environment variables are empty.
+//   - Every other function traps.
+package wasi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const ModuleName = "wasi_snapshot_preview1"
+
+var ErrNotSupported = errors.New("wasi function not supported")
+
+// ExitError is the trap raised by proc_exit.
+type ExitError struct {
+	Code uint32
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("wasi: exit code %d", e.Code)
+}
+
+type errno = uint64
+
+const (
+	errnoSuccess errno = 0
+	errnoBadf    errno = 8
+	errnoFault   errno = 21
+	errnoInval   errno = 28
+)
+
+const (
+	// Maximum number of buffers in a single fd_write, as IOV_MAX on Linux
+	maxIovs = 1024
+	// Maximum number of bytes written by a single fd_write. Guests write the
+	// rest with further calls, as with any partial write.
+	maxWriteSize = 64 * 1024
+	// Gas charged for every 32 byte word of random bytes, the cost of hashing
+	// the seed and counters the word is derived from
+	randomWordGas = params.Keccak256Gas + 2*params.Keccak256WordGas
+)
+
+// Memory is the linear memory of a module instance.
+type Memory interface {
+	Read(offset, byteCount uint32) ([]byte, bool)
+	Write(offset uint32, data []byte) bool
+}
+
+type valueType byte
+
+const (
+	i32 valueType = iota
+	i64
+	unsupported
+)
+
+// function is a WASI function. A nil call traps.
+type function struct {
+	name    string
+	params  []valueType
+	results []valueType
+	call    func(s *Shim, mem Memory, params []uint64) (uint64, error)
+}
+
+func fn(name string, params []valueType, call func(s *Shim, mem Memory, params []uint64) (uint64, error)) function {
+	return function{name, params, []valueType{i32}, call}
+}
+
+var (
+	p1 = []valueType{i32}
+	p2 = []valueType{i32, i32}
+	p3 = []valueType{i32, i32, i32}
+	p4 = []valueType{i32, i32, i32, i32}
+	p5 = []valueType{i32, i32, i32, i32, i32}
+	p6 = []valueType{i32, i32, i32, i32, i32, i32}
+)
+
+// functions are the functions of the shim, in the order they are exported.
+var functions = []function{
+	fn("args_get", p2, emptyList),
+	fn("args_sizes_get", p2, emptyListSizes),
+	fn("environ_get", p2, emptyList),
+	fn("environ_sizes_get", p2, emptyListSizes),
+	fn("clock_res_get", p2, clockResGet),
+	fn("clock_time_get", []valueType{i32, i64, i32}, clockTimeGet),
+	fn("fd_write", p4, fdWrite),
+	fn("random_get", p2, randomGet),
+	fn("sched_yield", nil, func(*Shim, Memory, []uint64) (uint64, error) { return errnoSuccess, nil }),
+	{"proc_exit", p1, nil, procExit},
+
+	fn("fd_advise", []valueType{i32, i64, i64, i32}, nil),
+	fn("fd_allocate", []valueType{i32, i64, i64}, nil),
+	fn("fd_close", p1, nil),
+	fn("fd_datasync", p1, nil),
+	fn("fd_fdstat_get", p2, nil),
+	fn("fd_fdstat_set_flags", p2, nil),
+	fn("fd_fdstat_set_rights", []valueType{i32, i64, i64}, nil),
+	fn("fd_filestat_get", p2, nil),
+	fn("fd_filestat_set_size", []valueType{i32, i64}, nil),
+	fn("fd_filestat_set_times", []valueType{i32, i64, i64, i32}, nil),
+	fn("fd_pread", []valueType{i32, i32, i32, i64, i32}, nil),
+	fn("fd_prestat_get", p2, nil),
+	fn("fd_prestat_dir_name", p3, nil),
+	fn("fd_pwrite", []valueType{i32, i32, i32, i64, i32}, nil),
+	fn("fd_read", p4, nil),
+	fn("fd_readdir", []valueType{i32, i32, i32, i64, i32}, nil),
+	fn("fd_renumber", p2, nil),
+	fn("fd_seek", []valueType{i32, i64, i32, i32}, nil),
+	fn("fd_sync", p1, nil),
+	fn("fd_tell", p2, nil),
+	fn("path_create_directory", p3, nil),
+	fn("path_filestat_get", p5, nil),
+	fn("path_filestat_set_times", []valueType{i32, i32, i32, i32, i64, i64, i32}, nil),
+	fn("path_link", []valueType{i32, i32, i32, i32, i32, i32, i32}, nil),
+	fn("path_open", []valueType{i32, i32, i32, i32, i32, i64, i64, i32, i32}, nil),
+	fn("path_readlink", p6, nil),
+	fn("path_remove_directory", p3, nil),
+	fn("path_rename", p6, nil),
+	fn("path_symlink", p5, nil),
+	fn("path_unlink_file", p3, nil),
+	fn("poll_oneoff", p4, nil),
+	fn("proc_raise", p1, nil),
+	fn("sock_accept", p3, nil),
+	fn("sock_recv", p6, nil),
+	fn("sock_send", p5, nil),
+	fn("sock_shutdown", p2, nil),
+}
+
+var functionsByName = func() map[string]*function {
+	byName := make(map[string]*function, len(functions))
+	for ii := range functions {
+		byName[functions[ii].name] = &functions[ii]
+	}
+	return byName
+}()
+
+// Shim is an instance of the WASI module for a precompile. The environment
+// getter returns the environment of the running call, or nil outside of calls.
+type Shim struct {
+	getEnv      func() api.Environment
+	randomCalls uint64
+}
+
+func NewShim(getEnv func() api.Environment) *Shim {
+	return &Shim{getEnv: getEnv}
+}
+
+// Reset must be called before every precompile call, so the random bytes
+// returned during a call only depend on the call.
+func (s *Shim) Reset() {
+	s.randomCalls = 0
+}
+
+func (s *Shim) env() api.Environment {
+	if s.getEnv == nil {
+		return nil
+	}
+	return s.getEnv()
+}
+
+// trustedEnv returns the environment of the running call if it is trusted.
+func (s *Shim) trustedEnv() *api.Env {
+	if env, ok := s.env().(*api.Env); ok && env != nil && env.Config().Trusted {
+		return env
+	}
+	return nil
+}
+
+// useGas charges gas to the environment of the running call, returning its
+// error if there is not enough gas left.
+func (s *Shim) useGas(gas uint64) error {
+	env, ok := s.env().(*api.Env)
+	if !ok || env == nil {
+		return nil
+	}
+	env.UseGas(gas)
+	return env.Error()
+}
+
+// Import is a function a module is allowed to import besides the shim.
+type Import struct {
+	Module string
+	Name   string
+}
+
+// validateImport checks that a module can import a function with the given
+// signature, i.e. that it is one of the host functions or a shim function
+// with a matching signature.
+func validateImport(module, name string, params, results []valueType, host []Import) error {
+	for _, imp := range host {
+		if imp.Module == module && imp.Name == name {
+			return nil
+		}
+	}
+	if module != ModuleName {
+		return fmt.Errorf("import %s.%s not allowed", module, name)
+	}
+	f, ok := functionsByName[name]
+	if !ok {
+		return fmt.Errorf("import %s.%s not allowed", module, name)
+	}
+	if !equalTypes(f.params, params) || !equalTypes(f.results, results) {
+		return fmt.Errorf("import %s.%s has a mismatched signature", module, name)
+	}
+	return nil
+}
+
+func equalTypes(a, b []valueType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for ii := range a {
+		if a[ii] != b[ii] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Shim) call(f *function, mem Memory, params []uint64) (uint64, error) {
+	if f.call == nil {
+		return 0, fmt.Errorf("%w: %s", ErrNotSupported, f.name)
+	}
+	return f.call(s, mem, params)
+}
+
+func writeUint32(mem Memory, offset uint64, value uint32) bool {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], value)
+	return mem.Write(uint32(offset), buf[:])
+}
+
+func writeUint64(mem Memory, offset uint64, value uint64) bool {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], value)
+	return mem.Write(uint32(offset), buf[:])
+}
+
+func emptyList(*Shim, Memory, []uint64) (uint64, error) {
+	return errnoSuccess, nil
+}
+
+func emptyListSizes(s *Shim, mem Memory, params []uint64) (uint64, error) {
+	if !writeUint32(mem, params[0], 0) || !writeUint32(mem, params[1], 0) {
+		return errnoFault, nil
+	}
+	return errnoSuccess, nil
+}
+
+// Clocks have a resolution of a second, as block timestamps.
+const clockResolution = uint64(1e9)
+
+func clockResGet(s *Shim, mem Memory, params []uint64) (uint64, error) {
+	if !writeUint64(mem, params[1], clockResolution) {
+		return errnoFault, nil
+	}
+	return errnoSuccess, nil
+}
+
+// clockTimeGet returns the block timestamp for every clock, which keeps the
+// monotonic clock monotonic across blocks.
+func clockTimeGet(s *Shim, mem Memory, params []uint64) (uint64, error) {
+	var timestamp uint64
+	if env := s.env(); env != nil {
+		timestamp = env.GetBlockTimestamp() * clockResolution
+	}
+	if !writeUint64(mem, params[2], timestamp) {
+		return errnoFault, nil
+	}
+	return errnoSuccess, nil
+}
+
+// fdWrite writes stdout and stderr to the Debug environment method, which is
+// only available to trusted environments. Output is discarded otherwise. At
+// most maxWriteSize bytes are written per call.
+func fdWrite(s *Shim, mem Memory, params []uint64) (uint64, error) {
+	fd, iovs, iovsLen, resultPtr := params[0], params[1], params[2], params[3]
+	if fd != 1 && fd != 2 {
+		return errnoBadf, nil
+	}
+	if iovsLen > maxIovs {
+		return errnoInval, nil
+	}
+	iovsData, ok := mem.Read(uint32(iovs), uint32(iovsLen)*8)
+	if !ok {
+		return errnoFault, nil
+	}
+	var (
+		env     = s.trustedEnv()
+		data    []byte
+		written uint32
+	)
+	for ii := 0; ii < len(iovsData) && written < maxWriteSize; ii += 8 {
+		offset := binary.LittleEndian.Uint32(iovsData[ii:])
+		length := binary.LittleEndian.Uint32(iovsData[ii+4:])
+		if length > maxWriteSize-written {
+			length = maxWriteSize - written
+		}
+		// Memory reads do not copy, so this only checks the bounds
+		buf, ok := mem.Read(offset, length)
+		if !ok {
+			return errnoFault, nil
+		}
+		if env != nil {
+			data = append(data, buf...)
+		}
+		written += length
+	}
+	if env != nil && len(data) > 0 {
+		env.Debug(string(data))
+	}
+	if !writeUint32(mem, resultPtr, written) {
+		return errnoFault, nil
+	}
+	return errnoSuccess, nil
+}
+
+// randomGet fills the buffer with bytes derived from the block's prevRandao,
+// so they are the same on every node but should not be relied on to be
+// unpredictable. Outside of calls the seed is zero. Every word is charged
+// randomWordGas.
+func randomGet(s *Shim, mem Memory, params []uint64) (uint64, error) {
+	buf, length := uint32(params[0]), uint32(params[1])
+	if _, ok := mem.Read(buf, length); !ok {
+		return errnoFault, nil
+	}
+	words := (uint64(length) + 31) / 32
+	if err := s.useGas(words * randomWordGas); err != nil {
+		return 0, err
+	}
+	var seed common.Hash
+	if env := s.env(); env != nil {
+		seed = env.GetPrevRandom()
+	}
+	s.randomCalls++
+	var counters [16]byte
+	binary.BigEndian.PutUint64(counters[:8], s.randomCalls)
+	for block := uint64(0); block < words; block++ {
+		binary.BigEndian.PutUint64(counters[8:], block)
+		word := crypto.Keccak256(seed.Bytes(), counters[:])
+		if remaining := length - uint32(block*32); remaining < 32 {
+			word = word[:remaining]
+		}
+		if !mem.Write(buf+uint32(block*32), word) {
+			return errnoFault, nil
+		}
+	}
+	return errnoSuccess, nil
+}
+
+func procExit(s *Shim, mem Memory, params []uint64) (uint64, error) {
+	return 0, &ExitError{Code: uint32(params[0])}
+}
@@ -0,0 +1,338 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasi
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/stretchr/testify/require"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+type testBlockContext struct {
+	api.BlockContext
+	timestamp uint64
+	random    common.Hash
+}
+
+func (b *testBlockContext) Timestamp() uint64   { return b.timestamp }
+func (b *testBlockContext) Random() common.Hash { return b.random }
+
+func newTestEnvironment(timestamp uint64, random common.Hash) *api.Env {
+	block := &testBlockContext{api.NewMockBlockContext(), timestamp, random}
+	return api.NewEnvironment(common.Address{}, api.EnvConfig{Trusted: true}, api.NewMockStateDB(), block, api.NewMockCallContext(), api.NewMockCaller(), false, 0)
+}
+
+// Minimal encoding of WASM modules with up to 127 of anything but section
+// sizes
+
+func wasmVec(items ...[]byte) []byte {
+	vec := []byte{byte(len(items))}
+	for _, item := range items {
+		vec = append(vec, item...)
+	}
+	return vec
+}
+
+func wasmName(name string) []byte {
+	return append([]byte{byte(len(name))}, name...)
+}
+
+func wasmSection(id byte, items ...[]byte) []byte {
+	content := wasmVec(items...)
+	section := []byte{id}
+	for size := len(content); ; size >>= 7 {
+		if size < 0x80 {
+			section = append(section, byte(size))
+			break
+		}
+		section = append(section, byte(size&0x7f|0x80))
+	}
+	return append(section, content...)
+}
+
+func wasmFuncType(params []byte, results []byte) []byte {
+	return append(append(append([]byte{0x60, byte(len(params))}, params...), byte(len(results))), results...)
+}
+
+func wasmFuncImport(module, name string, typeIdx byte) []byte {
+	return append(append(wasmName(module), wasmName(name)...), 0x00, typeIdx)
+}
+
+const (
+	wasmI32 = 0x7f
+	wasmI64 = 0x7e
+)
+
+// importsModule returns a module importing the given functions, all of type
+// (i32, i32) -> i32.
+func importsModule(imports ...[2]string) []byte {
+	code := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	code = append(code, wasmSection(1, wasmFuncType([]byte{wasmI32, wasmI32}, []byte{wasmI32}))...)
+	var entries [][]byte
+	for _, imp := range imports {
+		entries = append(entries, wasmFuncImport(imp[0], imp[1], 0))
+	}
+	return append(code, wasmSection(2, entries...)...)
+}
+
+// guestModule exports functions calling the shim:
+//   - time() writes the clock time at 0
+//   - random() writes 40 random bytes at 8
+//   - exit() exits with code 3
+//   - close() calls an unsupported function
+var guestModule = func() []byte {
+	code := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	code = append(code, wasmSection(1,
+		wasmFuncType([]byte{wasmI32, wasmI64, wasmI32}, []byte{wasmI32}),
+		wasmFuncType([]byte{wasmI32, wasmI32}, []byte{wasmI32}),
+		wasmFuncType(nil, []byte{wasmI32}),
+		wasmFuncType([]byte{wasmI32}, nil),
+		wasmFuncType([]byte{wasmI32}, []byte{wasmI32}),
+	)...)
+	code = append(code, wasmSection(2,
+		wasmFuncImport(ModuleName, "clock_time_get", 0),
+		wasmFuncImport(ModuleName, "random_get", 1),
+		wasmFuncImport(ModuleName, "proc_exit", 3),
+		wasmFuncImport(ModuleName, "fd_close", 4),
+	)...)
+	code = append(code, wasmSection(3, []byte{2}, []byte{2}, []byte{2}, []byte{2})...)
+	code = append(code, wasmSection(5, []byte{0x00, 0x01})...)
+	code = append(code, wasmSection(7,
+		append(wasmName("memory"), 0x02, 0x00),
+		append(wasmName("time"), 0x00, 4),
+		append(wasmName("random"), 0x00, 5),
+		append(wasmName("exit"), 0x00, 6),
+		append(wasmName("close"), 0x00, 7),
+	)...)
+	body := func(instrs ...byte) []byte {
+		return append([]byte{byte(len(instrs) + 2), 0x00}, append(instrs, 0x0b)...)
+	}
+	code = append(code, wasmSection(10,
+		body(0x41, 0x00, 0x42, 0x00, 0x41, 0x00, 0x10, 0x00),
+		body(0x41, 0x08, 0x41, 0x28, 0x10, 0x01),
+		body(0x41, 0x03, 0x10, 0x02, 0x41, 0x00),
+		body(0x41, 0x00, 0x10, 0x03),
+	)...)
+	return code
+}()
+
+func TestValidateWazero(t *testing.T) {
+	var (
+		r       = require.New(t)
+		ctx     = context.Background()
+		runtime = wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter())
+		host    = Import{"env", "concrete_Environment"}
+	)
+	defer runtime.Close(ctx)
+
+	validate := func(code []byte) error {
+		compiled, err := runtime.CompileModule(ctx, code)
+		r.NoError(err)
+		return ValidateWazero(compiled, host)
+	}
+	r.NoError(validate(guestModule))
+	r.NoError(validate(importsModule([2]string{"env", "concrete_Environment"}, [2]string{ModuleName, "random_get"})))
+	r.EqualError(validate(importsModule([2]string{"env", "other"})), "import env.other not allowed")
+	r.EqualError(validate(importsModule([2]string{ModuleName, "unknown"})), "import wasi_snapshot_preview1.unknown not allowed")
+	r.EqualError(validate(importsModule([2]string{ModuleName, "fd_close"})), "import wasi_snapshot_preview1.fd_close has a mismatched signature")
+
+	memoryImport := append(append(wasmName("env"), wasmName("memory")...), 0x02, 0x00, 0x01)
+	code := append([]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}, wasmSection(2, memoryImport)...)
+	r.EqualError(validate(code), "imported memories not allowed")
+}
+
+func TestShimWazero(t *testing.T) {
+	var (
+		r   = require.New(t)
+		ctx = context.Background()
+		env *api.Env
+	)
+	shim := NewShim(func() api.Environment {
+		if env == nil {
+			return nil
+		}
+		return env
+	})
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter())
+	defer runtime.Close(ctx)
+	r.NoError(shim.InstantiateWazero(ctx, runtime))
+	mod, err := runtime.Instantiate(ctx, guestModule)
+	r.NoError(err)
+
+	call := func(name string) (uint64, error) {
+		ret, err := mod.ExportedFunction(name).Call(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return ret[0], nil
+	}
+	read := func(offset, length uint32) []byte {
+		data, ok := mod.Memory().Read(offset, length)
+		r.True(ok)
+		return append([]byte{}, data...)
+	}
+
+	// Without an environment the clock is zero
+	errno, err := call("time")
+	r.NoError(err)
+	r.Zero(errno)
+	r.Zero(binary.LittleEndian.Uint64(read(0, 8)))
+
+	env = newTestEnvironment(1700000000, common.Hash{1})
+	_, err = call("time")
+	r.NoError(err)
+	r.Equal(uint64(1700000000)*1e9, binary.LittleEndian.Uint64(read(0, 8)))
+
+	// Random bytes only depend on the environment and the calls since reset
+	shim.Reset()
+	_, err = call("random")
+	r.NoError(err)
+	first := read(8, 40)
+	_, err = call("random")
+	r.NoError(err)
+	r.NotEqual(first, read(8, 40))
+	shim.Reset()
+	_, err = call("random")
+	r.NoError(err)
+	r.Equal(first, read(8, 40))
+	env = newTestEnvironment(1700000000, common.Hash{2})
+	shim.Reset()
+	_, err = call("random")
+	r.NoError(err)
+	r.NotEqual(first, read(8, 40))
+
+	_, err = call("close")
+	r.ErrorIs(err, ErrNotSupported)
+
+	_, err = call("exit")
+	var exitErr *sys.ExitError
+	r.True(errors.As(err, &exitErr))
+	r.Equal(uint32(3), exitErr.ExitCode())
+}
+
+type mockMemory []byte
+
+func (m mockMemory) Read(offset, byteCount uint32) ([]byte, bool) {
+	if uint64(offset)+uint64(byteCount) > uint64(len(m)) {
+		return nil, false
+	}
+	return m[offset : offset+byteCount], true
+}
+
+func (m mockMemory) Write(offset uint32, data []byte) bool {
+	if uint64(offset)+uint64(len(data)) > uint64(len(m)) {
+		return false
+	}
+	copy(m[offset:], data)
+	return true
+}
+
+func TestFdWrite(t *testing.T) {
+	var (
+		r    = require.New(t)
+		mem  = make(mockMemory, 64)
+		env  = newTestEnvironment(0, common.Hash{})
+		shim = NewShim(func() api.Environment { return env })
+	)
+	// Two iovecs at 0 pointing to "hello" at 32 and " world" at 40
+	binary.LittleEndian.PutUint32(mem[0:], 32)
+	binary.LittleEndian.PutUint32(mem[4:], 5)
+	binary.LittleEndian.PutUint32(mem[8:], 40)
+	binary.LittleEndian.PutUint32(mem[12:], 6)
+	copy(mem[32:], "hello")
+	copy(mem[40:], " world")
+
+	f := functionsByName["fd_write"]
+	errno, err := shim.call(f, mem, []uint64{1, 0, 2, 16})
+	r.NoError(err)
+	r.Equal(errnoSuccess, errno)
+	r.Equal(uint32(11), binary.LittleEndian.Uint32(mem[16:]))
+	r.NoError(env.Error())
+
+	errno, err = shim.call(f, mem, []uint64{3, 0, 2, 16})
+	r.NoError(err)
+	r.Equal(errnoBadf, errno)
+
+	errno, err = shim.call(f, mem, []uint64{2, 60, 2, 16})
+	r.NoError(err)
+	r.Equal(errnoFault, errno)
+}
+
+func TestFdWriteLimits(t *testing.T) {
+	var (
+		r    = require.New(t)
+		mem  = make(mockMemory, 2*maxWriteSize)
+		env  *api.Env
+		shim = NewShim(func() api.Environment { return env })
+		f    = functionsByName["fd_write"]
+	)
+	// Two iovecs at 0 covering the memory after them
+	binary.LittleEndian.PutUint32(mem[0:], 32)
+	binary.LittleEndian.PutUint32(mem[4:], maxWriteSize)
+	binary.LittleEndian.PutUint32(mem[8:], 32)
+	binary.LittleEndian.PutUint32(mem[12:], maxWriteSize)
+
+	// Untrusted writes are discarded but still report the bytes written
+	env = api.NewEnvironment(common.Address{}, api.EnvConfig{}, api.NewMockStateDB(), api.NewMockBlockContext(), api.NewMockCallContext(), api.NewMockCaller(), false, 0)
+	errno, err := shim.call(f, mem, []uint64{1, 0, 1, 16})
+	r.NoError(err)
+	r.Equal(errnoSuccess, errno)
+	r.Equal(uint32(maxWriteSize), binary.LittleEndian.Uint32(mem[16:]))
+
+	// Writes are partial beyond the maximum size
+	env = newTestEnvironment(0, common.Hash{})
+	errno, err = shim.call(f, mem, []uint64{1, 0, 2, 16})
+	r.NoError(err)
+	r.Equal(errnoSuccess, errno)
+	r.Equal(uint32(maxWriteSize), binary.LittleEndian.Uint32(mem[16:]))
+
+	errno, err = shim.call(f, mem, []uint64{1, 0, maxIovs + 1, 16})
+	r.NoError(err)
+	r.Equal(errnoInval, errno)
+}
+
+func TestRandomGet(t *testing.T) {
+	var (
+		r    = require.New(t)
+		mem  = make(mockMemory, 4096)
+		env  = api.NewEnvironment(common.Address{}, api.EnvConfig{}, api.NewMockStateDB(), api.NewMockBlockContext(), api.NewMockCallContext(), api.NewMockCaller(), true, 1000)
+		shim = NewShim(func() api.Environment { return env })
+		f    = functionsByName["random_get"]
+	)
+	// Buffers out of bounds fail before any gas is charged
+	errno, err := shim.call(f, mem, []uint64{64, 4096})
+	r.NoError(err)
+	r.Equal(errnoFault, errno)
+	r.Equal(uint64(1000), env.Gas())
+
+	// Every word is charged, on top of the cost of charging gas
+	errno, err = shim.call(f, mem, []uint64{0, 40})
+	r.NoError(err)
+	r.Equal(errnoSuccess, errno)
+	r.LessOrEqual(env.Gas(), uint64(1000-2*randomWordGas))
+	r.NotEqual(make([]byte, 40), []byte(mem[:40]))
+	r.Equal(make([]byte, 4096-40), []byte(mem[40:]))
+
+	_, err = shim.call(f, mem, []uint64{0, 4096})
+	r.ErrorIs(err, api.ErrOutOfGas)
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !mips && !mipsle && !mips64 && !mips64le
+
+// This file will ignored when building for mips to prevent compatibility
+// issues.
+
+package wasi
+
+import (
+	"errors"
+
+	"github.com/wasmerio/wasmer-go/wasmer"
+)
+
+type wasmerMemory struct {
+	memory *wasmer.Memory
+}
+
+func (m *wasmerMemory) Read(offset, byteCount uint32) ([]byte, bool) {
+	data := m.memory.Data()
+	if uint64(offset)+uint64(byteCount) > uint64(len(data)) {
+		return nil, false
+	}
+	return data[offset : offset+byteCount], true
+}
+
+func (m *wasmerMemory) Write(offset uint32, v []byte) bool {
+	data := m.memory.Data()
+	if uint64(offset)+uint64(len(v)) > uint64(len(data)) {
+		return false
+	}
+	copy(data[offset:], v)
+	return true
+}
+
+// WasmerImports holds the shim functions for a wasmer instance. Init must be
+// called with the instance before any of them is called.
+type WasmerImports struct {
+	shim   *Shim
+	memory *wasmerMemory
+}
+
+func (s *Shim) NewWasmerImports() *WasmerImports {
+	return &WasmerImports{shim: s, memory: &wasmerMemory{}}
+}
+
+func (w *WasmerImports) Init(instance *wasmer.Instance) error {
+	mem, err := instance.Exports.GetMemory("memory")
+	if err != nil {
+		return err
+	}
+	w.memory.memory = mem
+	return nil
+}
+
+func wasmerValueTypes(types []valueType) []*wasmer.ValueType {
+	kinds := make([]wasmer.ValueKind, len(types))
+	for ii, t := range types {
+		if t == i64 {
+			kinds[ii] = wasmer.I64
+		} else {
+			kinds[ii] = wasmer.I32
+		}
+	}
+	return wasmer.NewValueTypes(kinds...)
+}
+
+func fromWasmerValueTypes(wsTypes []*wasmer.ValueType) []valueType {
+	types := make([]valueType, len(wsTypes))
+	for ii, t := range wsTypes {
+		switch t.Kind() {
+		case wasmer.I32:
+			types[ii] = i32
+		case wasmer.I64:
+			types[ii] = i64
+		default:
+			types[ii] = unsupported
+		}
+	}
+	return types
+}
+
+// Register registers the shim in the import object in place of the
+// wasi_snapshot_preview1 module. Errors, including exits, trap.
+func (w *WasmerImports) Register(store *wasmer.Store, importObject *wasmer.ImportObject) {
+	externs := make(map[string]wasmer.IntoExtern, len(functions))
+	for ii := range functions {
+		f := &functions[ii]
+		call := func(_ interface{}, args []wasmer.Value) ([]wasmer.Value, error) {
+			params := make([]uint64, len(args))
+			for jj, arg := range args {
+				if arg.Kind() == wasmer.I64 {
+					params[jj] = uint64(arg.I64())
+				} else {
+					params[jj] = uint64(uint32(arg.I32()))
+				}
+			}
+			ret, err := w.shim.call(f, w.memory, params)
+			if err != nil {
+				return nil, err
+			}
+			if len(f.results) == 0 {
+				return []wasmer.Value{}, nil
+			}
+			return []wasmer.Value{wasmer.NewI32(int32(ret))}, nil
+		}
+		externs[f.name] = wasmer.NewFunctionWithEnvironment(
+			store,
+			wasmer.NewFunctionType(wasmerValueTypes(f.params), wasmerValueTypes(f.results)),
+			w,
+			call,
+		)
+	}
+	importObject.Register(ModuleName, externs)
+}
+
+// ValidateWasmer checks that a module only imports functions from the shim or
+// the given host functions.
+func ValidateWasmer(module *wasmer.Module, host ...Import) error {
+	for _, imp := range module.Imports() {
+		externType := imp.Type()
+		if externType.Kind() != wasmer.FUNCTION {
+			return errors.New("only function imports allowed")
+		}
+		funcType := externType.IntoFunctionType()
+		params := fromWasmerValueTypes(funcType.Params())
+		results := fromWasmerValueTypes(funcType.Results())
+		if err := validateImport(imp.Module(), imp.Name(), params, results, host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !mips && !mipsle && !mips64 && !mips64le
+
+package wasi
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/stretchr/testify/require"
+	"github.com/wasmerio/wasmer-go/wasmer"
+)
+
+func TestShimWasmer(t *testing.T) {
+	var (
+		r     = require.New(t)
+		store = wasmer.NewStore(wasmer.NewEngine())
+		env   = newTestEnvironment(1700000000, common.Hash{1})
+		shim  = NewShim(func() api.Environment { return env })
+	)
+	invalid, err := wasmer.NewModule(store, importsModule([2]string{"env", "other"}))
+	r.NoError(err)
+	r.EqualError(ValidateWasmer(invalid), "import env.other not allowed")
+
+	module, err := wasmer.NewModule(store, guestModule)
+	r.NoError(err)
+	r.NoError(ValidateWasmer(module))
+	importObject := wasmer.NewImportObject()
+	imports := shim.NewWasmerImports()
+	imports.Register(store, importObject)
+	instance, err := wasmer.NewInstance(module, importObject)
+	r.NoError(err)
+	r.NoError(imports.Init(instance))
+
+	timeFunc, err := instance.Exports.GetFunction("time")
+	r.NoError(err)
+	_, err = timeFunc()
+	r.NoError(err)
+	mem, err := instance.Exports.GetMemory("memory")
+	r.NoError(err)
+	r.Equal(uint64(1700000000)*1e9, binary.LittleEndian.Uint64(mem.Data()[0:8]))
+
+	closeFunc, err := instance.Exports.GetFunction("close")
+	r.NoError(err)
+	_, err = closeFunc()
+	r.ErrorContains(err, ErrNotSupported.Error())
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+func wazeroValueTypes(types []valueType) []api.ValueType {
+	wzTypes := make([]api.ValueType, len(types))
+	for ii, t := range types {
+		if t == i64 {
+			wzTypes[ii] = api.ValueTypeI64
+		} else {
+			wzTypes[ii] = api.ValueTypeI32
+		}
+	}
+	return wzTypes
+}
+
+func fromWazeroValueTypes(wzTypes []api.ValueType) []valueType {
+	types := make([]valueType, len(wzTypes))
+	for ii, t := range wzTypes {
+		switch t {
+		case api.ValueTypeI32:
+			types[ii] = i32
+		case api.ValueTypeI64:
+			types[ii] = i64
+		default:
+			types[ii] = unsupported
+		}
+	}
+	return types
+}
+
+// InstantiateWazero instantiates the shim in the runtime in place of the
+// wasi_snapshot_preview1 module.
+func (s *Shim) InstantiateWazero(ctx context.Context, r wazero.Runtime) error {
+	builder := r.NewHostModuleBuilder(ModuleName)
+	for ii := range functions {
+		f := &functions[ii]
+		call := func(ctx context.Context, mod api.Module, stack []uint64) {
+			ret, err := s.call(f, mod.Memory(), stack)
+			if err != nil {
+				var exitErr *ExitError
+				if errors.As(err, &exitErr) {
+					panic(sys.NewExitError(exitErr.Code))
+				}
+				panic(err)
+			}
+			if len(f.results) > 0 {
+				stack[0] = ret
+			}
+		}
+		builder.NewFunctionBuilder().
+			WithGoModuleFunction(api.GoModuleFunc(call), wazeroValueTypes(f.params), wazeroValueTypes(f.results)).
+			Export(f.name)
+	}
+	_, err := builder.Instantiate(ctx)
+	return err
+}
+
+// ValidateWazero checks that a compiled module only imports functions from the
+// shim or the given host functions, and does not import memories.
+func ValidateWazero(compiled wazero.CompiledModule, host ...Import) error {
+	if len(compiled.ImportedMemories()) > 0 {
+		return errors.New("imported memories not allowed")
+	}
+	for _, def := range compiled.ImportedFunctions() {
+		module, name, _ := def.Import()
+		params := fromWazeroValueTypes(def.ParamTypes())
+		results := fromWazeroValueTypes(def.ResultTypes())
+		if err := validateImport(module, name, params, results, host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
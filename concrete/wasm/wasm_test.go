@@ -16,7 +16,10 @@
 package wasm
 
 import (
-	_ "embed"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -25,11 +28,24 @@ import (
 	"github.com/ethereum/go-ethereum/concrete/mock"
 )
 
-//go:embed testdata/gas.wasm
-var errCode []byte
+// loadTestdata reads a module built by `make concrete-wasm`, skipping the test
+// if it has not been built.
+func loadTestdata(t testing.TB, name string) []byte {
+	code, err := os.ReadFile(filepath.Join("testdata", name))
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Skipf("testdata/%s not found, run make concrete-wasm to build it", name)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
 
 func TestWasmEnvErr(t *testing.T) {
-	input := []byte{1}
+	var (
+		input   = []byte{1}
+		errCode = loadTestdata(t, "gas.wasm")
+	)
 	runtimes := []struct {
 		name string
 		pc   concrete.Precompile
@@ -53,3 +69,56 @@ func TestWasmEnvErr(t *testing.T) {
 		})
 	}
 }
+
+func TestUntrustedWasm(t *testing.T) {
+	var (
+		config  = api.EnvConfig{Trusted: false}
+		errCode = loadTestdata(t, "gas.wasm")
+	)
+
+	t.Run("OutOfGas", func(t *testing.T) {
+		pc := NewUntrustedWazeroPrecompile(errCode, DefaultSandboxConfig)
+		env := mock.NewMockEnvironment(common.Address{}, config, true, 0)
+		output, _, err := concrete.RunPrecompile(pc, env, []byte{1}, true)
+		if err != api.ErrOutOfGas {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(output) != 0 {
+			t.Fatalf("unexpected output: %x", output)
+		}
+	})
+
+	t.Run("FuelExhausted", func(t *testing.T) {
+		sandbox := DefaultSandboxConfig
+		sandbox.InstructionGas = 0
+		sandbox.MaxFuel = 1
+		pc := NewUntrustedWazeroPrecompile(errCode, sandbox)
+		env := mock.NewMockEnvironment(common.Address{}, config, true, 1e6)
+		_, remainingGas, err := concrete.RunPrecompile(pc, env, []byte{1}, true)
+		if err != api.ErrExecutionReverted {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if remainingGas != 0 {
+			t.Fatalf("unexpected remaining gas: %d", remainingGas)
+		}
+	})
+
+	t.Run("Trap", func(t *testing.T) {
+		pc := NewUntrustedWazeroPrecompile(errCode, DefaultSandboxConfig)
+		env := mock.NewMockEnvironment(common.Address{}, config, true, 1e6)
+		// Empty input makes the guest index out of range
+		_, _, err := concrete.RunPrecompile(pc, env, []byte{}, true)
+		if err != api.ErrExecutionReverted {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// The module is reinstantiated after a trap
+		env = mock.NewMockEnvironment(common.Address{}, config, true, 1e6)
+		output, _, err := concrete.RunPrecompile(pc, env, []byte{1}, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(output) != 1 || output[0] != 1 {
+			t.Fatalf("unexpected output: %x", output)
+		}
+	})
+}
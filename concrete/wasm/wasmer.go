@@ -21,15 +21,20 @@
 package wasm
 
 import (
-	"sync"
-
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/concrete"
 	"github.com/ethereum/go-ethereum/concrete/api"
 	"github.com/ethereum/go-ethereum/concrete/wasm/host"
 	"github.com/ethereum/go-ethereum/concrete/wasm/memory"
+	"github.com/ethereum/go-ethereum/concrete/wasm/wasi"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/wasmerio/wasmer-go/wasmer"
 )
 
+// Note: Wasmer precompiles are not sandboxed. Execution is not metered and
+// running them in an untrusted environment panics, so they are for trusted use
+// only. Untrusted precompiles must be created with NewUntrustedWazeroPrecompile.
+
 func NewWasmerPrecompile(code []byte) concrete.Precompile {
 	config := wasmer.NewConfig().UseCraneliftCompiler()
 	return newWasmerPrecompile(code, config)
@@ -39,7 +44,7 @@ func NewWasmerPrecompileWithConfig(code []byte, config *wasmer.Config) concrete.
 	return newWasmerPrecompile(code, config)
 }
 
-func newWasmerModule(envCall host.WasmerHostFunc, code []byte, engineConfig *wasmer.Config) (*wasmer.Module, *wasmer.Instance, error) {
+func newWasmerModule(envCall host.WasmerHostFunc, shim *wasi.Shim, code []byte, engineConfig *wasmer.Config) (*wasmer.Module, *wasmer.Instance, error) {
 	engine := wasmer.NewEngineWithConfig(engineConfig)
 	store := wasmer.NewStore(engine)
 	module, err := wasmer.NewModule(store, code)
@@ -47,15 +52,21 @@ func newWasmerModule(envCall host.WasmerHostFunc, code []byte, engineConfig *was
 	if err != nil {
 		return nil, nil, err
 	}
-
-	wasiEnv, err := wasmer.NewWasiStateBuilder("wasi-program").Finalize()
-	if err != nil {
+	if err := wasi.ValidateWasmer(module, wasi.Import{Module: "env", Name: Environment_WasmFuncName}); err != nil {
 		return nil, nil, err
 	}
-	importObject, err := wasiEnv.GenerateImportObject(store, module)
+
+	instance, err := instantiateWasmerModule(envCall, shim, store, module)
 	if err != nil {
 		return nil, nil, err
 	}
+	return module, instance, nil
+}
+
+func instantiateWasmerModule(envCall host.WasmerHostFunc, shim *wasi.Shim, store *wasmer.Store, module *wasmer.Module) (*wasmer.Instance, error) {
+	importObject := wasmer.NewImportObject()
+	wasiImports := shim.NewWasmerImports()
+	wasiImports.Register(store, importObject)
 
 	wasmerEnv := host.NewWasmerEnvironment()
 
@@ -76,60 +87,126 @@ func newWasmerModule(envCall host.WasmerHostFunc, code []byte, engineConfig *was
 
 	instance, err := wasmer.NewInstance(module, importObject)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	wasmerEnv.Init(instance)
+	if err := wasiImports.Init(instance); err != nil {
+		return nil, err
+	}
 
-	return module, instance, nil
+	return instance, nil
 }
 
 type wasmerPrecompile struct {
+	codeHash common.Hash
+	pool     *instancePool[*wasmerInstance]
+}
+
+func newWasmerPrecompile(code []byte, engineConfig *wasmer.Config) *wasmerPrecompile {
+	pc := &wasmerPrecompile{codeHash: crypto.Keccak256Hash(code)}
+
+	instance := newWasmerInstance()
+	module, wsInstance, err := newWasmerModule(instance.envCall(), instance.shim, code, engineConfig)
+	if err != nil {
+		panic(err)
+	}
+	if err := instance.init(wsInstance); err != nil {
+		panic(err)
+	}
+
+	// Other instances get their own store, as stores are not safe for
+	// concurrent use, and deserialize the compiled module instead of
+	// compiling it again.
+	serialized, err := module.Serialize()
+	if err != nil {
+		panic(err)
+	}
+	engine := wasmer.NewEngineWithConfig(engineConfig)
+	pc.pool = newInstancePool(DefaultPoolConfig, func() (*wasmerInstance, error) {
+		store := wasmer.NewStore(engine)
+		module, err := wasmer.DeserializeModule(store, serialized)
+		if err != nil {
+			return nil, err
+		}
+		instance := newWasmerInstance()
+		wsInstance, err := instantiateWasmerModule(instance.envCall(), instance.shim, store, module)
+		if err != nil {
+			return nil, err
+		}
+		return instance, instance.init(wsInstance)
+	})
+	pc.pool.put(instance)
+
+	return pc
+}
+
+type wasmerInstance struct {
 	instance    *wasmer.Instance
-	module      *wasmer.Module
-	mutex       sync.Mutex
 	memory      memory.Memory
 	allocator   memory.Allocator
 	environment *api.Env
+	shim        *wasi.Shim
 	expIsStatic wasmer.NativeFunction
 	expFinalise wasmer.NativeFunction
 	expCommit   wasmer.NativeFunction
 	expRun      wasmer.NativeFunction
 }
 
-func newWasmerPrecompile(code []byte, engineConfig *wasmer.Config) *wasmerPrecompile {
-	pc := &wasmerPrecompile{}
+func newWasmerInstance() *wasmerInstance {
+	inst := &wasmerInstance{}
+	inst.shim = wasi.NewShim(inst.shimEnvironment)
+	return inst
+}
 
-	envCall := host.NewWasmerEnvironmentCaller(func() api.Environment { return pc.environment })
-	module, instance, err := newWasmerModule(envCall, code, engineConfig)
-	if err != nil {
-		panic(err)
-	}
+func (p *wasmerInstance) envCall() host.WasmerHostFunc {
+	return host.NewWasmerEnvironmentCaller(func() api.Environment { return p.environment })
+}
 
-	pc.instance = instance
-	pc.module = module
-	pc.memory, pc.allocator = host.NewWasmerMemory(instance)
+func (p *wasmerInstance) init(instance *wasmer.Instance) error {
+	var err error
+	p.instance = instance
+	p.memory, p.allocator = host.NewWasmerMemory(instance)
 
-	pc.expIsStatic, err = instance.Exports.GetFunction(IsStatic_WasmFuncName)
+	p.expIsStatic, err = instance.Exports.GetFunction(IsStatic_WasmFuncName)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	pc.expFinalise, err = instance.Exports.GetFunction(Finalise_WasmFuncName)
+	p.expFinalise, err = instance.Exports.GetFunction(Finalise_WasmFuncName)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	pc.expCommit, err = instance.Exports.GetFunction(Commit_WasmFuncName)
+	p.expCommit, err = instance.Exports.GetFunction(Commit_WasmFuncName)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	pc.expRun, err = instance.Exports.GetFunction(Run_WasmFuncName)
+	p.expRun, err = instance.Exports.GetFunction(Run_WasmFuncName)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	return pc
+	return nil
+}
+
+func (p *wasmerInstance) memorySize() uint64 {
+	mem, err := p.instance.Exports.GetMemory("memory")
+	if err != nil {
+		return 0
+	}
+	return uint64(mem.DataSize())
+}
+
+func (p *wasmerInstance) close() {
+	p.instance.Close()
 }
 
-func (p *wasmerPrecompile) call__Uint64(expFunc wasmer.NativeFunction) uint64 {
+func (p *wasmerInstance) shimEnvironment() api.Environment {
+	if p.environment == nil {
+		return nil
+	}
+	return p.environment
+}
+
+func (p *wasmerInstance) call__Uint64(expFunc wasmer.NativeFunction) uint64 {
 	_ret, err := expFunc()
 	if err != nil {
 		panic(err)
@@ -138,7 +215,7 @@ func (p *wasmerPrecompile) call__Uint64(expFunc wasmer.NativeFunction) uint64 {
 	return uint64(ret)
 }
 
-func (p *wasmerPrecompile) call__Err(expFunc wasmer.NativeFunction) error {
+func (p *wasmerInstance) call__Err(expFunc wasmer.NativeFunction) error {
 	_retPointer := p.call__Uint64(expFunc)
 	retPointer := memory.MemPointer(_retPointer)
 	retErr := memory.GetError(p.memory, retPointer)
@@ -146,7 +223,7 @@ func (p *wasmerPrecompile) call__Err(expFunc wasmer.NativeFunction) error {
 	return retErr
 }
 
-func (p *wasmerPrecompile) call_Bytes_Uint64(expFunc wasmer.NativeFunction, input []byte) uint64 {
+func (p *wasmerInstance) call_Bytes_Uint64(expFunc wasmer.NativeFunction, input []byte) uint64 {
 	pointer := memory.PutValue(p.memory, input)
 	defer p.allocator.Free(pointer)
 	_ret, err := expFunc(int64(pointer))
@@ -157,7 +234,7 @@ func (p *wasmerPrecompile) call_Bytes_Uint64(expFunc wasmer.NativeFunction, inpu
 	return uint64(ret)
 }
 
-func (p *wasmerPrecompile) call_Bytes_BytesErr(expFunc wasmer.NativeFunction, input []byte) ([]byte, error) {
+func (p *wasmerInstance) call_Bytes_BytesErr(expFunc wasmer.NativeFunction, input []byte) ([]byte, error) {
 	_retPointer := p.call_Bytes_Uint64(expFunc, input)
 	retPointer := memory.MemPointer(_retPointer)
 	retValues, retErr := memory.GetReturnWithError(p.memory, retPointer, true)
@@ -167,7 +244,7 @@ func (p *wasmerPrecompile) call_Bytes_BytesErr(expFunc wasmer.NativeFunction, in
 	return retValues[0], retErr
 }
 
-func (p *wasmerPrecompile) before(env api.Environment) {
+func (p *wasmerPrecompile) before(env api.Environment) (*wasmerInstance, error) {
 	var envImpl *api.Env
 	if env != nil {
 		envImpl = env.(*api.Env)
@@ -175,38 +252,59 @@ func (p *wasmerPrecompile) before(env api.Environment) {
 			panic("untrusted environment")
 		}
 	}
-	p.mutex.Lock()
-	p.environment = envImpl
+	instance, err := p.pool.get()
+	if err != nil {
+		return nil, err
+	}
+	instance.environment = envImpl
+	instance.shim.Reset()
+	return instance, nil
+}
+
+func (p *wasmerPrecompile) after(instance *wasmerInstance) {
+	instance.environment = nil
+	// instance.allocator.Prune()
+	p.pool.put(instance)
 }
 
-func (p *wasmerPrecompile) after(env api.Environment) {
-	p.environment = nil
-	// p.allocator.Prune()
-	p.mutex.Unlock()
+func (p *wasmerPrecompile) CodeHash() common.Hash {
+	return p.codeHash
 }
 
 func (p *wasmerPrecompile) IsStatic(input []byte) bool {
-	p.before(nil)
-	defer p.after(nil)
-	return p.call_Bytes_Uint64(p.expIsStatic, input) != 0
+	instance, err := p.before(nil)
+	if err != nil {
+		return false
+	}
+	defer p.after(instance)
+	return instance.call_Bytes_Uint64(instance.expIsStatic, input) != 0
 }
 
 func (p *wasmerPrecompile) Finalise(env api.Environment) error {
-	p.before(env)
-	defer p.after(env)
-	return p.call__Err(p.expFinalise)
+	instance, err := p.before(env)
+	if err != nil {
+		return err
+	}
+	defer p.after(instance)
+	return instance.call__Err(instance.expFinalise)
 }
 
 func (p *wasmerPrecompile) Commit(env api.Environment) error {
-	p.before(env)
-	defer p.after(env)
-	return p.call__Err(p.expCommit)
+	instance, err := p.before(env)
+	if err != nil {
+		return err
+	}
+	defer p.after(instance)
+	return instance.call__Err(instance.expCommit)
 }
 
 func (p *wasmerPrecompile) Run(env api.Environment, input []byte) ([]byte, error) {
-	p.before(env)
-	defer p.after(env)
-	return p.call_Bytes_BytesErr(p.expRun, input)
+	instance, err := p.before(env)
+	if err != nil {
+		return nil, err
+	}
+	defer p.after(instance)
+	return instance.call_Bytes_BytesErr(instance.expRun, input)
 }
 
 var _ concrete.Precompile = (*wasmerPrecompile)(nil)
@@ -17,30 +17,48 @@ package wasm
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
 	"sync"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/concrete"
 	"github.com/ethereum/go-ethereum/concrete/api"
 	"github.com/ethereum/go-ethereum/concrete/wasm/host"
 	"github.com/ethereum/go-ethereum/concrete/wasm/memory"
+	"github.com/ethereum/go-ethereum/concrete/wasm/wasi"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/tetratelabs/wazero"
 	wz_api "github.com/tetratelabs/wazero/api"
-	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 )
 
-// Note: For trusted use only. Precompiles can trigger a panic in the host.
+// Note: Unless created with NewUntrustedWazeroPrecompile, precompiles are for
+// trusted use only and can trigger a panic in the host.
+//
+// Precompiles are compiled on first use, or when Compile is called, which
+// concrete.CompilePrecompiles does for all registered precompiles at startup.
 
 func NewWazeroPrecompile(code []byte) concrete.Precompile {
 	config := wazero.NewRuntimeConfigCompiler()
-	return newWazeroPrecompile(code, config)
+	return newWazeroPrecompile(code, config, nil)
 }
 
 func NewWazeroPrecompileWithConfig(code []byte, config wazero.RuntimeConfig) concrete.Precompile {
-	return newWazeroPrecompile(code, config)
+	return newWazeroPrecompile(code, config, nil)
 }
 
-func newWazeroModule(envCall host.WazeroHostFunc, code []byte, runtimeConfig wazero.RuntimeConfig) (wz_api.Module, wazero.Runtime, error) {
-	ctx := context.Background()
+// NewUntrustedWazeroPrecompile returns a precompile that runs in an untrusted
+// environment under the limits set by sandbox. Guest traps are returned as
+// errors instead of panicking the host. The code is instrumented for fuel
+// metering when compiled, so it must not use SIMD instructions.
+func NewUntrustedWazeroPrecompile(code []byte, sandbox SandboxConfig) concrete.Precompile {
+	config := wazero.NewRuntimeConfigCompiler()
+	return newWazeroPrecompile(code, sandbox.runtimeConfig(config), &sandbox)
+}
+
+func newWazeroModule(ctx context.Context, envCall host.WazeroHostFunc, shim *wasi.Shim, code []byte, runtimeConfig wazero.RuntimeConfig) (wazero.CompiledModule, wazero.Runtime, error) {
 	r := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
 	_, err := r.NewHostModuleBuilder("env").
 		NewFunctionBuilder().WithFunc(envCall).Export(Environment_WasmFuncName).
@@ -48,70 +66,259 @@ func newWazeroModule(envCall host.WazeroHostFunc, code []byte, runtimeConfig waz
 	if err != nil {
 		return nil, nil, err
 	}
-	wasi_snapshot_preview1.MustInstantiate(ctx, r)
-	mod, err := r.Instantiate(ctx, code)
+	if err := shim.InstantiateWazero(ctx, r); err != nil {
+		return nil, nil, err
+	}
+	compiled, err := r.CompileModule(ctx, code)
 	if err != nil {
 		return nil, nil, err
 	}
-	return mod, r, nil
+	if err := wasi.ValidateWazero(compiled, wasi.Import{Module: "env", Name: Environment_WasmFuncName}); err != nil {
+		return nil, nil, err
+	}
+	return compiled, r, nil
 }
 
 type wazeroPrecompile struct {
+	code          []byte
+	runtimeConfig wazero.RuntimeConfig
+	codeHash      common.Hash
+	sandbox       *SandboxConfig
+	compileOnce   sync.Once
+	compileErr    error
+	pool          *instancePool[*wazeroInstance]
+}
+
+func newWazeroPrecompile(code []byte, runtimeConfig wazero.RuntimeConfig, sandbox *SandboxConfig) *wazeroPrecompile {
+	return &wazeroPrecompile{
+		code:          code,
+		runtimeConfig: runtimeConfig,
+		codeHash:      crypto.Keccak256Hash(code),
+		sandbox:       sandbox,
+	}
+}
+
+// Compile compiles the module and creates its first instance, reporting
+// invalid code. Only the first call has an effect, and precompiles that are
+// not compiled before their first use are compiled without a cache directory.
+func (p *wazeroPrecompile) Compile(cacheDir string) error {
+	p.compileOnce.Do(func() {
+		p.compileErr = p.compile(cacheDir)
+	})
+	return p.compileErr
+}
+
+func (p *wazeroPrecompile) compile(cacheDir string) error {
+	cache, err := compilationCache(cacheDir)
+	if err != nil {
+		return err
+	}
+	// Every instance has its own runtime, so the host functions can find the
+	// environment of their instance. The compilation cache makes sure the
+	// code is only compiled once.
+	runtimeConfig := p.runtimeConfig.WithCompilationCache(cache)
+	code := p.code
+	if p.sandbox != nil {
+		if code, err = instrumentFuel(code, p.sandbox.MaxFuel); err != nil {
+			return err
+		}
+	}
+	instance, err := newWazeroInstance(code, runtimeConfig, p.sandbox)
+	if err != nil {
+		return err
+	}
+	p.pool = newInstancePool(DefaultPoolConfig, func() (*wazeroInstance, error) {
+		return newWazeroInstance(code, runtimeConfig, p.sandbox)
+	})
+	p.pool.put(instance)
+	return nil
+}
+
+// Validate checks that the module compiles and instantiates with the required
+// exports, using the interpreter so the result only depends on the code and the
+// sandbox limits, not on the host or its compilation cache.
+func (p *wazeroPrecompile) Validate() error {
+	var (
+		code          = p.code
+		runtimeConfig = wazero.NewRuntimeConfigInterpreter()
+		err           error
+	)
+	if p.sandbox != nil {
+		if code, err = instrumentFuel(code, p.sandbox.MaxFuel); err != nil {
+			return err
+		}
+		runtimeConfig = p.sandbox.runtimeConfig(runtimeConfig)
+	}
+	instance, err := newWazeroInstance(code, runtimeConfig, p.sandbox)
+	if err != nil {
+		return err
+	}
+	instance.close()
+	return nil
+}
+
+type wazeroInstance struct {
 	runtime     wazero.Runtime
+	compiled    wazero.CompiledModule
 	module      wz_api.Module
-	mutex       sync.Mutex
 	memory      memory.Memory
 	allocator   memory.Allocator
 	environment *api.Env
+	shim        *wasi.Shim
+	sandbox     *SandboxConfig
+	fuelGlobal  wz_api.MutableGlobal
+	fuel        uint64 // Fuel left in the invocation
+	fuelSet     uint64 // Fuel in the global when last set
+	trapped     bool
 	expIsStatic wz_api.Function
 	expFinalise wz_api.Function
 	expCommit   wz_api.Function
 	expRun      wz_api.Function
 }
 
-func newWazeroPrecompile(code []byte, runtimeConfig wazero.RuntimeConfig) *wazeroPrecompile {
-	pc := &wazeroPrecompile{}
+func newWazeroInstance(code []byte, runtimeConfig wazero.RuntimeConfig, sandbox *SandboxConfig) (*wazeroInstance, error) {
+	inst := &wazeroInstance{sandbox: sandbox}
 
-	envCall := host.NewWazeroEnvironmentCaller(func() api.Environment { return pc.environment })
-	mod, r, err := newWazeroModule(envCall, code, runtimeConfig)
+	ctx := context.Background()
+	envCall := host.NewWazeroEnvironmentCaller(func() api.Environment {
+		// Charge the fuel used so far, so the environment sees the gas left
+		inst.chargeFuel(false)
+		return inst.environment
+	})
+	inst.shim = wasi.NewShim(inst.shimEnvironment)
+	compiled, r, err := newWazeroModule(ctx, envCall, inst.shim, code, runtimeConfig)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	pc.runtime = r
-	pc.module = mod
-	pc.memory, pc.allocator = host.NewWazeroMemory(context.Background(), mod)
+	inst.runtime = r
+	inst.compiled = compiled
+	if err := inst.instantiate(); err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+
+	return inst, nil
+}
 
-	pc.expIsStatic = mod.ExportedFunction(IsStatic_WasmFuncName)
-	if pc.expIsStatic == nil {
-		panic("isStatic not exported")
+func (p *wazeroInstance) instantiate() error {
+	ctx := context.Background()
+	mod, err := p.runtime.InstantiateModule(ctx, p.compiled, wazero.NewModuleConfig())
+	if err != nil {
+		return err
+	}
+	if p.sandbox != nil {
+		global, ok := mod.ExportedGlobal(Fuel_WasmGlobalName).(wz_api.MutableGlobal)
+		if !ok {
+			return errors.New("fuel global not exported")
+		}
+		p.fuelGlobal = global
 	}
-	pc.expFinalise = mod.ExportedFunction(Finalise_WasmFuncName)
-	if pc.expFinalise == nil {
-		panic("finalise not exported")
+
+	p.module = mod
+	p.memory, p.allocator = host.NewWazeroMemory(ctx, mod)
+
+	p.expIsStatic = mod.ExportedFunction(IsStatic_WasmFuncName)
+	if p.expIsStatic == nil {
+		return errors.New("isStatic not exported")
 	}
-	pc.expCommit = mod.ExportedFunction(Commit_WasmFuncName)
-	if pc.expCommit == nil {
-		panic("commit not exported")
+	p.expFinalise = mod.ExportedFunction(Finalise_WasmFuncName)
+	if p.expFinalise == nil {
+		return errors.New("finalise not exported")
 	}
-	pc.expRun = mod.ExportedFunction(Run_WasmFuncName)
-	if pc.expRun == nil {
-		panic("run not exported")
+	p.expCommit = mod.ExportedFunction(Commit_WasmFuncName)
+	if p.expCommit == nil {
+		return errors.New("commit not exported")
+	}
+	p.expRun = mod.ExportedFunction(Run_WasmFuncName)
+	if p.expRun == nil {
+		return errors.New("run not exported")
 	}
 
-	return pc
+	return nil
 }
 
-func (p *wazeroPrecompile) call__Uint64(expFunc wz_api.Function) uint64 {
+// reset replaces the module instance after a trap, as the guest memory may have
+// been left in an inconsistent state.
+func (p *wazeroInstance) reset() error {
 	ctx := context.Background()
-	_ret, err := expFunc.Call(ctx)
+	p.module.Close(ctx)
+	if err := p.instantiate(); err != nil {
+		return err
+	}
+	p.trapped = false
+	return nil
+}
+
+func (p *wazeroInstance) memorySize() uint64 {
+	return uint64(p.module.Memory().Size())
+}
+
+func (p *wazeroInstance) close() {
+	p.runtime.Close(context.Background())
+}
+
+// setFuel sets the fuel available to the guest until the next charge, which is
+// the fuel left in the invocation bounded by the gas left.
+func (p *wazeroInstance) setFuel() {
+	if p.sandbox == nil {
+		return
+	}
+	fuel := p.fuel
+	if env := p.environment; env != nil && env.GasMetered() && p.sandbox.InstructionGas > 0 {
+		if gasFuel := env.Gas() / p.sandbox.InstructionGas; gasFuel < fuel {
+			fuel = gasFuel
+		}
+	}
+	p.fuelSet = fuel
+	p.fuelGlobal.Set(fuel)
+}
+
+// chargeFuel charges the gas for the fuel used since it was last set, and sets
+// the fuel again. When the fuel was exhausted, one more unit is charged, so
+// running out of fuel because of the gas left is an out of gas error.
+func (p *wazeroInstance) chargeFuel(exhausted bool) {
+	if p.sandbox == nil {
+		return
+	}
+	used := p.fuelSet - p.fuelGlobal.Get()
+	p.fuel -= used
+	if exhausted {
+		used++
+	}
+	if env := p.environment; env != nil && p.sandbox.InstructionGas > 0 {
+		hi, gas := bits.Mul64(used, p.sandbox.InstructionGas)
+		if hi != 0 {
+			gas = math.MaxUint64
+		}
+		env.UseGas(gas)
+	}
+	p.setFuel()
+}
+
+// callGuest calls an exported function, charging the fuel it used.
+func (p *wazeroInstance) callGuest(expFunc wz_api.Function, params ...uint64) ([]uint64, error) {
+	ret, err := expFunc.Call(context.Background(), params...)
+	if p.sandbox == nil {
+		return ret, err
+	}
+	exhausted := err != nil && p.fuelGlobal.Get() == 0
+	p.chargeFuel(exhausted)
+	if exhausted {
+		err = ErrFuelExhausted
+	}
+	return ret, err
+}
+
+func (p *wazeroInstance) call__Uint64(expFunc wz_api.Function) uint64 {
+	_ret, err := p.callGuest(expFunc)
 	if err != nil {
 		panic(err)
 	}
 	return _ret[0]
 }
 
-func (p *wazeroPrecompile) call__Err(expFunc wz_api.Function) error {
+func (p *wazeroInstance) call__Err(expFunc wz_api.Function) error {
 	_retPointer := p.call__Uint64(expFunc)
 	retPointer := memory.MemPointer(_retPointer)
 	retErr := memory.GetError(p.memory, retPointer)
@@ -119,26 +326,26 @@ func (p *wazeroPrecompile) call__Err(expFunc wz_api.Function) error {
 	return retErr
 }
 
-func (p *wazeroPrecompile) call_Bytes_Uint64(expFunc wz_api.Function, input []byte) (ret uint64) {
+func (p *wazeroInstance) call_Bytes_Uint64(expFunc wz_api.Function, input []byte) (ret uint64) {
 	defer func() {
 		if r := recover(); r != nil {
 			if p.environment.Error() == nil {
 				panic(r)
 			}
+			p.trapped = true
 			ret = memory.NullPointer.Uint64()
 		}
 	}()
-	ctx := context.Background()
 	pointer := memory.PutValue(p.memory, input)
 	defer p.allocator.Free(pointer)
-	_ret, err := expFunc.Call(ctx, pointer.Uint64())
+	_ret, err := p.callGuest(expFunc, pointer.Uint64())
 	if err != nil {
 		panic(err)
 	}
 	return _ret[0]
 }
 
-func (p *wazeroPrecompile) call_Bytes_BytesErr(expFunc wz_api.Function, input []byte) ([]byte, error) {
+func (p *wazeroInstance) call_Bytes_BytesErr(expFunc wz_api.Function, input []byte) ([]byte, error) {
 	_retPointer := p.call_Bytes_Uint64(expFunc, input)
 	retPointer := memory.MemPointer(_retPointer)
 	retValues, retErr := memory.GetReturnWithError(p.memory, retPointer, true)
@@ -148,46 +355,116 @@ func (p *wazeroPrecompile) call_Bytes_BytesErr(expFunc wz_api.Function, input []
 	return retValues[0], retErr
 }
 
-func (p *wazeroPrecompile) before(env api.Environment) {
+// recoverTrap converts a guest trap into an error when running sandboxed. As
+// with EVM exceptional halts, a trap consumes all the remaining gas.
+func (p *wazeroInstance) recoverTrap(err *error) {
+	if p.sandbox == nil {
+		return
+	}
+	if r := recover(); r != nil {
+		p.trapped = true
+		if p.environment != nil {
+			p.environment.UseGas(p.environment.Gas())
+		}
+		*err = fmt.Errorf("%w: %v", ErrTrap, r)
+	}
+}
+
+func (p *wazeroInstance) shimEnvironment() api.Environment {
+	if p.environment == nil {
+		return nil
+	}
+	return p.environment
+}
+
+func (p *wazeroPrecompile) before(env api.Environment) (*wazeroInstance, error) {
 	var envImpl *api.Env
 	if env != nil {
 		envImpl = env.(*api.Env)
-		if !envImpl.Config().Trusted {
+		if !envImpl.Config().Trusted && p.sandbox == nil {
 			panic("untrusted environment")
 		}
 	}
-	p.mutex.Lock()
-	p.environment = envImpl
+	if err := p.Compile(""); err != nil {
+		return nil, err
+	}
+	instance, err := p.pool.get()
+	if err != nil {
+		return nil, err
+	}
+	instance.environment = envImpl
+	instance.shim.Reset()
+	if p.sandbox != nil {
+		instance.fuel = p.sandbox.MaxFuel
+		instance.setFuel()
+	}
+	return instance, nil
+}
+
+func (p *wazeroPrecompile) after(instance *wazeroInstance) {
+	instance.environment = nil
+	if instance.trapped && p.sandbox != nil {
+		if err := instance.reset(); err != nil {
+			instance.close()
+			return
+		}
+	}
+	// instance.allocator.Prune()
+	p.pool.put(instance)
 }
 
-func (p *wazeroPrecompile) after(env api.Environment) {
-	p.environment = nil
-	// p.allocator.Prune()
-	p.mutex.Unlock()
+func (p *wazeroPrecompile) CodeHash() common.Hash {
+	return p.codeHash
+}
+
+func (p *wazeroPrecompile) Untrusted() bool {
+	return p.sandbox != nil
 }
 
 func (p *wazeroPrecompile) IsStatic(input []byte) bool {
-	p.before(nil)
-	defer p.after(nil)
-	return p.call_Bytes_Uint64(p.expIsStatic, input) != 0
+	instance, err := p.before(nil)
+	if err != nil {
+		return false
+	}
+	defer p.after(instance)
+	// A trap is reported as non-static
+	defer instance.recoverTrap(&err)
+	return instance.call_Bytes_Uint64(instance.expIsStatic, input) != 0
 }
 
-func (p *wazeroPrecompile) Finalise(env api.Environment) error {
-	p.before(env)
-	defer p.after(env)
-	return p.call__Err(p.expFinalise)
+func (p *wazeroPrecompile) Finalise(env api.Environment) (err error) {
+	instance, err := p.before(env)
+	if err != nil {
+		return err
+	}
+	defer p.after(instance)
+	defer instance.recoverTrap(&err)
+	return instance.call__Err(instance.expFinalise)
 }
 
-func (p *wazeroPrecompile) Commit(env api.Environment) error {
-	p.before(env)
-	defer p.after(env)
-	return p.call__Err(p.expCommit)
+func (p *wazeroPrecompile) Commit(env api.Environment) (err error) {
+	instance, err := p.before(env)
+	if err != nil {
+		return err
+	}
+	defer p.after(instance)
+	defer instance.recoverTrap(&err)
+	return instance.call__Err(instance.expCommit)
 }
 
-func (p *wazeroPrecompile) Run(env api.Environment, input []byte) ([]byte, error) {
-	p.before(env)
-	defer p.after(env)
-	return p.call_Bytes_BytesErr(p.expRun, input)
+func (p *wazeroPrecompile) Run(env api.Environment, input []byte) (output []byte, err error) {
+	instance, err := p.before(env)
+	if err != nil {
+		return nil, err
+	}
+	defer p.after(instance)
+	defer instance.recoverTrap(&err)
+	return instance.call_Bytes_BytesErr(instance.expRun, input)
 }
 
-var _ concrete.Precompile = (*wazeroPrecompile)(nil)
+var (
+	_ concrete.Precompile          = (*wazeroPrecompile)(nil)
+	_ concrete.UntrustedPrecompile = (*wazeroPrecompile)(nil)
+	_ concrete.CompilingPrecompile = (*wazeroPrecompile)(nil)
+	_ ValidatingPrecompile         = (*wazeroPrecompile)(nil)
+)
@@ -53,7 +53,8 @@ type BlockGen struct {
 
 	engine consensus.Engine
 
-	concrete concrete.PrecompileRegistry
+	concrete  concrete.PrecompileRegistry
+	scheduled types.Transactions
 }
 
 // SetCoinbase sets the coinbase of the generated block.
@@ -118,9 +119,11 @@ func (b *BlockGen) addTx(bc *BlockChain, vmConfig vm.Config, tx *types.Transacti
 	if b.gasPool == nil {
 		b.SetCoinbase(common.Address{})
 	}
+	if len(b.scheduled) > 0 && !tx.IsDepositTx() {
+		b.addScheduledTxs()
+	}
 	b.statedb.SetTxContext(tx.Hash(), len(b.txs))
-	concretePcs := b.concrete.Precompiles(b.header.Number.Uint64())
-	receipt, err := ApplyTransaction(b.cm.config, bc, &b.header.Coinbase, b.gasPool, b.statedb, b.header, tx, &b.header.GasUsed, vmConfig, concretePcs)
+	receipt, err := ApplyTransaction(b.cm.config, bc, &b.header.Coinbase, b.gasPool, b.statedb, b.header, tx, &b.header.GasUsed, vmConfig, b.statedb.BlockConcretePrecompiles())
 	if err != nil {
 		panic(err)
 	}
@@ -131,6 +134,16 @@ func (b *BlockGen) addTx(bc *BlockChain, vmConfig vm.Config, tx *types.Transacti
 	}
 }
 
+// addScheduledTxs adds the calls scheduled by concrete precompiles for the
+// generated block, which must follow its leading deposit transactions.
+func (b *BlockGen) addScheduledTxs() {
+	scheduled := b.scheduled
+	b.scheduled = nil
+	for _, tx := range scheduled {
+		b.addTx(nil, vm.Config{}, tx)
+	}
+}
+
 // AddTx adds a transaction to the generated block. If no coinbase has
 // been set, the block's coinbase is set to the zero address.
 //
@@ -348,10 +361,19 @@ func GenerateChainWithConcrete(config *params.ChainConfig, parent *types.Block,
 		if config.DAOForkSupport && config.DAOForkBlock != nil && config.DAOForkBlock.Cmp(b.header.Number) == 0 {
 			misc.ApplyDAOHardFork(statedb)
 		}
+		ProcessConcreteMigrations(config, concreteRegistry, b.header, parent.Time(), statedb)
+		var (
+			concretePcs = ProcessConcretePrecompiles(concreteRegistry, b.header, statedb)
+			concreteEVM = vm.NewEVMWithConcrete(NewEVMBlockContext(b.header, cm, &b.header.Coinbase, config, statedb), vm.TxContext{}, statedb, config, vm.Config{}, concretePcs)
+		)
+		ProcessConcreteBeginBlock(concreteEVM, statedb)
+		b.scheduled = ProcessConcreteSchedule(b.header, statedb)
 		// Execute any user modifications to the block
 		if gen != nil {
 			gen(i, b)
 		}
+		b.addScheduledTxs()
+		ProcessConcreteEndBlock(concreteEVM, statedb)
 
 		block, err := b.engine.FinalizeAndAssemble(cm, b.header, statedb, b.txs, b.uncles, b.receipts, b.withdrawals)
 		if err != nil {
@@ -360,7 +382,7 @@ func GenerateChainWithConcrete(config *params.ChainConfig, parent *types.Block,
 
 		// Write state changes to db
 		root, err := statedb.CommitWithConcrete(
-			concreteRegistry.Precompiles(b.header.Number.Uint64()),
+			concretePcs,
 			b.header.Number.Uint64(),
 			config.IsEIP158(b.header.Number),
 		)
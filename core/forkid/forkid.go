@@ -269,6 +269,11 @@ func gatherForks(config *params.ChainConfig, genesis uint64) ([]uint64, []uint64
 			}
 		}
 	}
+	// Concrete precompile activations change the ruleset too
+	if config.Concrete != nil {
+		forksByBlock = append(forksByBlock, config.Concrete.ForkBlocks()...)
+		forksByTime = append(forksByTime, config.Concrete.ForkTimes()...)
+	}
 	slices.Sort(forksByBlock)
 	slices.Sort(forksByTime)
 
@@ -439,3 +439,30 @@ func TestTimeBasedForkInGenesis(t *testing.T) {
 		}
 	}
 }
+
+func TestConcretePrecompileForks(t *testing.T) {
+	var (
+		block   = uint64(100)
+		time    = uint64(1690475657)
+		genesis = types.NewBlockWithHeader(&types.Header{})
+		config  = *params.TestChainConfig
+	)
+	base := NewID(&config, genesis, 0, 0)
+	if base.Next != 0 {
+		t.Fatalf("unexpected next fork: %d", base.Next)
+	}
+
+	config.Concrete = &params.ConcreteConfig{
+		Precompiles: []params.ConcretePrecompileConfig{
+			{Address: common.BytesToAddress([]byte{0x80}), Name: "a", Block: new(uint64)},
+			{Address: common.BytesToAddress([]byte{0x81}), Name: "b", Block: &block},
+			{Address: common.BytesToAddress([]byte{0x82}), Name: "c", Time: &time},
+		},
+	}
+	if have := NewID(&config, genesis, 0, 0); have.Hash != base.Hash || have.Next != block {
+		t.Fatalf("incorrect forkid before concrete block fork: have %x/%d, want %x/%d", have.Hash, have.Next, base.Hash, block)
+	}
+	if have := NewID(&config, genesis, block, 0); have.Hash == base.Hash || have.Next != time {
+		t.Fatalf("incorrect forkid after concrete block fork: have %x/%d, want next %d", have.Hash, have.Next, time)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	cc_api "github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Isolated storage is a key-value store of a concrete precompile kept in a trie
+// of its own, separate from the precompile account storage trie.
+//
+// Writes are buffered in memory and never touch a trie while transactions are
+// executed. They are only flushed when the state root is computed, once per
+// block in practice, into the storage trie of an account derived from the
+// precompile address. The root of that trie is then written to the reserved
+// cc_api.IsolatedStorageRootKey slot of the precompile account, so the
+// precompile storage root commits to the isolated store. As any other storage
+// trie, the isolated trie is reachable from the state root, so it is
+// committed, pruned and synced along with the rest of the state.
+//
+// The derived account is created with a nonce of one on its first non-empty
+// flush, so it is never deleted as empty. It is never cleaned up either: it
+// outlives the precompile being deactivated and clearing all of its keys.
+
+var isolatedStoragePrefix = []byte("concrete.isolated")
+
+// IsolatedStorageAddress returns the address of the account holding the
+// isolated storage of the given precompile.
+func IsolatedStorageAddress(addr common.Address) common.Address {
+	return common.BytesToAddress(crypto.Keccak256(isolatedStoragePrefix, addr.Bytes()))
+}
+
+// isolatedStorage holds the isolated storage writes not yet flushed into the
+// isolated tries.
+type isolatedStorage map[common.Address]Storage
+
+func newIsolatedStorage() isolatedStorage {
+	return make(isolatedStorage)
+}
+
+func (t isolatedStorage) get(addr common.Address, key common.Hash) (common.Hash, bool) {
+	value, ok := t[addr][key]
+	return value, ok
+}
+
+func (t isolatedStorage) set(addr common.Address, key, value common.Hash) {
+	if _, ok := t[addr]; !ok {
+		t[addr] = make(Storage)
+	}
+	t[addr][key] = value
+}
+
+func (t isolatedStorage) delete(addr common.Address, key common.Hash) {
+	delete(t[addr], key)
+	if len(t[addr]) == 0 {
+		delete(t, addr)
+	}
+}
+
+func (t isolatedStorage) Copy() isolatedStorage {
+	storage := make(isolatedStorage, len(t))
+	for addr, slots := range t {
+		storage[addr] = slots.Copy()
+	}
+	return storage
+}
+
+// SetIsolatedState writes a value to the isolated storage of the given address.
+func (s *StateDB) SetIsolatedState(addr common.Address, key, value common.Hash) {
+	prev, dirty := s.isolatedStorage.get(addr, key)
+	if !dirty {
+		prev = s.GetState(IsolatedStorageAddress(addr), key)
+	}
+	if prev == value {
+		return
+	}
+	s.journal.append(isolatedStorageChange{
+		account:  &addr,
+		key:      key,
+		prevalue: prev,
+		dirty:    dirty,
+	})
+	s.isolatedStorage.set(addr, key, value)
+}
+
+// GetIsolatedState reads a value from the isolated storage of the given address.
+func (s *StateDB) GetIsolatedState(addr common.Address, key common.Hash) common.Hash {
+	if value, dirty := s.isolatedStorage.get(addr, key); dirty {
+		return value
+	}
+	return s.GetState(IsolatedStorageAddress(addr), key)
+}
+
+// flushIsolatedStorage writes the buffered isolated storage writes into the
+// isolated tries and records their roots in the precompile accounts.
+func (s *StateDB) flushIsolatedStorage() {
+	for addr, slots := range s.isolatedStorage {
+		storageAddr := IsolatedStorageAddress(addr)
+		obj := s.getStateObject(storageAddr)
+		if obj == nil {
+			empty := true
+			for _, value := range slots {
+				if value != (common.Hash{}) {
+					empty = false
+					break
+				}
+			}
+			if empty {
+				continue
+			}
+			obj = s.getOrNewStateObject(storageAddr)
+			obj.SetNonce(1)
+		}
+		for key, value := range slots {
+			obj.SetState(key, value)
+		}
+		obj.updateRoot()
+		s.SetState(addr, cc_api.IsolatedStorageRootKey, obj.Root())
+	}
+	if len(s.isolatedStorage) > 0 {
+		s.isolatedStorage = newIsolatedStorage()
+	}
+}
@@ -149,6 +149,11 @@ type (
 		account       *common.Address
 		key, prevalue common.Hash
 	}
+	isolatedStorageChange struct {
+		account       *common.Address
+		key, prevalue common.Hash
+		dirty         bool
+	}
 	ephemeralStorageChange struct {
 		account       *common.Address
 		key, prevalue common.Hash
@@ -303,6 +308,18 @@ func (ch accessListAddSlotChange) dirtied() *common.Address {
 	return nil
 }
 
+func (ch isolatedStorageChange) revert(s *StateDB) {
+	if ch.dirty {
+		s.isolatedStorage.set(*ch.account, ch.key, ch.prevalue)
+	} else {
+		s.isolatedStorage.delete(*ch.account, ch.key)
+	}
+}
+
+func (ch isolatedStorageChange) dirtied() *common.Address {
+	return nil
+}
+
 func (ch ephemeralStorageChange) revert(s *StateDB) {
 	s.setEphemeralState(*ch.account, ch.key, ch.prevalue)
 }
@@ -115,6 +115,9 @@ type StateDB struct {
 
 	// Concrete
 	ephemeralStorage ephemeralStorage
+	concreteTouched  map[common.Address]struct{} // Concrete precompiles called in the current transaction
+	concreteBlockPcs concrete.PrecompileMap      // Concrete precompiles active in the current block
+	isolatedStorage  isolatedStorage             // Isolated storage writes not yet flushed into the tries
 
 	// Journal of state modifications. This is the backbone of
 	// Snapshot and RevertToSnapshot.
@@ -170,6 +173,8 @@ func New(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error)
 		accessList:           newAccessList(),
 		transientStorage:     newTransientStorage(),
 		ephemeralStorage:     newEphemeralStorage(),
+		concreteTouched:      make(map[common.Address]struct{}),
+		isolatedStorage:      newIsolatedStorage(),
 		hasher:               crypto.NewKeccakState(),
 	}
 	if sdb.snaps != nil {
@@ -272,14 +277,28 @@ func (s *StateDB) GetPersistentState(addr common.Address, key common.Hash) commo
 	return s.GetState(addr, key)
 }
 
+// TouchConcretePrecompile marks a concrete precompile as called in the current
+// transaction, so that it is finalised at the end of it.
+func (s *StateDB) TouchConcretePrecompile(addr common.Address) {
+	s.concreteTouched[addr] = struct{}{}
+}
+
+// FinaliseConcretePrecompiles runs the Finalise hook of the precompiles that
+// were called or had their storage written in the current transaction, and of
+// those that opt in to always be finalised.
 func (s *StateDB) FinaliseConcretePrecompiles(concretePrecompiles concrete.PrecompileMap) {
 	for addr, p := range concretePrecompiles {
+		_, touched := s.concreteTouched[addr]
+		_, dirty := s.journal.dirties[addr]
+		if !touched && !dirty && !concrete.AlwaysFinalise(p) {
+			continue
+		}
 		env := cc_api.NewNoCallEnvironment(
 			addr,
 			cc_api.EnvConfig{
 				Static:    true,
 				Ephemeral: true,
-				Trusted:   true,
+				Trusted:   concrete.IsTrusted(p),
 			},
 			s,
 			false,
@@ -302,7 +321,7 @@ func (s *StateDB) CommitConcretePrecompiles(concretePrecompiles concrete.Precomp
 			cc_api.EnvConfig{
 				Static:    true,
 				Ephemeral: true,
-				Trusted:   true,
+				Trusted:   concrete.IsTrusted(p),
 			},
 			s,
 			false,
@@ -318,6 +337,113 @@ func (s *StateDB) CommitConcretePrecompiles(concretePrecompiles concrete.Precomp
 	}
 }
 
+// SetBlockConcretePrecompiles records the concrete precompiles active in the
+// block being processed, which are committed along with its state.
+func (s *StateDB) SetBlockConcretePrecompiles(concretePrecompiles concrete.PrecompileMap) {
+	s.concreteBlockPcs = concretePrecompiles
+}
+
+// BlockConcretePrecompiles returns the concrete precompiles active in the block
+// being processed.
+func (s *StateDB) BlockConcretePrecompiles() concrete.PrecompileMap {
+	return s.concreteBlockPcs
+}
+
+// MigrateConcretePrecompiles runs the migration hook of the given precompiles,
+// in address order, with a trusted environment.
+func (s *StateDB) MigrateConcretePrecompiles(concretePrecompiles concrete.PrecompileMap) {
+	for _, addr := range sortedConcreteAddresses(concretePrecompiles) {
+		p, ok := concretePrecompiles[addr].(concrete.MigratingPrecompile)
+		if !ok {
+			continue
+		}
+		env := cc_api.NewNoCallEnvironment(
+			addr,
+			cc_api.EnvConfig{
+				Static:    false,
+				Ephemeral: true,
+				Trusted:   true,
+				NoLogs:    true,
+			},
+			s,
+			false,
+			0,
+		)
+		err := p.Migrate(env)
+		if env.Error() != nil {
+			err = env.Error()
+		}
+		if err != nil {
+			s.setError(fmt.Errorf("error in concrete precompile %x Migrate(): %v", addr, err))
+		}
+	}
+}
+
+// BeginBlockConcretePrecompiles runs the BeginBlock hook of the given
+// precompiles, in address order.
+func (s *StateDB) BeginBlockConcretePrecompiles(concretePrecompiles concrete.PrecompileMap, block cc_api.BlockContext) {
+	for _, addr := range sortedConcreteAddresses(concretePrecompiles) {
+		p, ok := concretePrecompiles[addr].(concrete.BeginBlocker)
+		if !ok {
+			continue
+		}
+		env := s.newConcreteBlockEnvironment(addr, p, block)
+		err := p.BeginBlock(env)
+		if env.Error() != nil {
+			err = env.Error()
+		}
+		if err != nil {
+			s.setError(fmt.Errorf("error in concrete precompile %x BeginBlock(): %v", addr, err))
+		}
+	}
+}
+
+// EndBlockConcretePrecompiles runs the EndBlock hook of the given precompiles,
+// in address order.
+func (s *StateDB) EndBlockConcretePrecompiles(concretePrecompiles concrete.PrecompileMap, block cc_api.BlockContext) {
+	for _, addr := range sortedConcreteAddresses(concretePrecompiles) {
+		p, ok := concretePrecompiles[addr].(concrete.EndBlocker)
+		if !ok {
+			continue
+		}
+		env := s.newConcreteBlockEnvironment(addr, p, block)
+		err := p.EndBlock(env)
+		if env.Error() != nil {
+			err = env.Error()
+		}
+		if err != nil {
+			s.setError(fmt.Errorf("error in concrete precompile %x EndBlock(): %v", addr, err))
+		}
+	}
+}
+
+func (s *StateDB) newConcreteBlockEnvironment(addr common.Address, p concrete.Precompile, block cc_api.BlockContext) *cc_api.Env {
+	return cc_api.NewEnvironment(
+		addr,
+		cc_api.EnvConfig{
+			Static:    false,
+			Ephemeral: true,
+			Trusted:   concrete.IsTrusted(p),
+			NoLogs:    true,
+		},
+		s,
+		block,
+		nil,
+		nil,
+		false,
+		0,
+	)
+}
+
+func sortedConcreteAddresses(concretePrecompiles concrete.PrecompileMap) []common.Address {
+	addrs := make([]common.Address, 0, len(concretePrecompiles))
+	for addr := range concretePrecompiles {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].Cmp(addrs[j]) < 0 })
+	return addrs
+}
+
 // AddPreimage records a SHA3 preimage seen by the VM.
 func (s *StateDB) AddPreimage(hash common.Hash, preimage []byte) {
 	if _, ok := s.preimages[hash]; !ok {
@@ -785,6 +911,7 @@ func (s *StateDB) Copy() *StateDB {
 		logs:                 make(map[common.Hash][]*types.Log, len(s.logs)),
 		logSize:              s.logSize,
 		preimages:            make(map[common.Hash][]byte, len(s.preimages)),
+		concreteBlockPcs:     s.concreteBlockPcs,
 		journal:              newJournal(),
 		hasher:               crypto.NewKeccakState(),
 
@@ -860,6 +987,11 @@ func (s *StateDB) Copy() *StateDB {
 	state.accessList = s.accessList.Copy()
 	state.transientStorage = s.transientStorage.Copy()
 	state.ephemeralStorage = s.ephemeralStorage.Copy()
+	state.isolatedStorage = s.isolatedStorage.Copy()
+	state.concreteTouched = make(map[common.Address]struct{}, len(s.concreteTouched))
+	for addr := range s.concreteTouched {
+		state.concreteTouched[addr] = struct{}{}
+	}
 
 	// If there's a prefetcher running, make an inactive copy of it that can
 	// only access data but does not actively preload (since the user will not
@@ -922,7 +1054,8 @@ func (s *StateDB) FinaliseWithConcrete(concretePrecompiles concrete.PrecompileMa
 			continue
 		}
 		_, isConcretePrecompile := concretePrecompiles[addr]
-		if obj.selfDestructed || (deleteEmptyObjects && obj.empty() && !isConcretePrecompile) {
+		isConcreteSystem := isConcretePrecompile || addr == params.ConcreteSchedulerAddress
+		if obj.selfDestructed || (deleteEmptyObjects && obj.empty() && !isConcreteSystem) {
 			obj.deleted = true
 
 			// We need to maintain account deletions explicitly (will remain
@@ -965,6 +1098,10 @@ func (s *StateDB) IntermediateRoot(deleteEmptyObjects bool) common.Hash {
 }
 
 func (s *StateDB) IntermediateRootWithConcrete(concretePrecompiles concrete.PrecompileMap, deleteEmptyObjects bool) common.Hash {
+	// Write the buffered isolated storage into its tries first, as it updates
+	// the storage of the precompile accounts
+	s.flushIsolatedStorage()
+
 	// Finalise all the dirty storage states and write them into the tries
 	s.FinaliseWithConcrete(concretePrecompiles, deleteEmptyObjects)
 
@@ -1038,6 +1175,9 @@ func (s *StateDB) clearJournalAndRefund() {
 		s.refund = 0
 	}
 	s.validRevisions = s.validRevisions[:0] // Snapshots can be created without journal entries
+	if len(s.concreteTouched) > 0 {
+		s.concreteTouched = make(map[common.Address]struct{})
+	}
 }
 
 // fastDeleteStorage is the function that efficiently deletes the storage trie
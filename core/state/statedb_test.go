@@ -30,6 +30,9 @@ import (
 	"testing/quick"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	cc_api "github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/concrete/lib"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -1189,3 +1192,135 @@ func TestDeleteStorage(t *testing.T) {
 		t.Fatalf("difference found:\nfast: %v\nslow: %v\n", fastRes, slowRes)
 	}
 }
+
+type finaliseCounter struct {
+	lib.BlankPrecompile
+	always bool
+	count  int
+}
+
+func (pc *finaliseCounter) Finalise(env cc_api.Environment) error {
+	pc.count++
+	return nil
+}
+
+func (pc *finaliseCounter) AlwaysFinalise() bool {
+	return pc.always
+}
+
+func TestFinaliseTouchedConcretePrecompiles(t *testing.T) {
+	state, _ := New(types.EmptyRootHash, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	var (
+		called    = &finaliseCounter{}
+		written   = &finaliseCounter{}
+		always    = &finaliseCounter{always: true}
+		untouched = &finaliseCounter{}
+		pcs       = concrete.PrecompileMap{
+			common.Address{0x01}: called,
+			common.Address{0x02}: written,
+			common.Address{0x03}: always,
+			common.Address{0x04}: untouched,
+		}
+	)
+	state.TouchConcretePrecompile(common.Address{0x01})
+	state.SetState(common.Address{0x02}, common.Hash{0x01}, common.Hash{0x01})
+	state.FinaliseWithConcrete(pcs, true)
+	if called.count != 1 || written.count != 1 || always.count != 1 || untouched.count != 0 {
+		t.Fatalf("finalise count mismatch: have %d %d %d %d, want 1 1 1 0", called.count, written.count, always.count, untouched.count)
+	}
+
+	// Touched precompiles are reset after each transaction
+	state.FinaliseWithConcrete(pcs, true)
+	if called.count != 1 || written.count != 1 || always.count != 2 || untouched.count != 0 {
+		t.Fatalf("finalise count mismatch: have %d %d %d %d, want 1 1 2 0", called.count, written.count, always.count, untouched.count)
+	}
+}
+
+func TestIsolatedStorage(t *testing.T) {
+	var (
+		db          = NewDatabase(rawdb.NewMemoryDatabase())
+		addr        = common.Address{0x01}
+		storageAddr = IsolatedStorageAddress(addr)
+		key         = common.Hash{0x01}
+		value       = common.Hash{0x02}
+		pcs         = concrete.PrecompileMap{addr: &lib.BlankPrecompile{}}
+	)
+	state, _ := New(types.EmptyRootHash, db, nil)
+
+	// Reads do not create the storage account
+	if have := state.GetIsolatedState(addr, key); have != (common.Hash{}) {
+		t.Fatalf("isolated state mismatch: have %x, want empty", have)
+	}
+	state.SetIsolatedState(addr, key, common.Hash{})
+	if state.Exist(storageAddr) {
+		t.Fatal("isolated storage account created without writes")
+	}
+
+	// Writes are journaled
+	id := state.Snapshot()
+	state.SetIsolatedState(addr, key, value)
+	if have := state.GetIsolatedState(addr, key); have != value {
+		t.Fatalf("isolated state mismatch: have %x, want %x", have, value)
+	}
+	state.RevertToSnapshot(id)
+	if have := state.GetIsolatedState(addr, key); have != (common.Hash{}) {
+		t.Fatalf("isolated state not reverted: have %x", have)
+	}
+	if state.Exist(storageAddr) {
+		t.Fatal("isolated storage account not reverted")
+	}
+
+	// Writes are buffered across transactions and kept out of the precompile
+	// storage
+	state.SetIsolatedState(addr, key, value)
+	state.Finalise(true)
+	if state.Exist(storageAddr) {
+		t.Fatal("isolated storage written to the trie before computing the root")
+	}
+	if have := state.GetIsolatedState(addr, key); have != value {
+		t.Fatalf("isolated state mismatch: have %x, want %x", have, value)
+	}
+	if have := state.GetState(addr, key); have != (common.Hash{}) {
+		t.Fatalf("isolated state written to the precompile storage: %x", have)
+	}
+	copied := state.Copy()
+	root, err := state.CommitWithConcrete(pcs, 0, true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if root == types.EmptyRootHash {
+		t.Fatal("isolated storage not committed to the state root")
+	}
+	if have, _ := copied.CommitWithConcrete(pcs, 0, true); have != root {
+		t.Fatalf("copied state root mismatch: have %x, want %x", have, root)
+	}
+
+	// Committed writes are read back from the state trie
+	state, _ = New(root, db, nil)
+	if have := state.GetIsolatedState(addr, key); have != value {
+		t.Fatalf("isolated state mismatch after commit: have %x, want %x", have, value)
+	}
+	isolatedRoot := state.GetStorageRoot(storageAddr)
+	if isolatedRoot == types.EmptyRootHash {
+		t.Fatal("isolated storage missing from the state trie")
+	}
+	// The precompile storage commits to the isolated storage root
+	if have := state.GetState(addr, cc_api.IsolatedStorageRootKey); have != isolatedRoot {
+		t.Fatalf("isolated storage root mismatch: have %x, want %x", have, isolatedRoot)
+	}
+
+	// Clearing the storage keeps the derived account and updates the root
+	state.SetIsolatedState(addr, key, common.Hash{})
+	root, err = state.CommitWithConcrete(pcs, 1, true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	state, _ = New(root, db, nil)
+	if !state.Exist(storageAddr) {
+		t.Fatal("isolated storage account deleted")
+	}
+	if have := state.GetState(addr, cc_api.IsolatedStorageRootKey); have != types.EmptyRootHash {
+		t.Fatalf("isolated storage root mismatch: have %x, want %x", have, types.EmptyRootHash)
+	}
+}
@@ -19,6 +19,7 @@ package core
 import (
 	"sync/atomic"
 
+	"github.com/ethereum/go-ethereum/concrete"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -52,7 +53,7 @@ func (p *statePrefetcher) Prefetch(block *types.Block, statedb *state.StateDB, c
 		header       = block.Header()
 		gaspool      = new(GasPool).AddGas(block.GasLimit())
 		blockContext = NewEVMBlockContext(header, p.bc, nil, p.config, statedb)
-		concretePcs  = p.bc.Concrete().Precompiles(header.Number.Uint64())
+		concretePcs  = concrete.GetPrecompiles(p.bc.Concrete(), header.Number.Uint64(), header.Time, statedb)
 		evm          = vm.NewEVMWithConcrete(blockContext, vm.TxContext{}, statedb, p.config, cfg, concretePcs)
 		signer       = types.MakeSigner(p.config, header.Number, header.Time)
 	)
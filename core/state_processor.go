@@ -23,6 +23,8 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/concrete"
+	cc_api "github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/concrete/scheduler"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -74,13 +76,23 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	}
 	misc.EnsureCreate2Deployer(p.config, block.Time(), statedb)
 	var (
-		context     = NewEVMBlockContext(header, p.bc, nil, p.config, statedb)
-		concretePcs = p.bc.Concrete().Precompiles(header.Number.Uint64())
-		vmenv       = vm.NewEVMWithConcrete(context, vm.TxContext{}, statedb, p.config, cfg, concretePcs)
-		signer      = types.MakeSigner(p.config, header.Number, header.Time)
+		context = NewEVMBlockContext(header, p.bc, nil, p.config, statedb)
+		signer  = types.MakeSigner(p.config, header.Number, header.Time)
 	)
 	if beaconRoot := block.BeaconRoot(); beaconRoot != nil {
-		ProcessBeaconBlockRoot(*beaconRoot, vmenv, statedb)
+		ProcessBeaconBlockRoot(*beaconRoot, vm.NewEVM(context, vm.TxContext{}, statedb, p.config, cfg), statedb)
+	}
+	if parent := p.bc.GetHeader(header.ParentHash, header.Number.Uint64()-1); parent != nil {
+		ProcessConcreteMigrations(p.config, p.bc.Concrete(), header, parent.Time, statedb)
+	}
+	var (
+		concretePcs = ProcessConcretePrecompiles(p.bc.Concrete(), header, statedb)
+		vmenv       = vm.NewEVMWithConcrete(context, vm.TxContext{}, statedb, p.config, cfg, concretePcs)
+	)
+	ProcessConcreteBeginBlock(vmenv, statedb)
+	scheduled := ProcessConcreteSchedule(header, statedb)
+	if err := ValidateScheduledTransactions(block.Transactions(), scheduled); err != nil {
+		return nil, nil, 0, err
 	}
 	// Iterate over and process the individual transactions
 	for i, tx := range block.Transactions() {
@@ -96,6 +108,7 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		receipts = append(receipts, receipt)
 		allLogs = append(allLogs, receipt.Logs...)
 	}
+	ProcessConcreteEndBlock(vmenv, statedb)
 	// Fail if Shanghai not enabled and len(withdrawals) is non-zero.
 	withdrawals := block.Withdrawals()
 	if len(withdrawals) > 0 && !p.config.IsShanghai(block.Number(), block.Time()) {
@@ -208,3 +221,101 @@ func ProcessBeaconBlockRoot(beaconRoot common.Hash, vmenv *vm.EVM, statedb *stat
 	_, _, _ = vmenv.Call(vm.AccountRef(msg.From), *msg.To, msg.Data, 30_000_000, common.U2560)
 	statedb.Finalise(true)
 }
+
+// ProcessConcreteMigrations runs the migration hook of the concrete precompiles
+// activated at the given block. It must be called before any transaction in the
+// block is applied.
+func ProcessConcreteMigrations(config *params.ChainConfig, registry concrete.PrecompileRegistry, header *types.Header, parentTime uint64, statedb *state.StateDB) {
+	if registry == nil {
+		return
+	}
+	activated := concrete.ActivatedPrecompiles(registry, header.Number.Uint64(), header.Time, parentTime, statedb)
+	if len(activated) == 0 {
+		return
+	}
+	statedb.MigrateConcretePrecompiles(activated)
+	// Finalise so that the migrated precompile accounts are not deleted as
+	// empty by later finalisations unaware of the precompiles
+	statedb.FinaliseWithConcrete(activated, config.IsEIP158(header.Number))
+}
+
+// ProcessConcretePrecompiles resolves the concrete precompiles active in the
+// given block and records them in the state. It must be called once per block,
+// after the migrations and before the block hooks, and the result used for the
+// hooks, every transaction and the commit of the block, so precompiles enabled
+// by state changes within a block, e.g. to on-chain code, are only active from
+// the next block.
+func ProcessConcretePrecompiles(registry concrete.PrecompileRegistry, header *types.Header, statedb *state.StateDB) concrete.PrecompileMap {
+	var concretePcs concrete.PrecompileMap
+	if registry != nil {
+		concretePcs = concrete.GetPrecompiles(registry, header.Number.Uint64(), header.Time, statedb)
+	}
+	statedb.SetBlockConcretePrecompiles(concretePcs)
+	return concretePcs
+}
+
+// ProcessConcreteBeginBlock runs the BeginBlock hook of the concrete precompiles
+// active in the EVM. It must be called before any transaction in the block is
+// applied.
+func ProcessConcreteBeginBlock(vmenv *vm.EVM, statedb *state.StateDB) {
+	processConcreteBlockHook(vmenv, statedb, statedb.BeginBlockConcretePrecompiles)
+}
+
+// ProcessConcreteEndBlock runs the EndBlock hook of the concrete precompiles
+// active in the EVM. It must be called after all transactions in the block have
+// been applied.
+func ProcessConcreteEndBlock(vmenv *vm.EVM, statedb *state.StateDB) {
+	processConcreteBlockHook(vmenv, statedb, statedb.EndBlockConcretePrecompiles)
+}
+
+// processConcreteBlockHook runs a block hook. Hooks cannot emit logs, as they
+// would not be part of any receipt.
+func processConcreteBlockHook(vmenv *vm.EVM, statedb *state.StateDB, hook func(concrete.PrecompileMap, cc_api.BlockContext)) {
+	concretePcs := vmenv.ConcretePrecompiles()
+	if len(concretePcs) == 0 {
+		return
+	}
+	hook(concretePcs, vm.NewConcreteBlockContext(vmenv))
+	statedb.FinaliseWithConcrete(concretePcs, vmenv.ChainConfig().IsEIP158(vmenv.Context.BlockNumber))
+}
+
+// ProcessConcreteSchedule removes the calls scheduled by concrete precompiles
+// for the given block from the queue and returns the transactions executing
+// them. It must be called before any transaction in the block is applied.
+func ProcessConcreteSchedule(header *types.Header, statedb *state.StateDB) types.Transactions {
+	number := header.Number.Uint64()
+	calls := scheduler.Calls(statedb, number)
+	if len(calls) == 0 {
+		return nil
+	}
+	scheduler.Clear(statedb, number)
+	return scheduler.Transactions(number, calls)
+}
+
+// ValidateScheduledTransactions checks that the scheduled transactions are
+// included in order right after the leading deposit transactions of the block,
+// and nowhere else.
+func ValidateScheduledTransactions(txs types.Transactions, scheduled types.Transactions) error {
+	if len(scheduled) == 0 {
+		return nil
+	}
+	isScheduled := make(map[common.Hash]bool, len(scheduled))
+	for _, tx := range scheduled {
+		isScheduled[tx.Hash()] = true
+	}
+	start := 0
+	for start < len(txs) && txs[start].IsDepositTx() && !isScheduled[txs[start].Hash()] {
+		start++
+	}
+	for ii, tx := range scheduled {
+		if start+ii >= len(txs) || txs[start+ii].Hash() != tx.Hash() {
+			return fmt.Errorf("missing scheduled transaction %d [%v]", ii, tx.Hash().Hex())
+		}
+	}
+	for ii, tx := range txs[start+len(scheduled):] {
+		if isScheduled[tx.Hash()] {
+			return fmt.Errorf("duplicate scheduled transaction %d [%v]", start+len(scheduled)+ii, tx.Hash().Hex())
+		}
+	}
+	return nil
+}
@@ -23,6 +23,8 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/lib"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/beacon"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
@@ -422,3 +424,86 @@ func GenerateBadBlock(parent *types.Block, engine consensus.Engine, txs types.Tr
 	}
 	return types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil))
 }
+
+// switchRegistry activates a precompile once a slot of a contract is set, as
+// on-chain registries activate precompiles when their code is written.
+type switchRegistry struct {
+	*concrete.GenericPrecompileRegistry
+	switchAddr common.Address
+	pcAddr     common.Address
+	pc         concrete.Precompile
+}
+
+func (r *switchRegistry) PrecompilesWithState(blockNumber uint64, time uint64, state concrete.StateReader) concrete.PrecompileMap {
+	if state.GetState(r.switchAddr, common.Hash{}) == (common.Hash{}) {
+		return concrete.PrecompileMap{}
+	}
+	return concrete.PrecompileMap{r.pcAddr: r.pc}
+}
+
+// counterPrecompile counts the calls made to it.
+type counterPrecompile struct {
+	lib.BlankPrecompile
+}
+
+func (p *counterPrecompile) IsStatic(input []byte) bool { return false }
+
+func (p *counterPrecompile) Run(env concrete.Environment, input []byte) ([]byte, error) {
+	count := env.StorageLoad(common.Hash{}).Big()
+	env.StorageStore(common.Hash{}, common.BigToHash(count.Add(count, common.Big1)))
+	return nil, nil
+}
+
+// Tests that precompiles enabled by a transaction are only active from the
+// next block, both when generating and when importing blocks.
+func TestConcretePrecompilesResolvedPerBlock(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		registry = &switchRegistry{
+			GenericPrecompileRegistry: concrete.NewRegistry(),
+			switchAddr:                common.HexToAddress("0xaaaa"),
+			pcAddr:                    common.HexToAddress("0xbbbb"),
+			pc:                        &counterPrecompile{},
+		}
+		gspec = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				sender: {Balance: big.NewInt(params.Ether)},
+				// Sets slot 0 to 1
+				registry.switchAddr: {Code: common.FromHex("0x600160005500")},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := GenerateChainWithGenesisWithConcrete(gspec, ethash.NewFaker(), 2, registry, func(i int, b *BlockGen) {
+		call := func(to common.Address) {
+			tx, err := types.SignTx(types.NewTransaction(b.TxNonce(sender), to, common.Big0, 100000, b.BaseFee(), nil), signer, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.AddTx(tx)
+		}
+		if i == 0 {
+			call(registry.switchAddr)
+		}
+		call(registry.pcAddr)
+	})
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	chain.SetConcrete(registry)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := statedb.GetState(registry.pcAddr, common.Hash{}); count != common.BigToHash(common.Big1) {
+		t.Errorf("precompile calls: have %v, want 1", count.Big())
+	}
+}
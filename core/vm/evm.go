@@ -72,13 +72,13 @@ func (evm *EVM) concretePrecompile(addr common.Address) (concrete.Precompile, bo
 	return pc, ok
 }
 
-func (evm *EVM) newConcreteEnvironment(contract *Contract, static bool, gas uint64) *cc_api.Env {
+func (evm *EVM) newConcreteEnvironment(pc concrete.Precompile, contract *Contract, static bool, gas uint64) *cc_api.Env {
 	env := cc_api.NewEnvironment(
 		contract.Address(),
 		cc_api.EnvConfig{
 			Static:    static,
 			Ephemeral: true,
-			Trusted:   true,
+			Trusted:   concrete.IsTrusted(pc),
 		},
 		evm.StateDB,
 		NewConcreteBlockContext(evm),
@@ -221,6 +221,12 @@ func (evm *EVM) Interpreter() *EVMInterpreter {
 // the necessary steps to create accounts and reverses the state in case of an
 // execution error or failed value transfer.
 func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas uint64, value *uint256.Int) (ret []byte, leftOverGas uint64, err error) {
+	if hook := evm.Config.CallHook; hook != nil {
+		caller = hook.BeforeCall(evm, CALL, caller, addr)
+		defer func(caller common.Address) {
+			ret, err = hook.AfterCall(evm, CALL, caller, addr, ret, err)
+		}(caller.Address())
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -275,7 +281,8 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 		contract := NewContract(caller, AccountRef(addrCopy), value, 0)
 		contract.Input = input
 		static := evm.Interpreter().readOnly
-		env := evm.newConcreteEnvironment(contract, static, gas)
+		evm.StateDB.TouchConcretePrecompile(addr)
+		env := evm.newConcreteEnvironment(ccp, contract, static, gas)
 		ret, gas, err = concrete.RunPrecompile(ccp, env, input, static)
 		if err == cc_api.ErrExecutionReverted {
 			err = ErrExecutionReverted
@@ -319,6 +326,12 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 // CallCode differs from Call in the sense that it executes the given address'
 // code with the caller as context.
 func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte, gas uint64, value *uint256.Int) (ret []byte, leftOverGas uint64, err error) {
+	if hook := evm.Config.CallHook; hook != nil {
+		caller = hook.BeforeCall(evm, CALLCODE, caller, addr)
+		defer func(caller common.Address) {
+			ret, err = hook.AfterCall(evm, CALLCODE, caller, addr, ret, err)
+		}(caller.Address())
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -367,6 +380,12 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 // DelegateCall differs from CallCode in the sense that it executes the given address'
 // code with the caller as context and the caller is set to the caller of the caller.
 func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []byte, gas uint64) (ret []byte, leftOverGas uint64, err error) {
+	if hook := evm.Config.CallHook; hook != nil {
+		caller = hook.BeforeCall(evm, DELEGATECALL, caller, addr)
+		defer func(caller common.Address) {
+			ret, err = hook.AfterCall(evm, DELEGATECALL, caller, addr, ret, err)
+		}(caller.Address())
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -392,7 +411,8 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 		contract := NewContract(caller, AccountRef(caller.Address()), nil, gas).AsDelegate()
 		contract.Input = input
 		static := evm.Interpreter().readOnly
-		env := evm.newConcreteEnvironment(contract, static, gas)
+		evm.StateDB.TouchConcretePrecompile(addr)
+		env := evm.newConcreteEnvironment(ccp, contract, static, gas)
 		ret, gas, err = concrete.RunPrecompile(ccp, env, input, static)
 		if err == cc_api.ErrExecutionReverted {
 			err = ErrExecutionReverted
@@ -419,6 +439,12 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 // Opcodes that attempt to perform such modifications will result in exceptions
 // instead of performing the modifications.
 func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte, gas uint64) (ret []byte, leftOverGas uint64, err error) {
+	if hook := evm.Config.CallHook; hook != nil {
+		caller = hook.BeforeCall(evm, STATICCALL, caller, addr)
+		defer func(caller common.Address) {
+			ret, err = hook.AfterCall(evm, STATICCALL, caller, addr, ret, err)
+		}(caller.Address())
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -451,7 +477,8 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 		contract := NewContract(caller, AccountRef(addrCopy), new(uint256.Int), gas)
 		contract.Input = input
 		static := true
-		env := evm.newConcreteEnvironment(contract, static, gas)
+		evm.StateDB.TouchConcretePrecompile(addr)
+		env := evm.newConcreteEnvironment(ccp, contract, static, gas)
 		ret, gas, err = concrete.RunPrecompile(ccp, env, input, static)
 		if err == cc_api.ErrExecutionReverted {
 			err = ErrExecutionReverted
@@ -585,6 +612,12 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 
 // Create creates a new contract using code as deployment code.
 func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *uint256.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	if hook := evm.Config.CallHook; hook != nil {
+		caller = hook.BeforeCall(evm, CREATE, caller, common.Address{})
+		defer func(caller common.Address) {
+			ret, err = hook.AfterCall(evm, CREATE, caller, contractAddr, ret, err)
+		}(caller.Address())
+	}
 	contractAddr = crypto.CreateAddress(caller.Address(), evm.StateDB.GetNonce(caller.Address()))
 	return evm.create(caller, &codeAndHash{code: code}, gas, value, contractAddr, CREATE)
 }
@@ -594,6 +627,12 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *uint2
 // The different between Create2 with Create is Create2 uses keccak256(0xff ++ msg.sender ++ salt ++ keccak256(init_code))[12:]
 // instead of the usual sender-and-nonce-hash as the address where the contract is initialized at.
 func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *uint256.Int, salt *uint256.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	if hook := evm.Config.CallHook; hook != nil {
+		caller = hook.BeforeCall(evm, CREATE2, caller, common.Address{})
+		defer func(caller common.Address) {
+			ret, err = hook.AfterCall(evm, CREATE2, caller, contractAddr, ret, err)
+		}(caller.Address())
+	}
 	codeAndHash := &codeAndHash{code: code}
 	contractAddr = crypto.CreateAddress2(caller.Address(), salt.Bytes32(), codeAndHash.Hash().Bytes())
 	return evm.create(caller, codeAndHash, gas, endowment, contractAddr, CREATE2)
@@ -82,6 +82,8 @@ type StateDB interface {
 	AddLog(*types.Log)
 	AddPreimage(common.Hash, []byte)
 
+	TouchConcretePrecompile(common.Address)
+
 	cc_api.StateDB
 }
 
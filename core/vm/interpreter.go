@@ -34,6 +34,20 @@ type Config struct {
 	EnablePreimageRecording     bool                // Enables recording of SHA3/keccak preimages
 	ExtraEips                   []int               // Additional EIPS that are to be enabled
 	OptimismPrecompileOverrides PrecompileOverrides // Precompile overrides for Optimism
+	CallHook                    CallHook            // Hook altering message calls, used for testing
+}
+
+// CallHook is called around every message call and contract creation, including
+// the top level call of a transaction. It is used by the concrete test tool to
+// implement cheatcodes and must not be set when processing blocks.
+type CallHook interface {
+	// BeforeCall returns the caller the call is executed with. The address is
+	// zero for creations, and the caller of a DELEGATECALL must be returned
+	// unchanged.
+	BeforeCall(evm *EVM, kind OpCode, caller ContractRef, addr common.Address) ContractRef
+	// AfterCall returns the result of the call. The address is the created
+	// contract for creations.
+	AfterCall(evm *EVM, kind OpCode, caller common.Address, addr common.Address, ret []byte, err error) ([]byte, error)
 }
 
 // ScopeContext contains the things that are per-call, such as stack and memory,
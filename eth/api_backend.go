@@ -266,7 +266,7 @@ func (b *EthAPIBackend) GetEVM(ctx context.Context, msg *core.Message, state *st
 	} else {
 		context = core.NewEVMBlockContext(header, b.eth.BlockChain(), nil, b.eth.blockchain.Config(), state)
 	}
-	concretePcs := b.eth.blockchain.Concrete().Precompiles(header.Number.Uint64())
+	concretePcs := concrete.GetPrecompiles(b.eth.blockchain.Concrete(), header.Number.Uint64(), header.Time, state)
 	return vm.NewEVMWithConcrete(context, txContext, state, b.ChainConfig(), *vmConfig, concretePcs)
 }
 
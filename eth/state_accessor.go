@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -152,7 +153,7 @@ func (eth *Ethereum) hashState(ctx context.Context, block *types.Block, reexec u
 		}
 		// Finalize the state so any modifications are written to the trie
 		root, err := statedb.CommitWithConcrete(
-			eth.blockchain.Concrete().Precompiles(current.NumberU64()),
+			statedb.BlockConcretePrecompiles(),
 			current.NumberU64(),
 			eth.blockchain.Config().IsEIP158(current.Number()),
 		)
@@ -251,7 +252,7 @@ func (eth *Ethereum) stateAtTransaction(ctx context.Context, block *types.Block,
 			return msg, context, statedb, release, nil
 		}
 		// Not yet the searched for transaction, execute on top of the current state
-		concretePcs := eth.blockchain.Concrete().Precompiles(block.NumberU64())
+		concretePcs := concrete.GetPrecompiles(eth.blockchain.Concrete(), block.NumberU64(), block.Time(), statedb)
 		vmenv := vm.NewEVMWithConcrete(context, txContext, statedb, eth.blockchain.Config(), vm.Config{}, concretePcs)
 		statedb.SetTxContext(tx.Hash(), idx)
 		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas())); err != nil {
@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/concrete"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
 	"github.com/ethereum/go-ethereum/core"
@@ -1284,7 +1285,7 @@ func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNr
 		Header:              header,
 		State:               state,
 		ErrorRatio:          estimateGasErrorRatio,
-		ConcretePrecompiles: b.Concrete().Precompiles(header.Nonce.Uint64()),
+		ConcretePrecompiles: concrete.GetPrecompiles(b.Concrete(), header.Number.Uint64(), header.Time, state),
 	}
 	// Run the gas estimation andwrap any revertals into a custom return
 	call, err := args.ToMessage(gasCap, header.BaseFee)
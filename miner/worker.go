@@ -98,6 +98,8 @@ type environment struct {
 	receipts []*types.Receipt
 	sidecars []*types.BlobTxSidecar
 	blobs    int
+
+	scheduled types.Transactions // calls scheduled by concrete precompiles, not yet committed
 }
 
 // copy creates a deep copy of environment.
@@ -120,6 +122,9 @@ func (env *environment) copy() *environment {
 	cpy.sidecars = make([]*types.BlobTxSidecar, len(env.sidecars))
 	copy(cpy.sidecars, env.sidecars)
 
+	cpy.scheduled = make(types.Transactions, len(env.scheduled))
+	copy(cpy.scheduled, env.scheduled)
+
 	return cpy
 }
 
@@ -838,8 +843,7 @@ func (w *worker) applyTransaction(env *environment, tx *types.Transaction) (*typ
 		snap = env.state.Snapshot()
 		gp   = env.gasPool.Gas()
 	)
-	concretePcs := w.chain.Concrete().Precompiles(env.header.Number.Uint64())
-	receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, &env.coinbase, env.gasPool, env.state, env.header, tx, &env.header.GasUsed, *w.chain.GetVMConfig(), concretePcs)
+	receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, &env.coinbase, env.gasPool, env.state, env.header, tx, &env.header.GasUsed, *w.chain.GetVMConfig(), env.state.BlockConcretePrecompiles())
 	if err != nil {
 		env.state.RevertToSnapshot(snap)
 		env.gasPool.SetGas(gp)
@@ -1101,9 +1105,38 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 		vmenv := vm.NewEVM(context, vm.TxContext{}, env.state, w.chainConfig, vm.Config{})
 		core.ProcessBeaconBlockRoot(*header.ParentBeaconRoot, vmenv, env.state)
 	}
+	core.ProcessConcreteMigrations(w.chainConfig, w.chain.Concrete(), header, parent.Time, env.state)
+	core.ProcessConcretePrecompiles(w.chain.Concrete(), header, env.state)
+	core.ProcessConcreteBeginBlock(w.concreteEVM(env), env.state)
+	env.scheduled = core.ProcessConcreteSchedule(header, env.state)
 	return env, nil
 }
 
+// concreteEVM returns an EVM with the concrete precompiles active in the
+// sealing block, used to run their block hooks.
+func (w *worker) concreteEVM(env *environment) *vm.EVM {
+	context := core.NewEVMBlockContext(env.header, w.chain, nil, w.chainConfig, env.state)
+	return vm.NewEVMWithConcrete(context, vm.TxContext{}, env.state, w.chainConfig, vm.Config{}, env.state.BlockConcretePrecompiles())
+}
+
+// commitScheduledTransactions commits the calls scheduled by concrete
+// precompiles for the sealing block, which must follow the leading deposit
+// transactions of the block.
+func (w *worker) commitScheduledTransactions(env *environment) error {
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	for _, tx := range env.scheduled {
+		env.state.SetTxContext(tx.Hash(), env.tcount)
+		if _, err := w.commitTransaction(env, tx); err != nil {
+			return fmt.Errorf("failed to include scheduled tx %s: %w", tx.Hash(), err)
+		}
+		env.tcount++
+	}
+	env.scheduled = nil
+	return nil
+}
+
 // fillTransactions retrieves the pending transactions from the txpool and fills them
 // into the given sealing block. The transaction selection and ordering strategy can
 // be customized with the plugin in the future.
@@ -1180,6 +1213,11 @@ func (w *worker) generateWork(genParams *generateParams) *newPayloadResult {
 	misc.EnsureCreate2Deployer(w.chainConfig, work.header.Time, work.state)
 
 	for _, tx := range genParams.txs {
+		if !tx.IsDepositTx() {
+			if err := w.commitScheduledTransactions(work); err != nil {
+				return &newPayloadResult{err: err}
+			}
+		}
 		from, _ := types.Sender(work.signer, tx)
 		work.state.SetTxContext(tx.Hash(), work.tcount)
 		_, err := w.commitTransaction(work, tx)
@@ -1188,6 +1226,9 @@ func (w *worker) generateWork(genParams *generateParams) *newPayloadResult {
 		}
 		work.tcount++
 	}
+	if err := w.commitScheduledTransactions(work); err != nil {
+		return &newPayloadResult{err: err}
+	}
 
 	// forced transactions done, fill rest of block with transactions
 	if !genParams.noTxs {
@@ -1212,6 +1253,7 @@ func (w *worker) generateWork(genParams *generateParams) *newPayloadResult {
 		return &newPayloadResult{err: errInterruptedUpdate}
 	}
 
+	core.ProcessConcreteEndBlock(w.concreteEVM(work), work.state)
 	block, err := w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, nil, work.receipts, genParams.withdrawals)
 	if err != nil {
 		return &newPayloadResult{err: err}
@@ -1248,6 +1290,11 @@ func (w *worker) commitWork(interrupt *atomic.Int32, timestamp int64) {
 	if err != nil {
 		return
 	}
+	if err := w.commitScheduledTransactions(work); err != nil {
+		log.Error("Failed to commit scheduled transactions", "err", err)
+		work.discard()
+		return
+	}
 	// Fill pending transactions from the txpool into the block.
 	err = w.fillTransactions(interrupt, work)
 	switch {
@@ -1300,6 +1347,7 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 		// Create a local environment copy, avoid the data race with snapshot state.
 		// https://github.com/ethereum/go-ethereum/issues/24299
 		env := env.copy()
+		core.ProcessConcreteEndBlock(w.concreteEVM(env), env.state)
 		// Withdrawals are set to nil here, because this is only called in PoW.
 		block, err := w.engine.FinalizeAndAssemble(w.chain, env.header, env.state, env.txs, nil, env.receipts, nil)
 		if err != nil {
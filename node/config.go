@@ -211,6 +211,11 @@ type Config struct {
 	EnablePersonal bool `toml:"-"`
 
 	DBEngine string `toml:",omitempty"`
+
+	// WasmCacheDir is the directory compiled WASM precompiles are kept in
+	// across restarts. Relative paths are resolved in the instance directory.
+	// If empty, precompiles are compiled at every start.
+	WasmCacheDir string `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -358,6 +363,15 @@ func (c *Config) ResolvePath(path string) string {
 	return filepath.Join(c.instanceDir(), path)
 }
 
+// WasmCachePath returns the resolved WASM compilation cache directory, or an
+// empty string if there is none, e.g. for ephemeral nodes.
+func (c *Config) WasmCachePath() string {
+	if c.WasmCacheDir == "" {
+		return ""
+	}
+	return c.ResolvePath(c.WasmCacheDir)
+}
+
 func (c *Config) instanceDir() string {
 	if c.DataDir == "" {
 		return ""
@@ -413,6 +413,9 @@ type ChainConfig struct {
 
 	// Optimism config, nil if not active
 	Optimism *OptimismConfig `json:"optimism,omitempty"`
+
+	// Concrete precompile schedule, nil if not used
+	Concrete *ConcreteConfig `json:"concrete,omitempty"`
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -446,6 +449,86 @@ func (o *OptimismConfig) String() string {
 	return "optimism"
 }
 
+// ConcreteConfig is the schedule of concrete precompile activations.
+type ConcreteConfig struct {
+	Precompiles []ConcretePrecompileConfig `json:"precompiles"`
+}
+
+// ConcretePrecompileConfig schedules a precompile implementation at an address.
+// The implementation is identified either by name or by the keccak256 hash of
+// its WASM module, and activates at either a block number or a timestamp.
+// Deactivation entries retire the address instead and have no implementation.
+type ConcretePrecompileConfig struct {
+	Address    common.Address `json:"address"`
+	Name       string         `json:"name,omitempty"`
+	CodeHash   *common.Hash   `json:"codeHash,omitempty"`
+	Deactivate bool           `json:"deactivate,omitempty"`
+	Block      *uint64        `json:"block,omitempty"`
+	Time       *uint64        `json:"time,omitempty"`
+}
+
+// String implements the stringer interface.
+func (c *ConcreteConfig) String() string {
+	return fmt.Sprintf("concrete(%d precompiles)", len(c.Precompiles))
+}
+
+// CheckConfig checks that every entry in the schedule has exactly one
+// activation and, unless it is a deactivation, exactly one implementation, and
+// that no address is scheduled twice at the same activation.
+func (c *ConcreteConfig) CheckConfig() error {
+	type activation struct {
+		address common.Address
+		block   bool
+		value   uint64
+	}
+	seen := make(map[activation]bool)
+	for _, pc := range c.Precompiles {
+		if pc.Deactivate {
+			if pc.Name != "" || pc.CodeHash != nil {
+				return fmt.Errorf("concrete precompile %v deactivation must not have a name or codeHash", pc.Address)
+			}
+		} else if (pc.Name == "") == (pc.CodeHash == nil) {
+			return fmt.Errorf("concrete precompile %v must have exactly one of name and codeHash", pc.Address)
+		}
+		var key activation
+		switch {
+		case pc.Block != nil && pc.Time == nil:
+			key = activation{pc.Address, true, *pc.Block}
+		case pc.Block == nil && pc.Time != nil:
+			key = activation{pc.Address, false, *pc.Time}
+		default:
+			return fmt.Errorf("concrete precompile %v must have exactly one of block and time", pc.Address)
+		}
+		if seen[key] {
+			return fmt.Errorf("concrete precompile %v scheduled twice at the same activation", pc.Address)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// ForkBlocks returns the block numbers at which precompiles are activated.
+func (c *ConcreteConfig) ForkBlocks() []uint64 {
+	var blocks []uint64
+	for _, pc := range c.Precompiles {
+		if pc.Block != nil {
+			blocks = append(blocks, *pc.Block)
+		}
+	}
+	return blocks
+}
+
+// ForkTimes returns the timestamps at which precompiles are activated.
+func (c *ConcreteConfig) ForkTimes() []uint64 {
+	var times []uint64
+	for _, pc := range c.Precompiles {
+		if pc.Time != nil {
+			times = append(times, *pc.Time)
+		}
+	}
+	return times
+}
+
 // Description returns a human-readable description of ChainConfig.
 func (c *ChainConfig) Description() string {
 	var banner string
@@ -801,6 +884,11 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 			lastFork = cur
 		}
 	}
+	if c.Concrete != nil {
+		if err := c.Concrete.CheckConfig(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -197,4 +197,6 @@ var (
 	BeaconRootsStorageAddress = common.HexToAddress("0x000F3df6D732807Ef1319fB7B8bB8522d0Beac02")
 	// SystemAddress is where the system-transaction is sent from as per EIP-4788
 	SystemAddress common.Address = common.HexToAddress("0xfffffffffffffffffffffffffffffffffffffffe")
+	// ConcreteSchedulerAddress is where the queue of calls scheduled by concrete precompiles is stored
+	ConcreteSchedulerAddress = common.HexToAddress("0xc0c0000000000000000000000000000000000001")
 )